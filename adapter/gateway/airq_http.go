@@ -3,12 +3,16 @@ package gateway
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+	"github.com/suzutan/m5stack_airq_exporter/domain/repository"
 )
 
 // HTTPClient interface for HTTP client operations (allows mocking)
@@ -18,18 +22,27 @@ type HTTPClient interface {
 
 // AirQHTTPGateway implements AirQRepository using HTTP client
 type AirQHTTPGateway struct {
-	url    string
-	client HTTPClient
+	url     string
+	client  HTTPClient
+	decoder PayloadDecoder
 }
 
-// NewAirQHTTPGateway creates a new AirQHTTPGateway with the given URL and HTTP client
+// NewAirQHTTPGateway creates a new AirQHTTPGateway with the given URL and HTTP
+// client, decoding EzData responses
 func NewAirQHTTPGateway(url string, client HTTPClient) *AirQHTTPGateway {
 	return &AirQHTTPGateway{
-		url:    url,
-		client: client,
+		url:     url,
+		client:  client,
+		decoder: EzDataDecoder{},
 	}
 }
 
+// WithDecoder sets the decoder of the response bodies
+func (g *AirQHTTPGateway) WithDecoder(decoder PayloadDecoder) *AirQHTTPGateway {
+	g.decoder = decoder
+	return g
+}
+
 // apiResponse represents the top-level response from ezdata2.m5stack.com API
 type apiResponse struct {
 	Code int       `json:"code"`
@@ -86,49 +99,95 @@ type profileData struct {
 
 // Fetch retrieves the latest air quality data from the API
 func (g *AirQHTTPGateway) Fetch(ctx context.Context) (*entity.AirQuality, error) {
+	body, err := g.FetchRaw(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := g.decoder.Decode(body, nil)
+	if err != nil {
+		return nil, err
+	}
+	data.FetchedAt = time.Now()
+	return data, nil
+}
+
+// FetchRaw retrieves the raw response body from the API
+func (g *AirQHTTPGateway) FetchRaw(ctx context.Context) ([]byte, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", redactURLError(err))
 	}
 
 	resp, err := g.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+		return nil, classify(repository.ErrorClassNetwork, fmt.Errorf("failed to execute request: %w", redactURLError(err)))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, classify(repository.ErrorClassHTTPStatus, fmt.Errorf("unexpected status code: %d", resp.StatusCode))
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
+	return body, nil
+}
+
+// redactURLError removes the token from the URL that net/url and net/http
+// include in their error messages
+func redactURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = RedactURL(urlErr.URL)
+	}
+	return err
+}
+
+// DecodeEzDataResponse parses an EzData API response body into an AirQuality entity.
+// FetchedAt is left for the caller to set.
+func DecodeEzDataResponse(body []byte) (*entity.AirQuality, error) {
+	return TraceEzDataResponse(body, nil)
+}
+
+// TraceEzDataResponse decodes like DecodeEzDataResponse and passes every
+// completed stage to step, for diagnosing payloads; step may be nil
+func TraceEzDataResponse(body []byte, step func(DecodeStep)) (*entity.AirQuality, error) {
+	if step == nil {
+		step = func(DecodeStep) {}
+	}
 
 	var apiResp apiResponse
 	if err := json.Unmarshal(body, &apiResp); err != nil {
-		return nil, fmt.Errorf("failed to parse API response: %w", err)
+		return nil, classify(repository.ErrorClassDecode, fmt.Errorf("failed to parse API response: %w", err))
 	}
+	step(DecodeStep{Name: "parse API response", Detail: fmt.Sprintf("code=%d msg=%q", apiResp.Code, apiResp.Msg)})
 
 	if apiResp.Code != 200 {
-		return nil, fmt.Errorf("API error: code=%d, msg=%s", apiResp.Code, apiResp.Msg)
+		return nil, classify(repository.ErrorClassAPI, fmt.Errorf("API error: code=%d, msg=%s", apiResp.Code, apiResp.Msg))
 	}
 
 	if apiResp.Data == nil {
-		return nil, fmt.Errorf("API response data is nil")
+		return nil, classify(repository.ErrorClassAPI, errors.New("API response data is nil"))
 	}
+	step(DecodeStep{Name: "check API status", Detail: fmt.Sprintf("name=%q data_type=%q", apiResp.Data.Name, apiResp.Data.DataType)})
 
 	// The value field may be double-escaped JSON, try to unescape it
 	valueStr := apiResp.Data.Value
-	if unquoted, err := strconv.Unquote(`"` + valueStr + `"`); err == nil {
+	escaping := "plain JSON"
+	if unquoted, err := strconv.Unquote(`"` + valueStr + `"`); err == nil && unquoted != valueStr {
 		valueStr = unquoted
+		escaping = "double-escaped JSON"
 	}
+	step(DecodeStep{Name: "unescape sensor data", Detail: fmt.Sprintf("%d bytes of %s", len(valueStr), escaping)})
 
 	var sensor sensorData
 	if err := json.Unmarshal([]byte(valueStr), &sensor); err != nil {
-		return nil, fmt.Errorf("failed to parse sensor data: %w", err)
+		return nil, classify(repository.ErrorClassDecode, fmt.Errorf("failed to parse sensor data: %w", err))
 	}
+	step(DecodeStep{Name: "parse sensor data", Detail: fmt.Sprintf("nickname=%q sleep_interval=%ds", sensor.Profile.Nickname, sensor.RTC.SleepInterval)})
 
 	return &entity.AirQuality{
 		PM1_0:            sensor.SEN55.PM1_0,
@@ -142,6 +201,25 @@ func (g *AirQHTTPGateway) Fetch(ctx context.Context) (*entity.AirQuality, error)
 		CO2:              sensor.SCD40.CO2,
 		SCD40Humidity:    sensor.SCD40.Humidity,
 		SCD40Temperature: sensor.SCD40.Temperature,
+		Device:           DeviceID(apiResp.Data.DataToken),
 		Nickname:         sensor.Profile.Nickname,
+		Name:             apiResp.Data.Name,
+		DataType:         apiResp.Data.DataType,
+		SleepInterval:    time.Duration(sensor.RTC.SleepInterval) * time.Second,
+		CreateTime:       parseEzDataTime(apiResp.Data.CreateTime),
+		UpdateTime:       parseEzDataTime(apiResp.Data.UpdateTime),
 	}, nil
 }
+
+// parseEzDataTime parses an EzData timestamp given as Unix seconds (or milliseconds).
+// It returns the zero time if the value cannot be parsed.
+func parseEzDataTime(value string) time.Time {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n <= 0 {
+		return time.Time{}
+	}
+	if n > 1e12 {
+		return time.UnixMilli(n)
+	}
+	return time.Unix(n, 0)
+}
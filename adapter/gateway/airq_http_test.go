@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestAirQHTTPGateway_Fetch_Success(t *testing.T) {
@@ -71,6 +72,24 @@ func TestAirQHTTPGateway_Fetch_Success(t *testing.T) {
 	if data.Nickname != "AirQ" {
 		t.Errorf("expected Nickname to be AirQ, got %s", data.Nickname)
 	}
+	if data.Device != DeviceID("test-token") {
+		t.Errorf("expected Device to be derived from test-token, got %s", data.Device)
+	}
+	if data.Name != "raw" || data.DataType != "string" {
+		t.Errorf("expected Name raw and DataType string, got %s and %s", data.Name, data.DataType)
+	}
+	if data.SleepInterval != time.Minute {
+		t.Errorf("expected SleepInterval to be 1m, got %v", data.SleepInterval)
+	}
+	if !data.CreateTime.Equal(time.Unix(1703591914, 0)) {
+		t.Errorf("expected CreateTime to be 1703591914, got %v", data.CreateTime)
+	}
+	if !data.UpdateTime.Equal(time.Unix(1767573960, 0)) {
+		t.Errorf("expected UpdateTime to be 1767573960, got %v", data.UpdateTime)
+	}
+	if data.FetchedAt.IsZero() {
+		t.Error("expected FetchedAt to be set")
+	}
 }
 
 func TestAirQHTTPGateway_Fetch_DoubleEscapedJSON(t *testing.T) {
@@ -200,3 +219,68 @@ func TestAirQHTTPGateway_Fetch_ContextCanceled(t *testing.T) {
 		t.Error("expected error, got nil")
 	}
 }
+
+func TestParseEzDataTime(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Time
+	}{
+		{"1767573960", time.Unix(1767573960, 0)},
+		{"1767573960123", time.UnixMilli(1767573960123)},
+		{"", time.Time{}},
+		{"not a number", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		if got := parseEzDataTime(tt.value); !got.Equal(tt.expected) {
+			t.Errorf("parseEzDataTime(%q) = %v, expected %v", tt.value, got, tt.expected)
+		}
+	}
+}
+
+func TestTraceEzDataResponse(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		steps []string
+	}{
+		{
+			name:  "double-escaped sensor data",
+			body:  `{"code":200,"msg":"OK","data":{"dataToken":"test-token","name":"raw","value":"{\\\"scd40\\\":{\\\"co2\\\":725}}"}}`,
+			steps: []string{"parse API response", "check API status", "unescape sensor data", "parse sensor data"},
+		},
+		{
+			name:  "API error",
+			body:  `{"code":401,"msg":"invalid token"}`,
+			steps: []string{"parse API response"},
+		},
+		{
+			name:  "invalid sensor data",
+			body:  `{"code":200,"msg":"OK","data":{"value":"not json"}}`,
+			steps: []string{"parse API response", "check API status", "unescape sensor data"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var steps []string
+			data, err := TraceEzDataResponse([]byte(tt.body), func(step DecodeStep) {
+				steps = append(steps, step.Name)
+			})
+			if len(steps) != len(tt.steps) {
+				t.Fatalf("expected steps %v, got %v", tt.steps, steps)
+			}
+			for i := range steps {
+				if steps[i] != tt.steps[i] {
+					t.Errorf("expected step %d to be %s, got %s", i, tt.steps[i], steps[i])
+				}
+			}
+			if complete := len(tt.steps) == 4; complete != (err == nil) {
+				t.Errorf("expected error %v, got %v", !complete, err)
+			}
+			if err == nil && data.CO2 != 725 {
+				t.Errorf("expected CO2 to be 725, got %d", data.CO2)
+			}
+		})
+	}
+}
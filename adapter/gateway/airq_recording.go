@@ -0,0 +1,239 @@
+package gateway
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+	"github.com/suzutan/m5stack_airq_exporter/domain/repository"
+)
+
+// ErrReplayFinished is returned by ReplayAirQGateway once every recorded response has been played
+var ErrReplayFinished = errors.New("replay finished")
+
+// RawFetcher retrieves raw EzData API response bodies
+type RawFetcher interface {
+	FetchRaw(ctx context.Context) ([]byte, error)
+}
+
+// recordedResponse is one fetch in a recording, one JSON object per line.
+// Either Body or Error is set.
+type recordedResponse struct {
+	Time       time.Time `json:"time"`
+	Body       string    `json:"body,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	ErrorClass string    `json:"error_class,omitempty"`
+}
+
+// RecordingAirQGateway implements AirQRepository by fetching through another
+// gateway and appending every raw response, or fetch error, to a recording file
+type RecordingAirQGateway struct {
+	fetcher RawFetcher
+	decoder PayloadDecoder
+	path    string
+	now     func() time.Time
+
+	mu sync.Mutex
+}
+
+// NewRecordingAirQGateway creates a new RecordingAirQGateway writing to path
+func NewRecordingAirQGateway(fetcher RawFetcher, path string) *RecordingAirQGateway {
+	return &RecordingAirQGateway{
+		fetcher: fetcher,
+		decoder: EzDataDecoder{},
+		path:    path,
+		now:     time.Now,
+	}
+}
+
+// WithDecoder sets the decoder of the recorded responses
+func (g *RecordingAirQGateway) WithDecoder(decoder PayloadDecoder) *RecordingAirQGateway {
+	g.decoder = decoder
+	return g
+}
+
+// Fetch retrieves the latest air quality data and records the raw response
+func (g *RecordingAirQGateway) Fetch(ctx context.Context) (*entity.AirQuality, error) {
+	fetchedAt := g.now()
+	body, err := g.fetcher.FetchRaw(ctx)
+
+	rec := recordedResponse{Time: fetchedAt, Body: string(redactDataToken(body))}
+	if err != nil {
+		rec.Error = err.Error()
+		rec.ErrorClass = repository.ErrorClass(err)
+	}
+	if recErr := g.record(rec); recErr != nil {
+		slog.Error("Failed to record response", "path", g.path, "error", recErr)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	data, err := g.decoder.Decode(body, nil)
+	if err != nil {
+		return nil, err
+	}
+	data.FetchedAt = fetchedAt
+	return data, nil
+}
+
+// redactDataToken replaces the dataToken of an EzData response, which is the
+// secret of its URL, with the device ID derived from it, so that recordings
+// can be shared. Other bodies are returned as is.
+func redactDataToken(body []byte) []byte {
+	var resp map[string]json.RawMessage
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return body
+	}
+	var data map[string]json.RawMessage
+	if err := json.Unmarshal(resp["data"], &data); err != nil {
+		return body
+	}
+	var token string
+	if err := json.Unmarshal(data["dataToken"], &token); err != nil || token == "" {
+		return body
+	}
+
+	data["dataToken"], _ = json.Marshal(DeviceID(token))
+	resp["data"], _ = json.Marshal(data)
+	redacted, err := json.Marshal(resp)
+	if err != nil {
+		return body
+	}
+	return redacted
+}
+
+func (g *RecordingAirQGateway) record(rec recordedResponse) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to encode response: %w", err)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	f, err := os.OpenFile(g.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open recording: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write recording: %w", err)
+	}
+	return f.Close()
+}
+
+// ReplayAirQGateway implements AirQRepository by playing back a recording made
+// by RecordingAirQGateway, keeping the recorded spacing between fetches
+type ReplayAirQGateway struct {
+	records []recordedResponse
+	decoder PayloadDecoder
+	speed   float64
+	now     func() time.Time
+
+	mu    sync.Mutex
+	next  int
+	start time.Time
+}
+
+// NewReplayAirQGateway loads the recording at path. Speed scales the recorded
+// spacing: 1 replays in real time, 60 replays an hour per minute and 0 replays
+// as fast as the responses are fetched.
+func NewReplayAirQGateway(path string, speed float64) (*ReplayAirQGateway, error) {
+	if speed < 0 {
+		return nil, fmt.Errorf("invalid replay speed: %g", speed)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording: %w", err)
+	}
+	defer f.Close()
+
+	var records []recordedResponse
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var rec recordedResponse
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("failed to parse recording line %d: %w", line, err)
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read recording: %w", err)
+	}
+
+	return &ReplayAirQGateway{
+		records: records,
+		decoder: EzDataDecoder{},
+		speed:   speed,
+		now:     time.Now,
+	}, nil
+}
+
+// WithDecoder sets the decoder of the recorded responses
+func (g *ReplayAirQGateway) WithDecoder(decoder PayloadDecoder) *ReplayAirQGateway {
+	g.decoder = decoder
+	return g
+}
+
+// Len returns the number of recorded responses
+func (g *ReplayAirQGateway) Len() int {
+	return len(g.records)
+}
+
+// Fetch waits until the next recorded response is due and returns it as it was
+// returned when recorded: the decoded reading, stamped with the recorded fetch
+// time, or the recorded error
+func (g *ReplayAirQGateway) Fetch(ctx context.Context) (*entity.AirQuality, error) {
+	g.mu.Lock()
+	if g.next >= len(g.records) {
+		g.mu.Unlock()
+		return nil, ErrReplayFinished
+	}
+	rec := g.records[g.next]
+	g.next++
+	if g.start.IsZero() {
+		g.start = g.now()
+	}
+	due := g.start
+	if g.speed > 0 {
+		offset := rec.Time.Sub(g.records[0].Time)
+		due = due.Add(time.Duration(float64(offset) / g.speed))
+	}
+	g.mu.Unlock()
+
+	if wait := due.Sub(g.now()); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if rec.Error != "" {
+		if rec.ErrorClass != "" {
+			return nil, classify(rec.ErrorClass, errors.New(rec.Error))
+		}
+		return nil, errors.New(rec.Error)
+	}
+	data, err := g.decoder.Decode([]byte(rec.Body), nil)
+	if err != nil {
+		return nil, err
+	}
+	data.FetchedAt = rec.Time
+	return data, nil
+}
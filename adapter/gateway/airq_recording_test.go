@@ -0,0 +1,141 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const recordingTestBody = `{"code":200,"msg":"OK","data":{"dataToken":"test-token","dataType":"string","name":"raw","value":"{\"sen55\":{\"pm2.5\":2.5,\"voc\":75},\"scd40\":{\"co2\":725},\"profile\":{\"nickname\":\"AirQ\"}}","createTime":"1703591914","updateTime":"1767573960"}}`
+
+func TestRecordingAndReplayAirQGateway(t *testing.T) {
+	fail := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(recordingTestBody))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "recording.jsonl")
+	recorder := NewRecordingAirQGateway(NewAirQHTTPGateway(server.URL, server.Client()), path)
+	base := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	times := []time.Time{base, base.Add(time.Minute)}
+	recorder.now = func() time.Time {
+		ts := times[0]
+		times = times[1:]
+		return ts
+	}
+
+	data, err := recorder.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if data.CO2 != 725 {
+		t.Errorf("expected CO2 to be 725, got %d", data.CO2)
+	}
+	if !data.FetchedAt.Equal(base) {
+		t.Errorf("expected FetchedAt to be %v, got %v", base, data.FetchedAt)
+	}
+
+	fail = true
+	if _, err := recorder.Fetch(context.Background()); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if strings.Contains(string(content), "test-token") || !strings.Contains(string(content), DeviceID("test-token")) {
+		t.Errorf("expected the data token to be replaced with the device ID, got %s", content)
+	}
+
+	replay, err := NewReplayAirQGateway(path, 0)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if replay.Len() != 2 {
+		t.Fatalf("expected 2 recorded responses, got %d", replay.Len())
+	}
+
+	data, err = replay.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if data.CO2 != 725 || data.Device != DeviceID("test-token") || data.Nickname != "AirQ" {
+		t.Errorf("unexpected replayed reading: %+v", data)
+	}
+	if !data.FetchedAt.Equal(base) {
+		t.Errorf("expected FetchedAt to be %v, got %v", base, data.FetchedAt)
+	}
+
+	if _, err := replay.Fetch(context.Background()); err == nil || err.Error() != "unexpected status code: 503" {
+		t.Errorf("expected recorded error, got %v", err)
+	}
+
+	if _, err := replay.Fetch(context.Background()); !errors.Is(err, ErrReplayFinished) {
+		t.Errorf("expected ErrReplayFinished, got %v", err)
+	}
+}
+
+func TestReplayAirQGateway_Speed(t *testing.T) {
+	base := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	replay := &ReplayAirQGateway{
+		records: []recordedResponse{
+			{Time: base, Body: recordingTestBody},
+			{Time: base.Add(time.Minute), Body: recordingTestBody},
+		},
+		decoder: EzDataDecoder{},
+		speed:   60 * 1000,
+		now:     time.Now,
+	}
+
+	start := time.Now()
+	for range 2 {
+		if _, err := replay.Fetch(context.Background()); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	// One minute at 60000x is one millisecond
+	if elapsed := time.Since(start); elapsed < time.Millisecond {
+		t.Errorf("expected replay to wait at least 1ms, waited %v", elapsed)
+	}
+}
+
+func TestReplayAirQGateway_Canceled(t *testing.T) {
+	base := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	replay := &ReplayAirQGateway{
+		records: []recordedResponse{
+			{Time: base, Body: recordingTestBody},
+			{Time: base.Add(time.Hour), Body: recordingTestBody},
+		},
+		decoder: EzDataDecoder{},
+		speed:   1,
+		now:     time.Now,
+	}
+
+	if _, err := replay.Fetch(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := replay.Fetch(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestNewReplayAirQGateway_InvalidFile(t *testing.T) {
+	if _, err := NewReplayAirQGateway(filepath.Join(t.TempDir(), "missing.jsonl"), 1); err == nil {
+		t.Error("expected error for missing recording, got nil")
+	}
+}
@@ -0,0 +1,41 @@
+package gateway
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+)
+
+// RegisterBuildInfoMetrics registers the exporter_build_info, exporter_config_hash
+// and exporter_start_time_seconds metrics describing the running exporter
+func RegisterBuildInfoMetrics(registry prometheus.Registerer, options PrometheusMetricsOptions, info entity.BuildInfo) {
+	namespace := options.namespace()
+
+	buildInfo := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "exporter_build_info",
+		Help:      "Version, revision and Go version of the exporter, always 1",
+	}, []string{"version", "revision", "goversion"})
+	buildInfo.WithLabelValues(info.Version, info.Revision, info.GoVersion).Set(1)
+
+	startTime := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "exporter_start_time_seconds",
+		Help:      "Start time of the exporter in seconds since the Unix epoch",
+	})
+	startTime.Set(float64(info.StartTime.UnixNano()) / 1e9)
+
+	registry.MustRegister(buildInfo, startTime)
+
+	// The hash is at most 48 bits so that it is exact as a float
+	if hash, err := strconv.ParseUint(info.ConfigHash, 16, 48); err == nil {
+		configHash := prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "exporter_config_hash",
+			Help:      "Hash of the exporter configuration; differs between exporters configured differently",
+		})
+		configHash.Set(float64(hash))
+		registry.MustRegister(configHash)
+	}
+}
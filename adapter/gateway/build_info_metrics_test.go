@@ -0,0 +1,46 @@
+package gateway
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+)
+
+func TestRegisterBuildInfoMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	RegisterBuildInfoMetrics(registry, PrometheusMetricsOptions{}, entity.BuildInfo{
+		Version:    "1.2.3",
+		Revision:   "abc123",
+		GoVersion:  "go1.25.4",
+		ConfigHash: "00000000ff01",
+		StartTime:  time.Unix(1767573960, 0),
+	})
+
+	expected := `
+		# HELP airq_exporter_build_info Version, revision and Go version of the exporter, always 1
+		# TYPE airq_exporter_build_info gauge
+		airq_exporter_build_info{goversion="go1.25.4",revision="abc123",version="1.2.3"} 1
+		# HELP airq_exporter_config_hash Hash of the exporter configuration; differs between exporters configured differently
+		# TYPE airq_exporter_config_hash gauge
+		airq_exporter_config_hash 65281
+		# HELP airq_exporter_start_time_seconds Start time of the exporter in seconds since the Unix epoch
+		# TYPE airq_exporter_start_time_seconds gauge
+		airq_exporter_start_time_seconds 1.76757396e+09
+	`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected)); err != nil {
+		t.Errorf("build info metrics mismatch: %v", err)
+	}
+}
+
+func TestRegisterBuildInfoMetrics_WithoutConfigHash(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	RegisterBuildInfoMetrics(registry, PrometheusMetricsOptions{Namespace: "office"}, entity.BuildInfo{Version: "dev"})
+
+	if count, err := testutil.GatherAndCount(registry, "office_exporter_build_info", "office_exporter_config_hash"); err != nil || count != 1 {
+		t.Errorf("expected only the build info series, got %d (%v)", count, err)
+	}
+}
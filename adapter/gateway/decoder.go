@@ -0,0 +1,24 @@
+package gateway
+
+import "github.com/suzutan/m5stack_airq_exporter/domain/entity"
+
+// PayloadDecoder converts response bodies of a data source into readings
+type PayloadDecoder interface {
+	// Decode converts a response body into a reading, passing every completed
+	// stage to step when it is not nil. FetchedAt is left for the caller to set.
+	Decode(body []byte, step func(DecodeStep)) (*entity.AirQuality, error)
+}
+
+// DecodeStep is a completed stage of decoding a response, for diagnostics
+type DecodeStep struct {
+	Name   string
+	Detail string
+}
+
+// EzDataDecoder decodes responses of the M5Stack EzData API
+type EzDataDecoder struct{}
+
+// Decode implements PayloadDecoder
+func (EzDataDecoder) Decode(body []byte, step func(DecodeStep)) (*entity.AirQuality, error) {
+	return TraceEzDataResponse(body, step)
+}
@@ -0,0 +1,363 @@
+package gateway
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+)
+
+// storedReading is the on-disk representation of a reading, one JSON object per line
+type storedReading struct {
+	Device           string    `json:"device"`
+	Nickname         string    `json:"nickname,omitempty"`
+	PM1_0            float64   `json:"pm1_0"`
+	PM2_5            float64   `json:"pm2_5"`
+	PM4_0            float64   `json:"pm4_0"`
+	PM10_0           float64   `json:"pm10_0"`
+	Humidity         float64   `json:"humidity"`
+	Temperature      float64   `json:"temperature"`
+	VOC              int       `json:"voc"`
+	NOx              int       `json:"nox"`
+	CO2              int       `json:"co2"`
+	SCD40Humidity    float64   `json:"scd40_humidity"`
+	SCD40Temperature float64   `json:"scd40_temperature"`
+	CreateTime       time.Time `json:"create_time,omitzero"`
+	UpdateTime       time.Time `json:"update_time,omitzero"`
+	FetchedAt        time.Time `json:"fetched_at,omitzero"`
+	WarmingUp        bool      `json:"warming_up,omitempty"`
+	Maintenance      bool      `json:"maintenance,omitempty"`
+	// Measured lists the provided fields of readings that do not have all of them
+	Measured []string `json:"measured,omitempty"`
+}
+
+func newStoredReading(data *entity.AirQuality) storedReading {
+	return storedReading{
+		Device:           data.Device,
+		Nickname:         data.Nickname,
+		PM1_0:            data.PM1_0,
+		PM2_5:            data.PM2_5,
+		PM4_0:            data.PM4_0,
+		PM10_0:           data.PM10_0,
+		Humidity:         data.Humidity,
+		Temperature:      data.Temperature,
+		VOC:              data.VOC,
+		NOx:              data.NOx,
+		CO2:              data.CO2,
+		SCD40Humidity:    data.SCD40Humidity,
+		SCD40Temperature: data.SCD40Temperature,
+		CreateTime:       data.CreateTime,
+		UpdateTime:       data.UpdateTime,
+		FetchedAt:        data.FetchedAt,
+		WarmingUp:        data.WarmingUp,
+		Maintenance:      data.Maintenance,
+		Measured:         measuredKeys(data),
+	}
+}
+
+func (r storedReading) entity() *entity.AirQuality {
+	var measured map[string]bool
+	if r.Measured != nil {
+		measured = make(map[string]bool, len(r.Measured))
+		for _, key := range r.Measured {
+			measured[key] = true
+		}
+	}
+	return &entity.AirQuality{
+		Device:           r.Device,
+		Nickname:         r.Nickname,
+		PM1_0:            r.PM1_0,
+		PM2_5:            r.PM2_5,
+		PM4_0:            r.PM4_0,
+		PM10_0:           r.PM10_0,
+		Humidity:         r.Humidity,
+		Temperature:      r.Temperature,
+		VOC:              r.VOC,
+		NOx:              r.NOx,
+		CO2:              r.CO2,
+		SCD40Humidity:    r.SCD40Humidity,
+		SCD40Temperature: r.SCD40Temperature,
+		CreateTime:       r.CreateTime,
+		UpdateTime:       r.UpdateTime,
+		FetchedAt:        r.FetchedAt,
+		WarmingUp:        r.WarmingUp,
+		Maintenance:      r.Maintenance,
+		Measured:         measured,
+	}
+}
+
+// measuredKeys returns the provided fields in display order, or nil when the
+// reading has all of them
+func measuredKeys(data *entity.AirQuality) []string {
+	if data.Measured == nil {
+		return nil
+	}
+	keys := []string{}
+	for _, f := range entity.Fields {
+		if data.Measured[f.Key] {
+			keys = append(keys, f.Key)
+		}
+	}
+	return keys
+}
+
+// storeCompactInterval is how often a store with a retention drops expired
+// readings while readings are appended
+const storeCompactInterval = 24 * time.Hour
+
+// FileHistoryGateway implements ReadingSink and HistoryRepository on top of an
+// append-only JSON Lines file, so that history survives restarts and can be read
+// by the export command while the exporter is running
+type FileHistoryGateway struct {
+	path      string
+	retention time.Duration
+	now       func() time.Time
+
+	mu          sync.Mutex
+	compactedAt time.Time
+}
+
+// NewFileHistoryGateway creates a new FileHistoryGateway storing readings at path
+func NewFileHistoryGateway(path string) *FileHistoryGateway {
+	return &FileHistoryGateway{
+		path: path,
+		now:  time.Now,
+	}
+}
+
+// WithRetention drops readings older than retention from the store on the
+// first append and then once a day, so that the file does not grow without bound
+func (g *FileHistoryGateway) WithRetention(retention time.Duration) *FileHistoryGateway {
+	g.retention = retention
+	return g
+}
+
+// Publish appends the reading to the store
+func (g *FileHistoryGateway) Publish(data *entity.AirQuality) {
+	if err := g.Append(data); err != nil {
+		slog.Error("Failed to store reading", "path", g.path, "error", err)
+	}
+}
+
+// Append writes the reading to the end of the store file
+func (g *FileHistoryGateway) Append(data *entity.AirQuality) error {
+	line, err := json.Marshal(newStoredReading(data))
+	if err != nil {
+		return fmt.Errorf("failed to encode reading: %w", err)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	f, err := os.OpenFile(g.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open store: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write store: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write store: %w", err)
+	}
+
+	if now := g.now(); g.retention > 0 && now.Sub(g.compactedAt) >= storeCompactInterval {
+		if err := g.compactLocked(now.Add(-g.retention)); err != nil {
+			slog.Error("Failed to compact store", "path", g.path, "error", err)
+		}
+	}
+	return nil
+}
+
+// Range returns the stored readings taken within [from, to], oldest first
+func (g *FileHistoryGateway) Range(from, to time.Time) []*entity.AirQuality {
+	var result []*entity.AirQuality
+	err := g.scan(func(data *entity.AirQuality) {
+		ts := data.Timestamp()
+		if !ts.Before(from) && !ts.After(to) {
+			result = append(result, data)
+		}
+	})
+	if err != nil {
+		slog.Error("Failed to read store", "path", g.path, "error", err)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Timestamp().Before(result[j].Timestamp())
+	})
+	return result
+}
+
+// Each calls fn for every stored reading taken within [from, to] in the order
+// they were stored, which is oldest first for every device, reading the file
+// line by line. It stops at the first error of fn. The lock is not held, so that
+// a slow consumer such as an export download does not block appends: appends
+// only add whole lines, and compaction replaces the file rather than changing it.
+func (g *FileHistoryGateway) Each(from, to time.Time, fn func(data *entity.AirQuality) error) error {
+	return scanFile(g.path, func(data *entity.AirQuality) error {
+		if ts := data.Timestamp(); ts.Before(from) || ts.After(to) {
+			return nil
+		}
+		return fn(data)
+	})
+}
+
+// Latest returns the most recent stored reading of every device, ordered by device
+func (g *FileHistoryGateway) Latest() []*entity.AirQuality {
+	latest := make(map[string]*entity.AirQuality)
+	err := g.scan(func(data *entity.AirQuality) {
+		if prev, ok := latest[data.Device]; !ok || !prev.Timestamp().After(data.Timestamp()) {
+			latest[data.Device] = data
+		}
+	})
+	if err != nil {
+		slog.Error("Failed to read store", "path", g.path, "error", err)
+	}
+
+	result := make([]*entity.AirQuality, 0, len(latest))
+	for _, data := range latest {
+		result = append(result, data)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Device < result[j].Device
+	})
+	return result
+}
+
+// Compact rewrites the store without readings taken before the given time
+func (g *FileHistoryGateway) Compact(before time.Time) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.compactLocked(before)
+}
+
+// compactLocked is Compact for callers that already hold the lock. The kept
+// readings are copied line by line rather than loaded at once.
+func (g *FileHistoryGateway) compactLocked(before time.Time) error {
+	err := g.writeLocked(func(enc *json.Encoder) error {
+		return scanFile(g.path, func(data *entity.AirQuality) error {
+			if data.Timestamp().Before(before) {
+				return nil
+			}
+			if err := enc.Encode(newStoredReading(data)); err != nil {
+				return fmt.Errorf("failed to write store: %w", err)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+	g.compactedAt = g.now()
+	return nil
+}
+
+// Replace atomically rewrites the store with the given readings
+func (g *FileHistoryGateway) Replace(readings []*entity.AirQuality) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.writeLocked(func(enc *json.Encoder) error {
+		for _, data := range readings {
+			if err := enc.Encode(newStoredReading(data)); err != nil {
+				return fmt.Errorf("failed to write store: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// Sync flushes the store file to disk, so that the readings appended last
+// survive a crash of the host
+func (g *FileHistoryGateway) Sync() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	f, err := os.OpenFile(g.path, os.O_WRONLY, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open store: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to sync store: %w", err)
+	}
+	return f.Close()
+}
+
+// writeLocked writes the readings encoded by write to a temporary file, flushed
+// to disk, and renames it over the store
+func (g *FileHistoryGateway) writeLocked(write func(enc *json.Encoder) error) error {
+	tmp := g.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to create store: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	if err := write(json.NewEncoder(w)); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write store: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write store: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write store: %w", err)
+	}
+	return os.Rename(tmp, g.path)
+}
+
+// scan calls fn for every reading in the store, skipping lines that cannot be decoded
+// (such as a partially written last line after a crash). A missing file is empty.
+func (g *FileHistoryGateway) scan(fn func(data *entity.AirQuality)) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.scanLocked(fn)
+}
+
+// scanLocked is scan for callers that already hold the lock
+func (g *FileHistoryGateway) scanLocked(fn func(data *entity.AirQuality)) error {
+	return scanFile(g.path, func(data *entity.AirQuality) error {
+		fn(data)
+		return nil
+	})
+}
+
+// scanFile calls fn for every reading in the store file at path, line by line,
+// like scan, and stops at the first error of fn
+func scanFile(path string, fn func(data *entity.AirQuality) error) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open store: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var r storedReading
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			continue
+		}
+		if err := fn(r.entity()); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
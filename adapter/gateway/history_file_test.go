@@ -0,0 +1,170 @@
+package gateway
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+)
+
+func TestFileHistoryGateway_PublishAndRange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "readings.jsonl")
+	store := NewFileHistoryGateway(path)
+	base := time.Unix(1767573960, 0)
+
+	store.Publish(&entity.AirQuality{Device: "dev-1", Nickname: "Office", CO2: 700, PM2_5: 2.5, UpdateTime: base})
+	store.Publish(&entity.AirQuality{Device: "dev-1", Nickname: "Office", CO2: 800, UpdateTime: base.Add(time.Minute)})
+	store.Publish(&entity.AirQuality{Device: "dev-2", CO2: 900, UpdateTime: base.Add(2 * time.Minute)})
+
+	// A new instance reads what the previous one wrote
+	readings := NewFileHistoryGateway(path).Range(base, base.Add(time.Minute))
+	if len(readings) != 2 {
+		t.Fatalf("expected 2 readings, got %d", len(readings))
+	}
+	first := readings[0]
+	if first.Device != "dev-1" || first.Nickname != "Office" || first.CO2 != 700 || first.PM2_5 != 2.5 {
+		t.Errorf("unexpected reading: %+v", first)
+	}
+	if !first.UpdateTime.Equal(base) {
+		t.Errorf("expected update time %v, got %v", base, first.UpdateTime)
+	}
+
+	latest := store.Latest()
+	if len(latest) != 2 || latest[0].CO2 != 800 || latest[1].CO2 != 900 {
+		t.Errorf("unexpected latest readings: %+v", latest)
+	}
+}
+
+func TestFileHistoryGateway_MissingFile(t *testing.T) {
+	store := NewFileHistoryGateway(filepath.Join(t.TempDir(), "missing.jsonl"))
+
+	if readings := store.Range(time.Time{}, time.Now()); len(readings) != 0 {
+		t.Errorf("expected no readings, got %d", len(readings))
+	}
+}
+
+func TestFileHistoryGateway_SkipsCorruptLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "readings.jsonl")
+	content := `{"device":"dev-1","co2":700,"update_time":"2026-01-05T00:46:00Z"}
+{"device":"dev-1","co2":
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	readings := NewFileHistoryGateway(path).Range(time.Time{}, time.Now())
+	if len(readings) != 1 || readings[0].CO2 != 700 {
+		t.Errorf("expected only the valid reading, got %+v", readings)
+	}
+}
+
+func TestFileHistoryGateway_Compact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "readings.jsonl")
+	store := NewFileHistoryGateway(path)
+	base := time.Unix(1767573960, 0)
+
+	for i := 0; i < 3; i++ {
+		store.Publish(&entity.AirQuality{Device: "dev-1", CO2: 700 + i, UpdateTime: base.Add(time.Duration(i) * time.Hour)})
+	}
+
+	if err := store.Compact(base.Add(time.Hour)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	readings := store.Range(time.Time{}, base.Add(24*time.Hour))
+	if len(readings) != 2 || readings[0].CO2 != 701 {
+		t.Errorf("expected old reading to be removed, got %+v", readings)
+	}
+}
+
+func TestFileHistoryGateway_CompactOnAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "readings.jsonl")
+	base := time.Unix(1767573960, 0)
+	now := base
+	store := NewFileHistoryGateway(path).WithRetention(2 * time.Hour)
+	store.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		store.Publish(&entity.AirQuality{Device: "dev-1", CO2: 700 + i, UpdateTime: base.Add(time.Duration(i-3) * time.Hour)})
+	}
+	// The first append compacted the store, so expired readings stay until the next day
+	if readings := store.Range(time.Time{}, now); len(readings) != 2 {
+		t.Errorf("expected readings appended after the first compaction to be kept, got %+v", readings)
+	}
+
+	now = base.Add(storeCompactInterval)
+	store.Publish(&entity.AirQuality{Device: "dev-1", CO2: 800, UpdateTime: now})
+
+	readings := store.Range(time.Time{}, now)
+	if len(readings) != 1 || readings[0].CO2 != 800 {
+		t.Errorf("expected expired readings to be removed a day later, got %+v", readings)
+	}
+}
+
+func TestFileHistoryGateway_Each(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "readings.jsonl")
+	store := NewFileHistoryGateway(path)
+	base := time.Unix(1767573960, 0)
+
+	store.Publish(&entity.AirQuality{Device: "dev-1", CO2: 700, UpdateTime: base})
+	store.Publish(&entity.AirQuality{Device: "dev-2", CO2: 800, UpdateTime: base.Add(time.Minute)})
+	store.Publish(&entity.AirQuality{Device: "dev-1", CO2: 900, UpdateTime: base.Add(2 * time.Minute)})
+	store.Publish(&entity.AirQuality{Device: "dev-1", CO2: 1000, UpdateTime: base.Add(time.Hour)})
+
+	var co2 []int
+	err := store.Each(base, base.Add(2*time.Minute), func(data *entity.AirQuality) error {
+		co2 = append(co2, data.CO2)
+		// Appending while a reading is being consumed must not block
+		store.Publish(&entity.AirQuality{Device: "dev-3", CO2: 1, UpdateTime: base.Add(time.Hour)})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(co2) != 3 || co2[0] != 700 || co2[1] != 800 || co2[2] != 900 {
+		t.Errorf("expected readings in range in stored order, got %v", co2)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = store.Each(base, base.Add(time.Hour), func(data *entity.AirQuality) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("expected Each to stop at the first error, got %v after %d calls", err, calls)
+	}
+}
+
+func TestFileHistoryGateway_ReplaceAndSync(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.jsonl")
+	store := NewFileHistoryGateway(path)
+	base := time.Unix(1767573960, 0)
+
+	// Syncing a store that was never written is not an error
+	if err := store.Sync(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	store.Publish(&entity.AirQuality{Device: "dev-1", CO2: 600, UpdateTime: base})
+	if err := store.Replace([]*entity.AirQuality{
+		{Device: "dev-1", CO2: 700, UpdateTime: base.Add(time.Minute)},
+		{Device: "dev-2", CO2: 800, UpdateTime: base.Add(2 * time.Minute)},
+	}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := store.Sync(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	readings := NewFileHistoryGateway(path).Range(time.Time{}, base.Add(time.Hour))
+	if len(readings) != 2 || readings[0].CO2 != 700 || readings[1].CO2 != 800 {
+		t.Errorf("expected the replaced readings, got %+v", readings)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("expected no temporary file to be left, got %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package gateway
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+)
+
+// MemoryHistoryGateway implements ReadingSink and HistoryRepository by keeping
+// readings in memory for a fixed retention period
+type MemoryHistoryGateway struct {
+	retention time.Duration
+
+	mu       sync.RWMutex
+	readings []*entity.AirQuality
+	latest   map[string]*entity.AirQuality
+}
+
+// NewMemoryHistoryGateway creates a new MemoryHistoryGateway with the given retention
+func NewMemoryHistoryGateway(retention time.Duration) *MemoryHistoryGateway {
+	return &MemoryHistoryGateway{
+		retention: retention,
+		latest:    make(map[string]*entity.AirQuality),
+	}
+}
+
+// Publish records the reading and drops readings older than the retention period
+func (g *MemoryHistoryGateway) Publish(data *entity.AirQuality) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// Readings normally arrive in order; keep the slice sorted if they do not
+	ts := data.Timestamp()
+	i := len(g.readings)
+	for i > 0 && g.readings[i-1].Timestamp().After(ts) {
+		i--
+	}
+	g.readings = append(g.readings, nil)
+	copy(g.readings[i+1:], g.readings[i:])
+	g.readings[i] = data
+
+	if prev, ok := g.latest[data.Device]; !ok || !prev.Timestamp().After(ts) {
+		g.latest[data.Device] = data
+	}
+
+	g.prune()
+}
+
+// Range returns the readings taken within [from, to], oldest first
+func (g *MemoryHistoryGateway) Range(from, to time.Time) []*entity.AirQuality {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	start := sort.Search(len(g.readings), func(i int) bool {
+		return !g.readings[i].Timestamp().Before(from)
+	})
+	end := sort.Search(len(g.readings), func(i int) bool {
+		return g.readings[i].Timestamp().After(to)
+	})
+	if start >= end {
+		return nil
+	}
+
+	result := make([]*entity.AirQuality, end-start)
+	copy(result, g.readings[start:end])
+	return result
+}
+
+// Each calls fn for every reading of Range, stopping at the first error of fn
+func (g *MemoryHistoryGateway) Each(from, to time.Time, fn func(data *entity.AirQuality) error) error {
+	for _, data := range g.Range(from, to) {
+		if err := fn(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Latest returns the most recent reading of every known device, ordered by device.
+// Devices are kept even after their readings fall outside the retention period.
+func (g *MemoryHistoryGateway) Latest() []*entity.AirQuality {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	result := make([]*entity.AirQuality, 0, len(g.latest))
+	for _, data := range g.latest {
+		result = append(result, data)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Device < result[j].Device
+	})
+	return result
+}
+
+// prune drops readings older than the retention period relative to the newest reading,
+// so that replayed historical data is retained as well; callers must hold the lock
+func (g *MemoryHistoryGateway) prune() {
+	if len(g.readings) == 0 {
+		return
+	}
+	cutoff := g.readings[len(g.readings)-1].Timestamp().Add(-g.retention)
+	n := sort.Search(len(g.readings), func(i int) bool {
+		return !g.readings[i].Timestamp().Before(cutoff)
+	})
+	if n > 0 {
+		g.readings = append(g.readings[:0], g.readings[n:]...)
+	}
+}
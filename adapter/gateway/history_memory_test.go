@@ -0,0 +1,82 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+)
+
+func TestMemoryHistoryGateway_Range(t *testing.T) {
+	history := NewMemoryHistoryGateway(time.Hour)
+	base := time.Unix(1767573960, 0)
+
+	for i := 0; i < 5; i++ {
+		history.Publish(&entity.AirQuality{CO2: 700 + i, UpdateTime: base.Add(time.Duration(i) * time.Minute)})
+	}
+
+	readings := history.Range(base.Add(time.Minute), base.Add(3*time.Minute))
+	if len(readings) != 3 {
+		t.Fatalf("expected 3 readings, got %d", len(readings))
+	}
+	if readings[0].CO2 != 701 || readings[2].CO2 != 703 {
+		t.Errorf("unexpected readings: %d..%d", readings[0].CO2, readings[2].CO2)
+	}
+
+	if readings := history.Range(base.Add(time.Hour), base.Add(2*time.Hour)); len(readings) != 0 {
+		t.Errorf("expected no readings, got %d", len(readings))
+	}
+}
+
+func TestMemoryHistoryGateway_OutOfOrder(t *testing.T) {
+	history := NewMemoryHistoryGateway(time.Hour)
+	base := time.Unix(1767573960, 0)
+
+	history.Publish(&entity.AirQuality{CO2: 1, UpdateTime: base})
+	history.Publish(&entity.AirQuality{CO2: 3, UpdateTime: base.Add(2 * time.Minute)})
+	history.Publish(&entity.AirQuality{CO2: 2, UpdateTime: base.Add(time.Minute)})
+
+	readings := history.Range(base, base.Add(time.Hour))
+	for i, r := range readings {
+		if r.CO2 != i+1 {
+			t.Errorf("expected readings to be sorted, got CO2 %d at index %d", r.CO2, i)
+		}
+	}
+}
+
+func TestMemoryHistoryGateway_Retention(t *testing.T) {
+	history := NewMemoryHistoryGateway(time.Hour)
+	base := time.Unix(1767573960, 0)
+
+	history.Publish(&entity.AirQuality{CO2: 1, UpdateTime: base})
+	history.Publish(&entity.AirQuality{CO2: 2, UpdateTime: base.Add(30 * time.Minute)})
+	history.Publish(&entity.AirQuality{CO2: 3, UpdateTime: base.Add(90 * time.Minute)})
+
+	readings := history.Range(base, base.Add(2*time.Hour))
+	if len(readings) != 2 || readings[0].CO2 != 2 {
+		t.Errorf("expected reading outside retention to be dropped, got %d readings", len(readings))
+	}
+}
+
+func TestMemoryHistoryGateway_Latest(t *testing.T) {
+	history := NewMemoryHistoryGateway(time.Hour)
+	base := time.Unix(1767573960, 0)
+
+	history.Publish(&entity.AirQuality{Device: "dev-2", CO2: 1, UpdateTime: base})
+	history.Publish(&entity.AirQuality{Device: "dev-1", CO2: 2, UpdateTime: base})
+	history.Publish(&entity.AirQuality{Device: "dev-1", CO2: 3, UpdateTime: base.Add(time.Minute)})
+	history.Publish(&entity.AirQuality{Device: "dev-1", CO2: 4, UpdateTime: base.Add(-time.Minute)})
+	// dev-2 falls out of the retention period but stays known
+	history.Publish(&entity.AirQuality{Device: "dev-1", CO2: 5, UpdateTime: base.Add(2 * time.Hour)})
+
+	latest := history.Latest()
+	if len(latest) != 2 {
+		t.Fatalf("expected 2 devices, got %d", len(latest))
+	}
+	if latest[0].Device != "dev-1" || latest[0].CO2 != 5 {
+		t.Errorf("expected latest reading of dev-1 to have CO2 5, got %+v", latest[0])
+	}
+	if latest[1].Device != "dev-2" || latest[1].CO2 != 1 {
+		t.Errorf("expected latest reading of dev-2 to have CO2 1, got %+v", latest[1])
+	}
+}
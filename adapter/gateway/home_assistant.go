@@ -0,0 +1,292 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+)
+
+// offlineAfterFailures is the number of consecutive failed fetches after which
+// the devices are reported unavailable, so that a single timeout does not make
+// every sensor flap in Home Assistant
+const offlineAfterFailures = 3
+
+// HomeAssistantOptions configures HomeAssistantGateway
+type HomeAssistantOptions struct {
+	// TopicPrefix prefixes the state and availability topics; empty is "airq"
+	TopicPrefix string
+	// DiscoveryPrefix is the discovery prefix of Home Assistant; empty is "homeassistant"
+	DiscoveryPrefix string
+}
+
+func (o HomeAssistantOptions) topicPrefix() string {
+	if o.TopicPrefix == "" {
+		return "airq"
+	}
+	return o.TopicPrefix
+}
+
+func (o HomeAssistantOptions) discoveryPrefix() string {
+	if o.DiscoveryPrefix == "" {
+		return "homeassistant"
+	}
+	return o.DiscoveryPrefix
+}
+
+// StatusTopic returns the availability topic of the exporter itself, to be
+// used as the last will of the MQTT connection
+func (o HomeAssistantOptions) StatusTopic() string {
+	return o.topicPrefix() + "/status"
+}
+
+// homeAssistantDeviceClasses maps field keys to Home Assistant sensor device
+// classes; PM4.0 and the VOC and NOx indices have none
+var homeAssistantDeviceClasses = map[string]string{
+	"pm1_0":             "pm1",
+	"pm2_5":             "pm25",
+	"pm10_0":            "pm10",
+	"humidity":          "humidity",
+	"temperature":       "temperature",
+	"co2":               "carbon_dioxide",
+	"scd40_humidity":    "humidity",
+	"scd40_temperature": "temperature",
+}
+
+// topicUnsafe matches characters not allowed in discovery node and object IDs
+var topicUnsafe = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// HomeAssistantGateway implements ReadingSink and FetchObserver by publishing
+// readings to MQTT, with retained Home Assistant discovery messages that create
+// one sensor per field, grouped into a device per AirQ
+type HomeAssistantGateway struct {
+	client  MQTTClient
+	options HomeAssistantOptions
+
+	mu       sync.Mutex
+	devices  map[string]*homeAssistantDevice
+	failures int
+}
+
+// homeAssistantDevice is the published state of a device
+type homeAssistantDevice struct {
+	node string
+	// discovery identifies the published discovery messages, fields their field keys
+	discovery string
+	fields    []string
+	online    bool
+	last      *entity.AirQuality
+}
+
+// NewHomeAssistantGateway creates a new HomeAssistantGateway publishing with
+// client. Everything is published again when the client reconnects or Home
+// Assistant restarts.
+func NewHomeAssistantGateway(client MQTTClient, options HomeAssistantOptions) *HomeAssistantGateway {
+	g := &HomeAssistantGateway{
+		client:  client,
+		options: options,
+		devices: make(map[string]*homeAssistantDevice),
+	}
+	client.OnConnect(g.republish)
+	client.Subscribe(options.discoveryPrefix()+"/status", func(payload []byte) {
+		if string(payload) == mqttOnline {
+			g.republish()
+		}
+	})
+	return g
+}
+
+// Publish implements ReadingSink
+func (g *HomeAssistantGateway) Publish(data *entity.AirQuality) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	device, ok := g.devices[data.Device]
+	if !ok {
+		device = &homeAssistantDevice{node: topicUnsafe.ReplaceAllString(data.Device, "_")}
+		g.devices[data.Device] = device
+	}
+	device.last = data
+	g.publishDiscovery(device, false)
+	g.setAvailability(device, true, false)
+	g.publishState(device)
+}
+
+// FetchSucceeded implements FetchObserver
+func (g *HomeAssistantGateway) FetchSucceeded(data *entity.AirQuality) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.failures = 0
+	if device, ok := g.devices[data.Device]; ok {
+		g.setAvailability(device, true, false)
+	}
+}
+
+// FetchFailed implements FetchObserver
+func (g *HomeAssistantGateway) FetchFailed(err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.failures++
+	if g.failures < offlineAfterFailures {
+		return
+	}
+	for _, device := range g.devices {
+		g.setAvailability(device, false, false)
+	}
+}
+
+// republish sends the discovery messages, availability and last state of every device again
+func (g *HomeAssistantGateway) republish() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, device := range g.devices {
+		g.publishDiscovery(device, true)
+		g.setAvailability(device, device.online, true)
+		g.publishState(device)
+	}
+}
+
+// homeAssistantDiscovery is the discovery message of a sensor
+type homeAssistantDiscovery struct {
+	Name              string                      `json:"name"`
+	UniqueID          string                      `json:"unique_id"`
+	StateTopic        string                      `json:"state_topic"`
+	ValueTemplate     string                      `json:"value_template"`
+	DeviceClass       string                      `json:"device_class,omitempty"`
+	UnitOfMeasurement string                      `json:"unit_of_measurement,omitempty"`
+	StateClass        string                      `json:"state_class,omitempty"`
+	EntityCategory    string                      `json:"entity_category,omitempty"`
+	Availability      []homeAssistantAvailability `json:"availability"`
+	AvailabilityMode  string                      `json:"availability_mode"`
+	Device            homeAssistantDeviceInfo     `json:"device"`
+	Origin            homeAssistantOrigin         `json:"origin"`
+}
+
+type homeAssistantAvailability struct {
+	Topic string `json:"topic"`
+}
+
+type homeAssistantDeviceInfo struct {
+	Identifiers []string `json:"identifiers"`
+	Name        string   `json:"name"`
+}
+
+type homeAssistantOrigin struct {
+	Name string `json:"name"`
+}
+
+// publishDiscovery publishes the discovery messages of the measured fields when
+// they, or the nickname, changed, and removes those of fields no longer measured
+func (g *HomeAssistantGateway) publishDiscovery(device *homeAssistantDevice, force bool) {
+	data := device.last
+	var fields []string
+	measured := make(map[string]bool)
+	for _, f := range entity.Fields {
+		if data.Measures(f.Key) {
+			fields = append(fields, f.Key)
+			measured[f.Key] = true
+		}
+	}
+	signature := data.Nickname + "\x00" + strings.Join(fields, ",")
+	if signature == device.discovery && !force {
+		return
+	}
+
+	// An empty retained message deletes the sensor in Home Assistant
+	for _, old := range device.fields {
+		if !measured[old] {
+			g.client.Publish(g.discoveryTopic("sensor", device, old), true, nil)
+		}
+	}
+
+	name := data.Nickname
+	if name == "" {
+		name = data.Device
+	}
+	base := homeAssistantDiscovery{
+		StateTopic: g.deviceTopic(device, "state"),
+		Availability: []homeAssistantAvailability{
+			{Topic: g.options.StatusTopic()},
+			{Topic: g.deviceTopic(device, "availability")},
+		},
+		AvailabilityMode: "all",
+		Device:           homeAssistantDeviceInfo{Identifiers: []string{data.Device}, Name: name},
+		Origin:           homeAssistantOrigin{Name: "m5stack_airq_exporter"},
+	}
+	for _, key := range fields {
+		f, _ := entity.FieldByKey(key)
+		config := base
+		config.Name = f.Description
+		config.UniqueID = device.node + "_" + key
+		config.ValueTemplate = fmt.Sprintf("{{ value_json.%s }}", key)
+		config.DeviceClass = homeAssistantDeviceClasses[key]
+		config.UnitOfMeasurement = f.Unit
+		config.StateClass = "measurement"
+		g.publishJSON(g.discoveryTopic("sensor", device, key), true, config)
+	}
+
+	warmingUp := base
+	warmingUp.Name = "Sensors warming up"
+	warmingUp.UniqueID = device.node + "_warming_up"
+	warmingUp.ValueTemplate = "{{ 'ON' if value_json.warming_up else 'OFF' }}"
+	warmingUp.EntityCategory = "diagnostic"
+	g.publishJSON(g.discoveryTopic("binary_sensor", device, "warming_up"), true, warmingUp)
+
+	// Lets automations skip readings taken while the device is serviced
+	maintenance := base
+	maintenance.Name = "Maintenance"
+	maintenance.UniqueID = device.node + "_maintenance"
+	maintenance.ValueTemplate = "{{ 'ON' if value_json.maintenance else 'OFF' }}"
+	maintenance.EntityCategory = "diagnostic"
+	g.publishJSON(g.discoveryTopic("binary_sensor", device, "maintenance"), true, maintenance)
+
+	device.discovery = signature
+	device.fields = fields
+}
+
+// setAvailability publishes the availability of a device when it changed
+func (g *HomeAssistantGateway) setAvailability(device *homeAssistantDevice, online, force bool) {
+	if device.online == online && !force {
+		return
+	}
+	device.online = online
+	payload := mqttOffline
+	if online {
+		payload = mqttOnline
+	}
+	g.client.Publish(g.deviceTopic(device, "availability"), true, []byte(payload))
+}
+
+// publishState publishes the measured fields of the last reading as one JSON object
+func (g *HomeAssistantGateway) publishState(device *homeAssistantDevice) {
+	data := device.last
+	state := map[string]any{"warming_up": data.WarmingUp, "maintenance": data.Maintenance}
+	for _, f := range entity.Fields {
+		if data.Measures(f.Key) {
+			state[f.Key] = f.Value(data)
+		}
+	}
+	g.publishJSON(g.deviceTopic(device, "state"), false, state)
+}
+
+func (g *HomeAssistantGateway) publishJSON(topic string, retained bool, value any) {
+	payload, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	g.client.Publish(topic, retained, payload)
+}
+
+func (g *HomeAssistantGateway) deviceTopic(device *homeAssistantDevice, name string) string {
+	return g.options.topicPrefix() + "/" + device.node + "/" + name
+}
+
+func (g *HomeAssistantGateway) discoveryTopic(component string, device *homeAssistantDevice, object string) string {
+	return g.options.discoveryPrefix() + "/" + component + "/" + device.node + "/" + object + "/config"
+}
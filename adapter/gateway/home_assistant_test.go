@@ -0,0 +1,159 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+)
+
+// mockMQTTClient is a mock implementation of MQTTClient recording the last
+// message of every topic
+type mockMQTTClient struct {
+	messages      map[string]mockMQTTMessage
+	published     int
+	subscriptions map[string]func([]byte)
+	onConnect     []func()
+}
+
+type mockMQTTMessage struct {
+	retained bool
+	payload  []byte
+}
+
+func newMockMQTTClient() *mockMQTTClient {
+	return &mockMQTTClient{
+		messages:      make(map[string]mockMQTTMessage),
+		subscriptions: make(map[string]func([]byte)),
+	}
+}
+
+func (m *mockMQTTClient) Publish(topic string, retained bool, payload []byte) {
+	m.messages[topic] = mockMQTTMessage{retained: retained, payload: payload}
+	m.published++
+}
+
+func (m *mockMQTTClient) Subscribe(topic string, handler func(payload []byte)) {
+	m.subscriptions[topic] = handler
+}
+
+func (m *mockMQTTClient) OnConnect(handler func()) {
+	m.onConnect = append(m.onConnect, handler)
+}
+
+func TestHomeAssistantGateway_Publish(t *testing.T) {
+	client := newMockMQTTClient()
+	gateway := NewHomeAssistantGateway(client, HomeAssistantOptions{})
+
+	gateway.Publish(&entity.AirQuality{Device: "dev.1", Nickname: "Office", CO2: 725, Temperature: 23.5})
+
+	config, ok := client.messages["homeassistant/sensor/dev_1/co2/config"]
+	if !ok || !config.retained {
+		t.Fatalf("expected a retained CO2 discovery message, got %+v", client.messages)
+	}
+	var discovery homeAssistantDiscovery
+	if err := json.Unmarshal(config.payload, &discovery); err != nil {
+		t.Fatalf("failed to parse discovery message: %v", err)
+	}
+	if discovery.DeviceClass != "carbon_dioxide" || discovery.UnitOfMeasurement != "ppm" || discovery.StateClass != "measurement" {
+		t.Errorf("unexpected CO2 sensor: %+v", discovery)
+	}
+	if discovery.StateTopic != "airq/dev_1/state" || discovery.UniqueID != "dev_1_co2" {
+		t.Errorf("unexpected topics of CO2 sensor: %+v", discovery)
+	}
+	if discovery.Device.Name != "Office" || len(discovery.Device.Identifiers) != 1 || discovery.Device.Identifiers[0] != "dev.1" {
+		t.Errorf("unexpected device of CO2 sensor: %+v", discovery.Device)
+	}
+	if len(discovery.Availability) != 2 || discovery.Availability[0].Topic != "airq/status" || discovery.Availability[1].Topic != "airq/dev_1/availability" {
+		t.Errorf("unexpected availability of CO2 sensor: %+v", discovery.Availability)
+	}
+	if _, ok := client.messages["homeassistant/binary_sensor/dev_1/warming_up/config"]; !ok {
+		t.Error("expected a warming up discovery message")
+	}
+
+	if got := string(client.messages["airq/dev_1/availability"].payload); got != "online" {
+		t.Errorf("expected device to be online, got %q", got)
+	}
+	var state map[string]any
+	if err := json.Unmarshal(client.messages["airq/dev_1/state"].payload, &state); err != nil {
+		t.Fatalf("failed to parse state: %v", err)
+	}
+	if state["co2"] != 725.0 || state["temperature"] != 23.5 || state["warming_up"] != false {
+		t.Errorf("unexpected state: %v", state)
+	}
+}
+
+func TestHomeAssistantGateway_DiscoveryOnlyWhenChanged(t *testing.T) {
+	client := newMockMQTTClient()
+	gateway := NewHomeAssistantGateway(client, HomeAssistantOptions{TopicPrefix: "home/airq", DiscoveryPrefix: "ha"})
+
+	gateway.Publish(&entity.AirQuality{Device: "dev", CO2: 700})
+	published := client.published
+	gateway.Publish(&entity.AirQuality{Device: "dev", CO2: 710})
+	if got := client.published - published; got != 1 {
+		t.Errorf("expected only the state to be published again, got %d messages", got)
+	}
+	if _, ok := client.messages["home/airq/dev/state"]; !ok {
+		t.Error("expected state under the topic prefix")
+	}
+
+	// A field no longer measured is removed with an empty retained message
+	gateway.Publish(&entity.AirQuality{Device: "dev", Temperature: 21, Measured: map[string]bool{"temperature": true}})
+	removed := client.messages["ha/sensor/dev/co2/config"]
+	if !removed.retained || len(removed.payload) != 0 {
+		t.Errorf("expected CO2 sensor to be removed, got %+v", removed)
+	}
+	if _, ok := client.messages["ha/sensor/dev/temperature/config"]; !ok {
+		t.Error("expected temperature sensor to be kept")
+	}
+}
+
+func TestHomeAssistantGateway_Availability(t *testing.T) {
+	client := newMockMQTTClient()
+	gateway := NewHomeAssistantGateway(client, HomeAssistantOptions{})
+	data := &entity.AirQuality{Device: "dev", CO2: 700}
+	gateway.Publish(data)
+
+	for i := 1; i < offlineAfterFailures; i++ {
+		gateway.FetchFailed(errors.New("timeout"))
+	}
+	if got := string(client.messages["airq/dev/availability"].payload); got != "online" {
+		t.Errorf("expected device to stay online before %d failures, got %q", offlineAfterFailures, got)
+	}
+
+	gateway.FetchFailed(errors.New("timeout"))
+	if got := string(client.messages["airq/dev/availability"].payload); got != "offline" {
+		t.Errorf("expected device to be offline, got %q", got)
+	}
+
+	gateway.FetchSucceeded(data)
+	if got := string(client.messages["airq/dev/availability"].payload); got != "online" {
+		t.Errorf("expected device to be online again, got %q", got)
+	}
+}
+
+func TestHomeAssistantGateway_RepublishWhenHomeAssistantStarts(t *testing.T) {
+	client := newMockMQTTClient()
+	gateway := NewHomeAssistantGateway(client, HomeAssistantOptions{})
+	gateway.Publish(&entity.AirQuality{Device: "dev", CO2: 700})
+
+	delete(client.messages, "homeassistant/sensor/dev/co2/config")
+	client.subscriptions["homeassistant/status"]([]byte("offline"))
+	if _, ok := client.messages["homeassistant/sensor/dev/co2/config"]; ok {
+		t.Error("expected no republish when Home Assistant goes offline")
+	}
+
+	client.subscriptions["homeassistant/status"]([]byte("online"))
+	if _, ok := client.messages["homeassistant/sensor/dev/co2/config"]; !ok {
+		t.Error("expected discovery messages to be republished")
+	}
+
+	delete(client.messages, "airq/dev/state")
+	for _, handler := range client.onConnect {
+		handler()
+	}
+	if _, ok := client.messages["airq/dev/state"]; !ok {
+		t.Error("expected state to be republished after reconnecting")
+	}
+}
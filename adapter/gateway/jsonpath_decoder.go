@@ -0,0 +1,330 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+	"github.com/suzutan/m5stack_airq_exporter/domain/repository"
+	"go.yaml.in/yaml/v2"
+)
+
+// JSONPathDecoderConfig maps the fields of a reading to JSONPath expressions,
+// for data sources other than EzData such as Home Assistant REST sensors
+type JSONPathDecoderConfig struct {
+	// Device and Nickname identify the device when the payload does not
+	Device   string `yaml:"device"`
+	Nickname string `yaml:"nickname"`
+	// DevicePath, NicknamePath and UpdateTimePath read the metadata from the payload
+	DevicePath     string `yaml:"device_path"`
+	NicknamePath   string `yaml:"nickname_path"`
+	UpdateTimePath string `yaml:"update_time_path"`
+	// Fields maps field keys, such as co2 and temperature, to the paths of their values
+	Fields map[string]string `yaml:"fields"`
+}
+
+// JSONPathDecoder implements PayloadDecoder with a JSONPathDecoderConfig. Paths
+// support the $.name, $['name'] and $[index] forms; string values that hold
+// JSON, like the EzData value field, are parsed when a path continues into them.
+type JSONPathDecoder struct {
+	config     JSONPathDecoderConfig
+	device     jsonPath
+	nickname   jsonPath
+	updateTime jsonPath
+	// fields lists the mapped fields in display order
+	fields []jsonPathField
+}
+
+// jsonPathField is a field with the path of its value
+type jsonPathField struct {
+	field entity.Field
+	path  jsonPath
+}
+
+// LoadJSONPathDecoder reads a YAML JSONPathDecoderConfig
+func LoadJSONPathDecoder(path string) (*JSONPathDecoder, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read decoder config: %w", err)
+	}
+
+	var config JSONPathDecoderConfig
+	if err := yaml.UnmarshalStrict(content, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse decoder config: %w", err)
+	}
+	return NewJSONPathDecoder(config)
+}
+
+// NewJSONPathDecoder validates the config and creates a new JSONPathDecoder
+func NewJSONPathDecoder(config JSONPathDecoderConfig) (*JSONPathDecoder, error) {
+	if config.Device == "" && config.DevicePath == "" {
+		return nil, errors.New("device or device_path is required")
+	}
+	if len(config.Fields) == 0 {
+		return nil, errors.New("at least one field is required")
+	}
+
+	d := &JSONPathDecoder{config: config}
+	var err error
+	for _, p := range []struct {
+		name string
+		expr string
+		path *jsonPath
+	}{
+		{"device_path", config.DevicePath, &d.device},
+		{"nickname_path", config.NicknamePath, &d.nickname},
+		{"update_time_path", config.UpdateTimePath, &d.updateTime},
+	} {
+		if p.expr == "" {
+			continue
+		}
+		if *p.path, err = parseJSONPath(p.expr); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", p.name, err)
+		}
+	}
+
+	for key, expr := range config.Fields {
+		f, ok := entity.FieldByKey(key)
+		if !ok {
+			return nil, fmt.Errorf("unknown field: %s", key)
+		}
+		path, err := parseJSONPath(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path of field %s: %w", key, err)
+		}
+		d.fields = append(d.fields, jsonPathField{field: f, path: path})
+	}
+	order := make(map[string]int, len(entity.Fields))
+	for i, f := range entity.Fields {
+		order[f.Key] = i
+	}
+	sort.Slice(d.fields, func(i, j int) bool { return order[d.fields[i].field.Key] < order[d.fields[j].field.Key] })
+	return d, nil
+}
+
+// Decode implements PayloadDecoder. Fields whose path is missing or does not
+// hold a number are not measured; a payload without any of them is an error.
+func (d *JSONPathDecoder) Decode(body []byte, step func(DecodeStep)) (*entity.AirQuality, error) {
+	if step == nil {
+		step = func(DecodeStep) {}
+	}
+
+	root, err := decodeJSON(body)
+	if err != nil {
+		return nil, classify(repository.ErrorClassDecode, fmt.Errorf("failed to parse response: %w", err))
+	}
+	step(DecodeStep{Name: "parse response", Detail: fmt.Sprintf("%d bytes of JSON", len(body))})
+
+	data := &entity.AirQuality{
+		Device:   d.config.Device,
+		Nickname: d.config.Nickname,
+		Measured: make(map[string]bool),
+	}
+	if d.device != nil {
+		value, ok := d.device.lookup(root)
+		if !ok || jsonString(value) == "" {
+			return nil, classify(repository.ErrorClassDecode, fmt.Errorf("device not found at %s", d.device))
+		}
+		data.Device = jsonString(value)
+	}
+	if d.nickname != nil {
+		if value, ok := d.nickname.lookup(root); ok {
+			data.Nickname = jsonString(value)
+		}
+	}
+	if d.updateTime != nil {
+		if value, ok := d.updateTime.lookup(root); ok {
+			data.UpdateTime = jsonTime(value)
+		}
+	}
+	step(DecodeStep{Name: "read device", Detail: fmt.Sprintf("device=%q nickname=%q update_time=%s", data.Device, data.Nickname, formatTime(data.UpdateTime))})
+
+	for _, f := range d.fields {
+		value, ok := f.path.lookup(root)
+		if !ok {
+			step(DecodeStep{Name: "read " + f.field.Key, Detail: fmt.Sprintf("%s not found, not measured", f.path)})
+			continue
+		}
+		v, ok := jsonNumber(value)
+		if !ok {
+			step(DecodeStep{Name: "read " + f.field.Key, Detail: fmt.Sprintf("%s is not a number, not measured", f.path)})
+			continue
+		}
+		f.field.Set(data, v)
+		data.Measured[f.field.Key] = true
+		step(DecodeStep{Name: "read " + f.field.Key, Detail: fmt.Sprintf("%s = %g", f.path, v)})
+	}
+	if len(data.Measured) == 0 {
+		return nil, classify(repository.ErrorClassDecode, errors.New("none of the configured fields found in the response"))
+	}
+	return data, nil
+}
+
+// decodeJSON parses JSON keeping numbers exact
+func decodeJSON(content []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// jsonString returns a string or number as a string
+func jsonString(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	}
+	return ""
+}
+
+// jsonNumber returns a number, or a string holding one, as a float
+func jsonNumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// jsonTime returns Unix seconds or milliseconds, or an RFC 3339 string, as a
+// time; it returns the zero time for other values
+func jsonTime(value any) time.Time {
+	s := jsonString(value)
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t
+	}
+	return parseEzDataTime(s)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "unknown"
+	}
+	return t.Format(time.RFC3339)
+}
+
+// jsonPath is a parsed path of object keys and array indices
+type jsonPath []jsonPathSegment
+
+// jsonPathSegment is an object key, or an array index when isIndex is set
+type jsonPathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// parseJSONPath parses a path such as $.attributes.co2, $['sen55']['pm2.5'] or $.values[0]
+func parseJSONPath(expr string) (jsonPath, error) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(expr), "$")
+	if !ok {
+		return nil, fmt.Errorf("path %q must start with $", expr)
+	}
+
+	var path jsonPath
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			key := rest[1 : end+1]
+			if key == "" {
+				return nil, fmt.Errorf("empty key in path %q", expr)
+			}
+			path = append(path, jsonPathSegment{key: key})
+			rest = rest[end+1:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed bracket in path %q", expr)
+			}
+			inner := rest[1:end]
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				path = append(path, jsonPathSegment{key: inner[1 : len(inner)-1]})
+			} else if index, err := strconv.Atoi(inner); err == nil && index >= 0 {
+				path = append(path, jsonPathSegment{index: index, isIndex: true})
+			} else {
+				return nil, fmt.Errorf("invalid bracket %q in path %q", inner, expr)
+			}
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("unexpected %q in path %q", rest[0], expr)
+		}
+	}
+	return path, nil
+}
+
+// lookup returns the value at the path, parsing strings that hold JSON on the way
+func (p jsonPath) lookup(root any) (any, bool) {
+	node := root
+	for _, segment := range p {
+		if s, ok := node.(string); ok {
+			node = parseEmbeddedJSON(s)
+		}
+		switch v := node.(type) {
+		case map[string]any:
+			if segment.isIndex {
+				return nil, false
+			}
+			value, ok := v[segment.key]
+			if !ok {
+				return nil, false
+			}
+			node = value
+		case []any:
+			if !segment.isIndex || segment.index >= len(v) {
+				return nil, false
+			}
+			node = v[segment.index]
+		default:
+			return nil, false
+		}
+	}
+	return node, true
+}
+
+// parseEmbeddedJSON parses a string holding JSON, which may be escaped once more
+func parseEmbeddedJSON(s string) any {
+	if unquoted, err := strconv.Unquote(`"` + s + `"`); err == nil {
+		s = unquoted
+	}
+	value, err := decodeJSON([]byte(s))
+	if err != nil {
+		return nil
+	}
+	return value
+}
+
+// String formats the path, with brackets only where needed
+func (p jsonPath) String() string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, segment := range p {
+		switch {
+		case segment.isIndex:
+			fmt.Fprintf(&b, "[%d]", segment.index)
+		case metricNamePattern.MatchString(segment.key):
+			b.WriteString("." + segment.key)
+		default:
+			fmt.Fprintf(&b, "[%q]", segment.key)
+		}
+	}
+	return b.String()
+}
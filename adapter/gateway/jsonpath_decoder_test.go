@@ -0,0 +1,133 @@
+package gateway
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJSONPathDecoder_HomeAssistant(t *testing.T) {
+	decoder, err := NewJSONPathDecoder(JSONPathDecoderConfig{
+		DevicePath:     "$.entity_id",
+		NicknamePath:   "$.attributes.friendly_name",
+		UpdateTimePath: "$.last_updated",
+		Fields: map[string]string{
+			"co2":               "$.state",
+			"scd40_temperature": "$.attributes['temperature']",
+			"scd40_humidity":    "$.attributes.humidity",
+		},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	body := `{"entity_id":"sensor.office_co2","state":"812.4","attributes":{"friendly_name":"Office CO2","temperature":22.5},"last_updated":"2026-10-19T08:00:00+00:00"}`
+	var steps []string
+	data, err := decoder.Decode([]byte(body), func(step DecodeStep) { steps = append(steps, step.Name) })
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if data.Device != "sensor.office_co2" || data.Nickname != "Office CO2" {
+		t.Errorf("expected device sensor.office_co2 named Office CO2, got %s named %s", data.Device, data.Nickname)
+	}
+	if want := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC); !data.UpdateTime.Equal(want) {
+		t.Errorf("expected update time %v, got %v", want, data.UpdateTime)
+	}
+	if data.CO2 != 812 {
+		t.Errorf("expected CO2 to be 812, got %d", data.CO2)
+	}
+	if data.SCD40Temperature != 22.5 {
+		t.Errorf("expected temperature to be 22.5, got %f", data.SCD40Temperature)
+	}
+	// The humidity attribute is missing
+	for key, want := range map[string]bool{"co2": true, "scd40_temperature": true, "scd40_humidity": false, "pm2_5": false} {
+		if got := data.Measures(key); got != want {
+			t.Errorf("expected Measures(%s) to be %v, got %v", key, want, got)
+		}
+	}
+	if len(steps) != 5 {
+		t.Errorf("expected 5 steps, got %v", steps)
+	}
+}
+
+func TestJSONPathDecoder_EmbeddedJSON(t *testing.T) {
+	decoder, err := NewJSONPathDecoder(JSONPathDecoderConfig{
+		Device: "bridge",
+		Fields: map[string]string{
+			"pm2_5": `$.data.value.sen55["pm2.5"]`,
+			"co2":   "$.data.value.scd40.co2",
+		},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// The value field holds double-escaped JSON, as with EzData
+	body := `{"data":{"value":"{\\\"sen55\\\":{\\\"pm2.5\\\":2.5},\\\"scd40\\\":{\\\"co2\\\":725}}"}}`
+	data, err := decoder.Decode([]byte(body), nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if data.Device != "bridge" || data.PM2_5 != 2.5 || data.CO2 != 725 {
+		t.Errorf("expected bridge with PM2.5 2.5 and CO2 725, got %s with %f and %d", data.Device, data.PM2_5, data.CO2)
+	}
+}
+
+func TestJSONPathDecoder_Errors(t *testing.T) {
+	decoder, err := NewJSONPathDecoder(JSONPathDecoderConfig{
+		DevicePath: "$.id",
+		Fields:     map[string]string{"co2": "$.values[1]"},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "invalid JSON", body: `{`},
+		{name: "missing device", body: `{"values":[1,2]}`},
+		{name: "no field found", body: `{"id":"a","values":[1]}`},
+		{name: "unavailable", body: `{"id":"a","values":[1,"unavailable"]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decoder.Decode([]byte(tt.body), nil); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestLoadJSONPathDecoder(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{name: "valid", content: "device: office\nfields:\n  co2: $.co2\n"},
+		{name: "no device", content: "fields:\n  co2: $.co2\n", wantErr: true},
+		{name: "no fields", content: "device: office\n", wantErr: true},
+		{name: "unknown field", content: "device: office\nfields:\n  ozone: $.o3\n", wantErr: true},
+		{name: "path without root", content: "device: office\nfields:\n  co2: co2\n", wantErr: true},
+		{name: "unclosed bracket", content: "device: office\nfields:\n  co2: $.values[0\n", wantErr: true},
+		{name: "unknown option", content: "device: office\nfield:\n  co2: $.co2\n", wantErr: true},
+	}
+
+	dir := t.TempDir()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "decoder.yml")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+			_, err := LoadJSONPathDecoder(path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package gateway
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+	"github.com/suzutan/m5stack_airq_exporter/usecase"
+	"go.yaml.in/yaml/v2"
+)
+
+// maintenanceWindowConfig is a maintenance window in the YAML file, with a Go
+// duration instead of seconds
+type maintenanceWindowConfig struct {
+	Device   string        `yaml:"device"`
+	Reason   string        `yaml:"reason"`
+	Schedule string        `yaml:"schedule"`
+	Duration time.Duration `yaml:"duration"`
+	Start    time.Time     `yaml:"start"`
+	End      time.Time     `yaml:"end"`
+}
+
+// LoadMaintenanceWindows reads a YAML list of recurring and one-off maintenance windows
+func LoadMaintenanceWindows(path string) ([]entity.MaintenanceWindow, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read maintenance windows: %w", err)
+	}
+
+	var configs []maintenanceWindowConfig
+	if err := yaml.UnmarshalStrict(content, &configs); err != nil {
+		return nil, fmt.Errorf("failed to parse maintenance windows: %w", err)
+	}
+	windows := make([]entity.MaintenanceWindow, 0, len(configs))
+	for i, c := range configs {
+		window := entity.MaintenanceWindow{
+			Device:   c.Device,
+			Reason:   c.Reason,
+			Schedule: c.Schedule,
+			Duration: c.Duration.Seconds(),
+			Start:    c.Start,
+			End:      c.End,
+		}
+		if err := usecase.ValidateMaintenanceWindow(window); err != nil {
+			return nil, fmt.Errorf("invalid maintenance window %d: %w", i+1, err)
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
@@ -0,0 +1,59 @@
+package gateway
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadMaintenanceWindows(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "maintenance.yml")
+	content := `
+- device: Office
+  reason: Weekly cleaning
+  schedule: "0 9 * * wed"
+  duration: 2h
+- device: airq-3f2a9c81d0e4
+  reason: Recalibration
+  start: 2026-11-02T08:00:00+09:00
+  end: 2026-11-02T12:00:00+09:00
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write windows: %v", err)
+	}
+
+	windows, err := LoadMaintenanceWindows(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(windows) != 2 {
+		t.Fatalf("expected 2 windows, got %d", len(windows))
+	}
+	if !windows[0].Recurring() || windows[0].Duration != 7200 || windows[0].Device != "Office" {
+		t.Errorf("unexpected recurring window: %+v", windows[0])
+	}
+	if windows[1].Recurring() || !windows[1].End.Equal(time.Date(2026, 11, 2, 3, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected one-off window: %+v", windows[1])
+	}
+}
+
+func TestLoadMaintenanceWindows_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"invalid schedule": "- schedule: '0 9 * *'\n  duration: 1h\n",
+		"no duration":      "- schedule: '0 9 * * *'\n",
+		"no end":           "- start: 2026-11-02T08:00:00Z\n",
+		"unknown field":    "- schedule: '0 9 * * *'\n  length: 1h\n",
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "maintenance.yml")
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				t.Fatalf("failed to write windows: %v", err)
+			}
+			if _, err := LoadMaintenanceWindows(path); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
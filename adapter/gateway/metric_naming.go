@@ -0,0 +1,170 @@
+package gateway
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+)
+
+// DefaultMetricNamespace is the prefix of every metric name unless configured otherwise
+const DefaultMetricNamespace = "airq"
+
+// metricNamePattern matches valid metric namespaces and label names
+var metricNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// exporterLabels are the labels exporter metrics carry themselves, which
+// constant labels must not override
+var exporterLabels = []string{"sensor", "window", "device", "nickname", "name", "data_type"}
+
+// MetricNaming selects the metric names the exporter emits
+type MetricNaming string
+
+// Metric naming schemes
+const (
+	// MetricNamingLegacy emits the original names such as airq_co2 and airq_scd40_humidity
+	MetricNamingLegacy MetricNaming = "legacy"
+	// MetricNamingConventional emits names with unit suffixes and a sensor label,
+	// such as airq_co2_ppm{sensor="scd40"}
+	MetricNamingConventional MetricNaming = "conventional"
+	// MetricNamingBoth emits both schemes, for migrating dashboards and alerts
+	MetricNamingBoth MetricNaming = "both"
+)
+
+// ParseMetricNaming parses a metric naming scheme
+func ParseMetricNaming(value string) (MetricNaming, error) {
+	switch n := MetricNaming(value); n {
+	case MetricNamingLegacy, MetricNamingConventional, MetricNamingBoth:
+		return n, nil
+	default:
+		return "", fmt.Errorf("unknown metric naming: %s", value)
+	}
+}
+
+// ParseMetricNamespace validates a metric namespace
+func ParseMetricNamespace(value string) (string, error) {
+	if !metricNamePattern.MatchString(value) {
+		return "", fmt.Errorf("invalid metric namespace: %q", value)
+	}
+	return value, nil
+}
+
+// ParseConstLabels parses comma-separated name=value pairs, such as
+// "site=tokyo,room=101", into labels added to every series
+func ParseConstLabels(value string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, val, ok := strings.Cut(part, "=")
+		name = strings.TrimSpace(name)
+		if !ok || val == "" {
+			return nil, fmt.Errorf("invalid label %q, expected name=value", part)
+		}
+		if !metricNamePattern.MatchString(name) || strings.HasPrefix(name, "__") {
+			return nil, fmt.Errorf("invalid label name: %q", name)
+		}
+		if slices.Contains(exporterLabels, name) {
+			return nil, fmt.Errorf("label %s is used by the exporter metrics", name)
+		}
+		if _, ok := labels[name]; ok {
+			return nil, fmt.Errorf("duplicate label: %s", name)
+		}
+		labels[name] = strings.TrimSpace(val)
+	}
+	return labels, nil
+}
+
+// Legacy reports whether the legacy names are emitted; the empty scheme is legacy
+func (n MetricNaming) Legacy() bool {
+	return n == "" || n == MetricNamingLegacy || n == MetricNamingBoth
+}
+
+// Conventional reports whether the conventional names are emitted
+func (n MetricNaming) Conventional() bool {
+	return n == MetricNamingConventional || n == MetricNamingBoth
+}
+
+// conventionalFor reports whether the conventional metric of a field is emitted.
+// Quantities measured by several sensors have no single legacy name, so their
+// families, such as temperature and humidity, are emitted under every scheme.
+func (n MetricNaming) conventionalFor(f entity.Field) bool {
+	return n.Conventional() || sharedQuantity(f)
+}
+
+// bestSensor is the sensor label of the series that repeats the preferred
+// sensor's value for quantities measured by several sensors
+const bestSensor = "best"
+
+// conventionalMetric describes the conventional metric family of a quantity,
+// with one series per sensor
+type conventionalMetric struct {
+	// base is the measured quantity, unit the Prometheus unit suffix
+	base string
+	unit string
+	// quantity and symbol make up the help text
+	quantity string
+	symbol   string
+}
+
+// conventionalMetrics maps quantities to their conventional metrics
+var conventionalMetrics = map[string]conventionalMetric{
+	"pm1_0":             {base: "pm1_0", unit: "micrograms_per_cubic_meter", quantity: "PM1.0 concentration", symbol: "µg/m³"},
+	"pm2_5":             {base: "pm2_5", unit: "micrograms_per_cubic_meter", quantity: "PM2.5 concentration", symbol: "µg/m³"},
+	"pm4_0":             {base: "pm4_0", unit: "micrograms_per_cubic_meter", quantity: "PM4.0 concentration", symbol: "µg/m³"},
+	"pm10_0":            {base: "pm10_0", unit: "micrograms_per_cubic_meter", quantity: "PM10.0 concentration", symbol: "µg/m³"},
+	"relative_humidity": {base: "relative_humidity", unit: "percent", quantity: "Relative humidity", symbol: "%"},
+	"temperature":       {base: "temperature", unit: "celsius", quantity: "Temperature", symbol: "°C"},
+	"voc":               {base: "voc", unit: "index", quantity: "VOC index"},
+	"nox":               {base: "nox", unit: "index", quantity: "NOx index"},
+	"co2":               {base: "co2", unit: "ppm", quantity: "CO2 concentration", symbol: "ppm"},
+}
+
+// conventionalMetricOf returns the conventional metric of a field
+func conventionalMetricOf(f entity.Field) conventionalMetric {
+	if m, ok := conventionalMetrics[f.Quantity]; ok {
+		return m
+	}
+	return conventionalMetric{base: f.Quantity, quantity: f.Description, symbol: f.Unit}
+}
+
+// reportsBest reports whether a field is the preferred source of a quantity that
+// several sensors measure, and so is repeated as the sensor="best" series
+func reportsBest(f entity.Field, preferred string) bool {
+	return f.Sensor == preferred && sharedQuantity(f)
+}
+
+// sharedQuantity reports whether other sensors measure the quantity of a field too
+func sharedQuantity(f entity.Field) bool {
+	for _, other := range entity.Fields {
+		if other.Quantity == f.Quantity && other.Sensor != f.Sensor {
+			return true
+		}
+	}
+	return false
+}
+
+// name returns the metric name, with an optional aggregate before the unit
+func (m conventionalMetric) name(namespace, aggregate string) string {
+	name := namespace + "_" + m.base
+	if aggregate != "" {
+		name += "_" + aggregate
+	}
+	if m.unit != "" {
+		name += "_" + m.unit
+	}
+	return name
+}
+
+// help returns the help text of the gauge
+func (m conventionalMetric) help() string {
+	help := m.quantity
+	if m.symbol != "" {
+		help += " in " + m.symbol
+	}
+	return help
+}
@@ -0,0 +1,162 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// MQTT availability payloads, as expected by Home Assistant
+const (
+	mqttOnline  = "online"
+	mqttOffline = "offline"
+)
+
+// MQTTClient publishes messages to an MQTT broker (allows mocking)
+type MQTTClient interface {
+	// Publish sends a message without waiting for the broker to acknowledge it
+	Publish(topic string, retained bool, payload []byte)
+	// Subscribe calls handler with the payload of every message on topic, also
+	// after reconnecting
+	Subscribe(topic string, handler func(payload []byte))
+	// OnConnect calls handler after every successful connection
+	OnConnect(handler func())
+}
+
+// MQTTOptions configures the connection to an MQTT broker
+type MQTTOptions struct {
+	// URL of the broker, such as tcp://broker:1883, ssl://broker:8883 or
+	// ws://broker/mqtt; user info in the URL sets the username and password
+	URL      string
+	ClientID string
+	// Password replaces the password of the URL when it is not empty
+	Password string
+	// StatusTopic receives a retained "online" after every connection and
+	// "offline" as the last will when the exporter disconnects
+	StatusTopic string
+}
+
+// PahoMQTTClient implements MQTTClient with the Eclipse Paho client, retrying
+// the connection in the background
+type PahoMQTTClient struct {
+	client      mqtt.Client
+	statusTopic string
+
+	mu            sync.Mutex
+	onConnect     []func()
+	subscriptions map[string]func([]byte)
+}
+
+// NewPahoMQTTClient creates a new PahoMQTTClient; Connect starts it
+func NewPahoMQTTClient(options MQTTOptions) (*PahoMQTTClient, error) {
+	u, err := url.Parse(options.URL)
+	if err != nil || u.Host == "" {
+		return nil, errors.New("invalid MQTT broker URL")
+	}
+	switch u.Scheme {
+	case "tcp", "mqtt", "ssl", "tls", "mqtts", "ws", "wss":
+	default:
+		return nil, fmt.Errorf("unsupported MQTT broker URL scheme: %s", u.Scheme)
+	}
+
+	c := &PahoMQTTClient{
+		statusTopic:   options.StatusTopic,
+		subscriptions: make(map[string]func([]byte)),
+	}
+	opts := mqtt.NewClientOptions().
+		AddBroker(options.URL).
+		SetClientID(options.ClientID).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(10 * time.Second).
+		SetAutoReconnect(true).
+		SetMaxReconnectInterval(time.Minute).
+		SetOnConnectHandler(func(mqtt.Client) { c.connected() }).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			slog.Warn("MQTT connection lost", "broker", RedactURL(options.URL), "error", err)
+		})
+	if u.User != nil {
+		opts.SetUsername(u.User.Username())
+		if password, ok := u.User.Password(); ok {
+			opts.SetPassword(password)
+		}
+	}
+	if options.Password != "" {
+		opts.SetPassword(options.Password)
+	}
+	if c.statusTopic != "" {
+		opts.SetWill(c.statusTopic, mqttOffline, 1, true)
+	}
+	c.client = mqtt.NewClient(opts)
+	return c, nil
+}
+
+// Connect starts connecting to the broker in the background
+func (c *PahoMQTTClient) Connect() {
+	c.client.Connect()
+}
+
+// connected announces the exporter, restores the subscriptions and runs the
+// connect handlers
+func (c *PahoMQTTClient) connected() {
+	slog.Info("MQTT connected")
+	if c.statusTopic != "" {
+		c.Publish(c.statusTopic, true, []byte(mqttOnline))
+	}
+
+	c.mu.Lock()
+	subscriptions := make(map[string]func([]byte), len(c.subscriptions))
+	for topic, handler := range c.subscriptions {
+		subscriptions[topic] = handler
+	}
+	onConnect := append([]func(){}, c.onConnect...)
+	c.mu.Unlock()
+
+	for topic, handler := range subscriptions {
+		c.client.Subscribe(topic, 1, func(_ mqtt.Client, msg mqtt.Message) { handler(msg.Payload()) })
+	}
+	for _, handler := range onConnect {
+		handler()
+	}
+}
+
+// Publish implements MQTTClient with QoS 1; messages published while the
+// connection is down are sent after reconnecting
+func (c *PahoMQTTClient) Publish(topic string, retained bool, payload []byte) {
+	token := c.client.Publish(topic, 1, retained, payload)
+	go func() {
+		if token.WaitTimeout(30*time.Second) && token.Error() != nil {
+			slog.Error("Failed to publish MQTT message", "topic", topic, "error", token.Error())
+		}
+	}()
+}
+
+// Subscribe implements MQTTClient
+func (c *PahoMQTTClient) Subscribe(topic string, handler func(payload []byte)) {
+	c.mu.Lock()
+	c.subscriptions[topic] = handler
+	c.mu.Unlock()
+	if c.client.IsConnectionOpen() {
+		c.client.Subscribe(topic, 1, func(_ mqtt.Client, msg mqtt.Message) { handler(msg.Payload()) })
+	}
+}
+
+// OnConnect implements MQTTClient
+func (c *PahoMQTTClient) OnConnect(handler func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onConnect = append(c.onConnect, handler)
+}
+
+// Close marks the exporter offline and disconnects, waiting up to timeout for
+// queued messages
+func (c *PahoMQTTClient) Close(timeout time.Duration) {
+	if c.client.IsConnectionOpen() && c.statusTopic != "" {
+		c.client.Publish(c.statusTopic, 1, true, mqttOffline).WaitTimeout(timeout)
+	}
+	c.client.Disconnect(uint(timeout.Milliseconds()))
+}
@@ -1,106 +1,207 @@
 package gateway
 
 import (
+	"slices"
+	"sync"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
 )
 
+// PrometheusMetricsOptions holds optional behaviour of PrometheusMetricsGateway
+// and RollingStatsCollector
+type PrometheusMetricsOptions struct {
+	// SuppressWarmingUp hides the VOC, NOx and CO2 gauges while the sensors are warming up
+	SuppressWarmingUp bool
+	// SuppressMaintenance hides the measurement gauges during maintenance
+	// windows, so that threshold alerts do not fire
+	SuppressMaintenance bool
+	// Naming selects legacy or conventional metric names, or both; empty is legacy
+	Naming MetricNaming
+	// PreferredSensor provides the sensor="best" series of quantities measured by
+	// several sensors; empty is the SEN55
+	PreferredSensor string
+	// Namespace prefixes every metric name; empty is DefaultMetricNamespace
+	Namespace string
+}
+
+// namespace returns the metric namespace
+func (o PrometheusMetricsOptions) namespace() string {
+	if o.Namespace == "" {
+		return DefaultMetricNamespace
+	}
+	return o.Namespace
+}
+
 // PrometheusMetricsGateway implements MetricsRepository using Prometheus client
 type PrometheusMetricsGateway struct {
-	// SEN55 sensor metrics
-	pm1_0       prometheus.Gauge
-	pm2_5       prometheus.Gauge
-	pm4_0       prometheus.Gauge
-	pm10_0      prometheus.Gauge
-	humidity    prometheus.Gauge
-	temperature prometheus.Gauge
-	voc         prometheus.Gauge
-	nox         prometheus.Gauge
-
-	// SCD40 sensor metrics
-	co2              prometheus.Gauge
-	scd40Humidity    prometheus.Gauge
-	scd40Temperature prometheus.Gauge
+	options PrometheusMetricsOptions
+
+	// Legacy metrics by field key, with a device label
+	legacy map[string]*prometheus.GaugeVec
+
+	// Conventional metrics by name, with device and sensor labels
+	conventional map[string]*prometheus.GaugeVec
+
+	// Device state metrics
+	warmingUp     *prometheus.GaugeVec
+	maintenance   *prometheus.GaugeVec
+	deviceInfo    *prometheus.GaugeVec
+	sleepInterval *prometheus.GaugeVec
+	// infoLabels holds the current device info labels of every device
+	infoMu     sync.Mutex
+	infoLabels map[string][]string
+
+	// Exporter metrics
+	duplicateFetches prometheus.Counter
 }
 
 // NewPrometheusMetricsGateway creates a new PrometheusMetricsGateway and registers metrics
-func NewPrometheusMetricsGateway(registry prometheus.Registerer) *PrometheusMetricsGateway {
+func NewPrometheusMetricsGateway(registry prometheus.Registerer, options PrometheusMetricsOptions) *PrometheusMetricsGateway {
+	if options.PreferredSensor == "" {
+		options.PreferredSensor = entity.SensorSEN55
+	}
+	namespace := options.namespace()
 	g := &PrometheusMetricsGateway{
-		pm1_0: prometheus.NewGauge(prometheus.GaugeOpts{
-			Name: "airq_pm1_0",
-			Help: "PM1.0 concentration in µg/m³",
-		}),
-		pm2_5: prometheus.NewGauge(prometheus.GaugeOpts{
-			Name: "airq_pm2_5",
-			Help: "PM2.5 concentration in µg/m³",
-		}),
-		pm4_0: prometheus.NewGauge(prometheus.GaugeOpts{
-			Name: "airq_pm4_0",
-			Help: "PM4.0 concentration in µg/m³",
-		}),
-		pm10_0: prometheus.NewGauge(prometheus.GaugeOpts{
-			Name: "airq_pm10_0",
-			Help: "PM10.0 concentration in µg/m³",
-		}),
-		humidity: prometheus.NewGauge(prometheus.GaugeOpts{
-			Name: "airq_humidity",
-			Help: "Relative humidity in % (SEN55)",
-		}),
-		temperature: prometheus.NewGauge(prometheus.GaugeOpts{
-			Name: "airq_temperature",
-			Help: "Temperature in °C (SEN55)",
-		}),
-		voc: prometheus.NewGauge(prometheus.GaugeOpts{
-			Name: "airq_voc",
-			Help: "VOC index",
-		}),
-		nox: prometheus.NewGauge(prometheus.GaugeOpts{
-			Name: "airq_nox",
-			Help: "NOx index",
-		}),
-		co2: prometheus.NewGauge(prometheus.GaugeOpts{
-			Name: "airq_co2",
-			Help: "CO2 concentration in ppm",
-		}),
-		scd40Humidity: prometheus.NewGauge(prometheus.GaugeOpts{
-			Name: "airq_scd40_humidity",
-			Help: "Relative humidity in % (SCD40)",
-		}),
-		scd40Temperature: prometheus.NewGauge(prometheus.GaugeOpts{
-			Name: "airq_scd40_temperature",
-			Help: "Temperature in °C (SCD40)",
+		options: options,
+		warmingUp: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "sensor_warming_up",
+			Help:      "1 while the sensors are warming up after a device restart, 0 otherwise",
+		}, []string{"device"}),
+		maintenance: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "maintenance",
+			Help:      "1 while the device is in a maintenance window, 0 otherwise",
+		}, []string{"device"}),
+		deviceInfo: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "device_info",
+			Help:      "Device metadata, always 1",
+		}, []string{"device", "nickname", "name", "data_type"}),
+		sleepInterval: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "device_sleep_interval_seconds",
+			Help:      "Configured time between device uploads in seconds",
+		}, []string{"device"}),
+		infoLabels: make(map[string][]string),
+		duplicateFetches: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "duplicate_fetches_total",
+			Help:      "Number of fetches that returned a reading the device had already uploaded",
 		}),
 	}
 
-	// Register all metrics
-	registry.MustRegister(
-		g.pm1_0,
-		g.pm2_5,
-		g.pm4_0,
-		g.pm10_0,
-		g.humidity,
-		g.temperature,
-		g.voc,
-		g.nox,
-		g.co2,
-		g.scd40Humidity,
-		g.scd40Temperature,
-	)
+	// Register the metrics of the selected naming schemes
+	g.legacy = make(map[string]*prometheus.GaugeVec)
+	for _, f := range entity.Fields {
+		g.legacy[f.Key] = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      f.Key,
+			Help:      legacyHelp[f.Key],
+		}, []string{"device"})
+		if options.Naming.Legacy() {
+			registry.MustRegister(g.legacy[f.Key])
+		}
+	}
+	g.conventional = make(map[string]*prometheus.GaugeVec)
+	for _, f := range entity.Fields {
+		m := conventionalMetricOf(f)
+		name := m.name(namespace, "")
+		if _, ok := g.conventional[name]; ok {
+			continue
+		}
+		g.conventional[name] = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: name,
+			Help: m.help(),
+		}, []string{"device", "sensor"})
+		if options.Naming.conventionalFor(f) {
+			registry.MustRegister(g.conventional[name])
+		}
+	}
+	registry.MustRegister(g.warmingUp, g.maintenance, g.deviceInfo, g.sleepInterval, g.duplicateFetches)
 
 	return g
 }
 
 // Update updates the Prometheus metrics with the given air quality data
 func (g *PrometheusMetricsGateway) Update(data *entity.AirQuality) {
-	g.pm1_0.Set(data.PM1_0)
-	g.pm2_5.Set(data.PM2_5)
-	g.pm4_0.Set(data.PM4_0)
-	g.pm10_0.Set(data.PM10_0)
-	g.humidity.Set(data.Humidity)
-	g.temperature.Set(data.Temperature)
-	g.voc.Set(float64(data.VOC))
-	g.nox.Set(float64(data.NOx))
-	g.co2.Set(float64(data.CO2))
-	g.scd40Humidity.Set(data.SCD40Humidity)
-	g.scd40Temperature.Set(data.SCD40Temperature)
+	if data.WarmingUp {
+		g.warmingUp.WithLabelValues(data.Device).Set(1)
+	} else {
+		g.warmingUp.WithLabelValues(data.Device).Set(0)
+	}
+	if data.Maintenance {
+		g.maintenance.WithLabelValues(data.Device).Set(1)
+	} else {
+		g.maintenance.WithLabelValues(data.Device).Set(0)
+	}
+
+	g.updateDeviceInfo(data)
+
+	// VOC/NOx indices and CO2 are unreliable right after power-up
+	suppress := data.WarmingUp && g.options.SuppressWarmingUp
+	// Readings taken while the device is serviced are not representative
+	inMaintenance := data.Maintenance && g.options.SuppressMaintenance
+	for _, f := range entity.Fields {
+		legacy := g.legacy[f.Key]
+		gauge := g.conventional[conventionalMetricOf(f).name(g.options.namespace(), "")]
+		if !data.Measures(f.Key) || suppress && warmUpSensitive(f) || inMaintenance {
+			legacy.DeleteLabelValues(data.Device)
+			gauge.DeleteLabelValues(data.Device, f.Sensor)
+			if reportsBest(f, g.options.PreferredSensor) {
+				gauge.DeleteLabelValues(data.Device, bestSensor)
+			}
+			continue
+		}
+		legacy.WithLabelValues(data.Device).Set(f.Value(data))
+		gauge.WithLabelValues(data.Device, f.Sensor).Set(f.Value(data))
+		if reportsBest(f, g.options.PreferredSensor) {
+			gauge.WithLabelValues(data.Device, bestSensor).Set(f.Value(data))
+		}
+	}
+}
+
+// updateDeviceInfo replaces the info series of the device when its metadata changed
+func (g *PrometheusMetricsGateway) updateDeviceInfo(data *entity.AirQuality) {
+	labels := []string{data.Device, data.Nickname, data.Name, data.DataType}
+
+	g.infoMu.Lock()
+	defer g.infoMu.Unlock()
+	if old, ok := g.infoLabels[data.Device]; ok && !slices.Equal(old, labels) {
+		g.deviceInfo.DeleteLabelValues(old...)
+	}
+	g.infoLabels[data.Device] = labels
+	g.deviceInfo.WithLabelValues(labels...).Set(1)
+
+	if data.SleepInterval > 0 {
+		g.sleepInterval.WithLabelValues(data.Device).Set(data.SleepInterval.Seconds())
+	} else {
+		g.sleepInterval.DeleteLabelValues(data.Device)
+	}
+}
+
+// legacyHelp holds the help text of the legacy metrics by field key
+var legacyHelp = map[string]string{
+	"pm1_0":             "PM1.0 concentration in µg/m³",
+	"pm2_5":             "PM2.5 concentration in µg/m³",
+	"pm4_0":             "PM4.0 concentration in µg/m³",
+	"pm10_0":            "PM10.0 concentration in µg/m³",
+	"humidity":          "Relative humidity in % (SEN55)",
+	"temperature":       "Temperature in °C (SEN55)",
+	"voc":               "VOC index",
+	"nox":               "NOx index",
+	"co2":               "CO2 concentration in ppm",
+	"scd40_humidity":    "Relative humidity in % (SCD40)",
+	"scd40_temperature": "Temperature in °C (SCD40)",
+}
+
+// warmUpSensitive reports whether a field is hidden while the sensors are warming up
+func warmUpSensitive(f entity.Field) bool {
+	return f.Key == "voc" || f.Key == "nox" || f.Key == "co2"
+}
+
+// RecordDuplicate counts a fetch that returned an already seen reading
+func (g *PrometheusMetricsGateway) RecordDuplicate(data *entity.AirQuality) {
+	g.duplicateFetches.Inc()
 }
@@ -3,6 +3,7 @@ package gateway
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
@@ -11,7 +12,7 @@ import (
 
 func TestPrometheusMetricsGateway_Update(t *testing.T) {
 	registry := prometheus.NewRegistry()
-	gateway := NewPrometheusMetricsGateway(registry)
+	gateway := NewPrometheusMetricsGateway(registry, PrometheusMetricsOptions{})
 
 	data := &entity.AirQuality{
 		PM1_0:            1.5,
@@ -25,6 +26,7 @@ func TestPrometheusMetricsGateway_Update(t *testing.T) {
 		CO2:              725,
 		SCD40Humidity:    17.99,
 		SCD40Temperature: 31.01,
+		Device:           "airq-1",
 		Nickname:         "AirQ",
 	}
 
@@ -34,36 +36,36 @@ func TestPrometheusMetricsGateway_Update(t *testing.T) {
 	expected := `
 		# HELP airq_pm1_0 PM1.0 concentration in µg/m³
 		# TYPE airq_pm1_0 gauge
-		airq_pm1_0 1.5
+		airq_pm1_0{device="airq-1"} 1.5
 	`
-	if err := testutil.CollectAndCompare(gateway.pm1_0, strings.NewReader(expected)); err != nil {
+	if err := testutil.CollectAndCompare(gateway.legacy["pm1_0"], strings.NewReader(expected)); err != nil {
 		t.Errorf("PM1.0 metric mismatch: %v", err)
 	}
 
 	expected = `
 		# HELP airq_pm2_5 PM2.5 concentration in µg/m³
 		# TYPE airq_pm2_5 gauge
-		airq_pm2_5 2.5
+		airq_pm2_5{device="airq-1"} 2.5
 	`
-	if err := testutil.CollectAndCompare(gateway.pm2_5, strings.NewReader(expected)); err != nil {
+	if err := testutil.CollectAndCompare(gateway.legacy["pm2_5"], strings.NewReader(expected)); err != nil {
 		t.Errorf("PM2.5 metric mismatch: %v", err)
 	}
 
 	expected = `
 		# HELP airq_pm4_0 PM4.0 concentration in µg/m³
 		# TYPE airq_pm4_0 gauge
-		airq_pm4_0 4
+		airq_pm4_0{device="airq-1"} 4
 	`
-	if err := testutil.CollectAndCompare(gateway.pm4_0, strings.NewReader(expected)); err != nil {
+	if err := testutil.CollectAndCompare(gateway.legacy["pm4_0"], strings.NewReader(expected)); err != nil {
 		t.Errorf("PM4.0 metric mismatch: %v", err)
 	}
 
 	expected = `
 		# HELP airq_pm10_0 PM10.0 concentration in µg/m³
 		# TYPE airq_pm10_0 gauge
-		airq_pm10_0 10
+		airq_pm10_0{device="airq-1"} 10
 	`
-	if err := testutil.CollectAndCompare(gateway.pm10_0, strings.NewReader(expected)); err != nil {
+	if err := testutil.CollectAndCompare(gateway.legacy["pm10_0"], strings.NewReader(expected)); err != nil {
 		t.Errorf("PM10.0 metric mismatch: %v", err)
 	}
 
@@ -71,18 +73,18 @@ func TestPrometheusMetricsGateway_Update(t *testing.T) {
 	expected = `
 		# HELP airq_humidity Relative humidity in % (SEN55)
 		# TYPE airq_humidity gauge
-		airq_humidity 32.54
+		airq_humidity{device="airq-1"} 32.54
 	`
-	if err := testutil.CollectAndCompare(gateway.humidity, strings.NewReader(expected)); err != nil {
+	if err := testutil.CollectAndCompare(gateway.legacy["humidity"], strings.NewReader(expected)); err != nil {
 		t.Errorf("Humidity metric mismatch: %v", err)
 	}
 
 	expected = `
 		# HELP airq_temperature Temperature in °C (SEN55)
 		# TYPE airq_temperature gauge
-		airq_temperature 23.42
+		airq_temperature{device="airq-1"} 23.42
 	`
-	if err := testutil.CollectAndCompare(gateway.temperature, strings.NewReader(expected)); err != nil {
+	if err := testutil.CollectAndCompare(gateway.legacy["temperature"], strings.NewReader(expected)); err != nil {
 		t.Errorf("Temperature metric mismatch: %v", err)
 	}
 
@@ -90,18 +92,18 @@ func TestPrometheusMetricsGateway_Update(t *testing.T) {
 	expected = `
 		# HELP airq_voc VOC index
 		# TYPE airq_voc gauge
-		airq_voc 75
+		airq_voc{device="airq-1"} 75
 	`
-	if err := testutil.CollectAndCompare(gateway.voc, strings.NewReader(expected)); err != nil {
+	if err := testutil.CollectAndCompare(gateway.legacy["voc"], strings.NewReader(expected)); err != nil {
 		t.Errorf("VOC metric mismatch: %v", err)
 	}
 
 	expected = `
 		# HELP airq_nox NOx index
 		# TYPE airq_nox gauge
-		airq_nox 1
+		airq_nox{device="airq-1"} 1
 	`
-	if err := testutil.CollectAndCompare(gateway.nox, strings.NewReader(expected)); err != nil {
+	if err := testutil.CollectAndCompare(gateway.legacy["nox"], strings.NewReader(expected)); err != nil {
 		t.Errorf("NOx metric mismatch: %v", err)
 	}
 
@@ -109,46 +111,48 @@ func TestPrometheusMetricsGateway_Update(t *testing.T) {
 	expected = `
 		# HELP airq_co2 CO2 concentration in ppm
 		# TYPE airq_co2 gauge
-		airq_co2 725
+		airq_co2{device="airq-1"} 725
 	`
-	if err := testutil.CollectAndCompare(gateway.co2, strings.NewReader(expected)); err != nil {
+	if err := testutil.CollectAndCompare(gateway.legacy["co2"], strings.NewReader(expected)); err != nil {
 		t.Errorf("CO2 metric mismatch: %v", err)
 	}
 
 	expected = `
 		# HELP airq_scd40_humidity Relative humidity in % (SCD40)
 		# TYPE airq_scd40_humidity gauge
-		airq_scd40_humidity 17.99
+		airq_scd40_humidity{device="airq-1"} 17.99
 	`
-	if err := testutil.CollectAndCompare(gateway.scd40Humidity, strings.NewReader(expected)); err != nil {
+	if err := testutil.CollectAndCompare(gateway.legacy["scd40_humidity"], strings.NewReader(expected)); err != nil {
 		t.Errorf("SCD40 Humidity metric mismatch: %v", err)
 	}
 
 	expected = `
 		# HELP airq_scd40_temperature Temperature in °C (SCD40)
 		# TYPE airq_scd40_temperature gauge
-		airq_scd40_temperature 31.01
+		airq_scd40_temperature{device="airq-1"} 31.01
 	`
-	if err := testutil.CollectAndCompare(gateway.scd40Temperature, strings.NewReader(expected)); err != nil {
+	if err := testutil.CollectAndCompare(gateway.legacy["scd40_temperature"], strings.NewReader(expected)); err != nil {
 		t.Errorf("SCD40 Temperature metric mismatch: %v", err)
 	}
 }
 
 func TestPrometheusMetricsGateway_UpdateMultipleTimes(t *testing.T) {
 	registry := prometheus.NewRegistry()
-	gateway := NewPrometheusMetricsGateway(registry)
+	gateway := NewPrometheusMetricsGateway(registry, PrometheusMetricsOptions{})
 
 	// First update
 	data1 := &entity.AirQuality{
-		PM2_5: 10.0,
-		CO2:   500,
+		Device: "airq-1",
+		PM2_5:  10.0,
+		CO2:    500,
 	}
 	gateway.Update(data1)
 
 	// Second update with different values
 	data2 := &entity.AirQuality{
-		PM2_5: 25.0,
-		CO2:   800,
+		Device: "airq-1",
+		PM2_5:  25.0,
+		CO2:    800,
 	}
 	gateway.Update(data2)
 
@@ -156,18 +160,309 @@ func TestPrometheusMetricsGateway_UpdateMultipleTimes(t *testing.T) {
 	expected := `
 		# HELP airq_pm2_5 PM2.5 concentration in µg/m³
 		# TYPE airq_pm2_5 gauge
-		airq_pm2_5 25
+		airq_pm2_5{device="airq-1"} 25
 	`
-	if err := testutil.CollectAndCompare(gateway.pm2_5, strings.NewReader(expected)); err != nil {
+	if err := testutil.CollectAndCompare(gateway.legacy["pm2_5"], strings.NewReader(expected)); err != nil {
 		t.Errorf("PM2.5 metric should be updated: %v", err)
 	}
 
 	expected = `
 		# HELP airq_co2 CO2 concentration in ppm
 		# TYPE airq_co2 gauge
-		airq_co2 800
+		airq_co2{device="airq-1"} 800
 	`
-	if err := testutil.CollectAndCompare(gateway.co2, strings.NewReader(expected)); err != nil {
+	if err := testutil.CollectAndCompare(gateway.legacy["co2"], strings.NewReader(expected)); err != nil {
 		t.Errorf("CO2 metric should be updated: %v", err)
 	}
 }
+
+func TestPrometheusMetricsGateway_WarmingUp(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	gateway := NewPrometheusMetricsGateway(registry, PrometheusMetricsOptions{})
+
+	gateway.Update(&entity.AirQuality{Device: "airq-1", CO2: 725, WarmingUp: true})
+	gateway.Update(&entity.AirQuality{Device: "airq-2", CO2: 410})
+
+	expected := `
+		# HELP airq_sensor_warming_up 1 while the sensors are warming up after a device restart, 0 otherwise
+		# TYPE airq_sensor_warming_up gauge
+		airq_sensor_warming_up{device="airq-1"} 1
+		airq_sensor_warming_up{device="airq-2"} 0
+	`
+	if err := testutil.CollectAndCompare(gateway.warmingUp, strings.NewReader(expected)); err != nil {
+		t.Errorf("warming up metric mismatch: %v", err)
+	}
+
+	// Without suppression the affected gauges are still exported, one per device
+	if count := testutil.CollectAndCount(gateway.legacy["co2"]); count != 2 {
+		t.Errorf("expected CO2 metric of both devices to be exported, got %d series", count)
+	}
+}
+
+func TestPrometheusMetricsGateway_SuppressWarmingUp(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	gateway := NewPrometheusMetricsGateway(registry, PrometheusMetricsOptions{SuppressWarmingUp: true})
+
+	gateway.Update(&entity.AirQuality{Device: "airq-1", PM2_5: 2.5, VOC: 100, NOx: 1, CO2: 725})
+	gateway.Update(&entity.AirQuality{Device: "airq-1", PM2_5: 3.5, VOC: 100, NOx: 1, CO2: 2000, WarmingUp: true})
+
+	for name, collector := range map[string]prometheus.Collector{
+		"VOC": gateway.legacy["voc"],
+		"NOx": gateway.legacy["nox"],
+		"CO2": gateway.legacy["co2"],
+	} {
+		if count := testutil.CollectAndCount(collector); count != 0 {
+			t.Errorf("expected %s metric to be suppressed, got %d series", name, count)
+		}
+	}
+
+	expected := `
+		# HELP airq_pm2_5 PM2.5 concentration in µg/m³
+		# TYPE airq_pm2_5 gauge
+		airq_pm2_5{device="airq-1"} 3.5
+	`
+	if err := testutil.CollectAndCompare(gateway.legacy["pm2_5"], strings.NewReader(expected)); err != nil {
+		t.Errorf("PM2.5 metric should not be suppressed: %v", err)
+	}
+
+	// Gauges come back once the warm-up window is over
+	gateway.Update(&entity.AirQuality{Device: "airq-1", VOC: 90, NOx: 1, CO2: 800})
+	expected = `
+		# HELP airq_co2 CO2 concentration in ppm
+		# TYPE airq_co2 gauge
+		airq_co2{device="airq-1"} 800
+	`
+	if err := testutil.CollectAndCompare(gateway.legacy["co2"], strings.NewReader(expected)); err != nil {
+		t.Errorf("CO2 metric should be restored: %v", err)
+	}
+}
+
+func TestPrometheusMetricsGateway_Maintenance(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	gateway := NewPrometheusMetricsGateway(registry, PrometheusMetricsOptions{Naming: MetricNamingBoth, SuppressMaintenance: true})
+
+	gateway.Update(&entity.AirQuality{Device: "airq-2", CO2: 700})
+	gateway.Update(&entity.AirQuality{Device: "airq-1", PM2_5: 80, CO2: 3000, Maintenance: true})
+	expected := `
+		# HELP airq_maintenance 1 while the device is in a maintenance window, 0 otherwise
+		# TYPE airq_maintenance gauge
+		airq_maintenance{device="airq-1"} 1
+		airq_maintenance{device="airq-2"} 0
+	`
+	if err := testutil.CollectAndCompare(gateway.maintenance, strings.NewReader(expected)); err != nil {
+		t.Errorf("unexpected maintenance metric: %v", err)
+	}
+	// Only the gauges of the device in maintenance are suppressed
+	expected = `
+		# HELP airq_co2 CO2 concentration in ppm
+		# TYPE airq_co2 gauge
+		airq_co2{device="airq-2"} 700
+	`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "airq_co2"); err != nil {
+		t.Errorf("expected the gauges of airq-1 to be suppressed: %v", err)
+	}
+
+	gateway.Update(&entity.AirQuality{Device: "airq-1", PM2_5: 3, CO2: 700})
+	if value := testutil.ToFloat64(gateway.maintenance.WithLabelValues("airq-1")); value != 0 {
+		t.Errorf("expected maintenance 0 after the window, got %f", value)
+	}
+	if count, err := testutil.GatherAndCount(registry, "airq_co2", "airq_co2_ppm", "airq_pm2_5"); err != nil || count != 6 {
+		t.Errorf("expected the gauges to come back, got %d series (%v)", count, err)
+	}
+}
+
+func TestPrometheusMetricsGateway_UnmeasuredFields(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	gateway := NewPrometheusMetricsGateway(registry, PrometheusMetricsOptions{Naming: MetricNamingBoth})
+
+	gateway.Update(&entity.AirQuality{Device: "airq-1", CO2: 812, SCD40Temperature: 22.5, Measured: map[string]bool{"co2": true, "scd40_temperature": true}})
+
+	expected := `
+		# HELP airq_co2 CO2 concentration in ppm
+		# TYPE airq_co2 gauge
+		airq_co2{device="airq-1"} 812
+		# HELP airq_co2_ppm CO2 concentration in ppm
+		# TYPE airq_co2_ppm gauge
+		airq_co2_ppm{device="airq-1",sensor="scd40"} 812
+		# HELP airq_temperature_celsius Temperature in °C
+		# TYPE airq_temperature_celsius gauge
+		airq_temperature_celsius{device="airq-1",sensor="scd40"} 22.5
+	`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "airq_co2", "airq_co2_ppm", "airq_pm2_5", "airq_temperature_celsius"); err != nil {
+		t.Errorf("expected only the measured fields, got: %v", err)
+	}
+}
+
+func TestPrometheusMetricsGateway_RecordDuplicate(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	gateway := NewPrometheusMetricsGateway(registry, PrometheusMetricsOptions{})
+
+	gateway.RecordDuplicate(&entity.AirQuality{})
+	gateway.RecordDuplicate(&entity.AirQuality{})
+
+	expected := `
+		# HELP airq_duplicate_fetches_total Number of fetches that returned a reading the device had already uploaded
+		# TYPE airq_duplicate_fetches_total counter
+		airq_duplicate_fetches_total 2
+	`
+	if err := testutil.CollectAndCompare(gateway.duplicateFetches, strings.NewReader(expected)); err != nil {
+		t.Errorf("duplicate fetches metric mismatch: %v", err)
+	}
+}
+
+func TestPrometheusMetricsGateway_ConventionalNaming(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	gateway := NewPrometheusMetricsGateway(registry, PrometheusMetricsOptions{Naming: MetricNamingConventional})
+
+	gateway.Update(&entity.AirQuality{
+		Device:           "airq-1",
+		PM2_5:            2.5,
+		Humidity:         32.5,
+		Temperature:      23.5,
+		CO2:              725,
+		SCD40Humidity:    18,
+		SCD40Temperature: 31,
+	})
+
+	expected := `
+		# HELP airq_co2_ppm CO2 concentration in ppm
+		# TYPE airq_co2_ppm gauge
+		airq_co2_ppm{device="airq-1",sensor="scd40"} 725
+		# HELP airq_pm2_5_micrograms_per_cubic_meter PM2.5 concentration in µg/m³
+		# TYPE airq_pm2_5_micrograms_per_cubic_meter gauge
+		airq_pm2_5_micrograms_per_cubic_meter{device="airq-1",sensor="sen55"} 2.5
+		# HELP airq_relative_humidity_percent Relative humidity in %
+		# TYPE airq_relative_humidity_percent gauge
+		airq_relative_humidity_percent{device="airq-1",sensor="best"} 32.5
+		airq_relative_humidity_percent{device="airq-1",sensor="scd40"} 18
+		airq_relative_humidity_percent{device="airq-1",sensor="sen55"} 32.5
+		# HELP airq_temperature_celsius Temperature in °C
+		# TYPE airq_temperature_celsius gauge
+		airq_temperature_celsius{device="airq-1",sensor="best"} 23.5
+		airq_temperature_celsius{device="airq-1",sensor="scd40"} 31
+		airq_temperature_celsius{device="airq-1",sensor="sen55"} 23.5
+	`
+	names := []string{"airq_co2_ppm", "airq_pm2_5_micrograms_per_cubic_meter", "airq_relative_humidity_percent", "airq_temperature_celsius"}
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), names...); err != nil {
+		t.Errorf("conventional metrics mismatch: %v", err)
+	}
+
+	// Legacy names are not registered
+	if count, err := testutil.GatherAndCount(registry, "airq_co2", "airq_temperature"); err != nil || count != 0 {
+		t.Errorf("expected no legacy series, got %d (%v)", count, err)
+	}
+}
+
+func TestPrometheusMetricsGateway_LegacyNamingClimateFamilies(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	gateway := NewPrometheusMetricsGateway(registry, PrometheusMetricsOptions{Naming: MetricNamingLegacy})
+
+	gateway.Update(&entity.AirQuality{Device: "airq-1", Temperature: 23.5, SCD40Temperature: 31, CO2: 725})
+
+	// Temperature and humidity are grouped by quantity under every naming
+	expected := `
+		# HELP airq_temperature_celsius Temperature in °C
+		# TYPE airq_temperature_celsius gauge
+		airq_temperature_celsius{device="airq-1",sensor="best"} 23.5
+		airq_temperature_celsius{device="airq-1",sensor="scd40"} 31
+		airq_temperature_celsius{device="airq-1",sensor="sen55"} 23.5
+	`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "airq_temperature_celsius"); err != nil {
+		t.Errorf("temperature metric mismatch: %v", err)
+	}
+
+	// Quantities measured by a single sensor keep only their legacy name
+	if count, err := testutil.GatherAndCount(registry, "airq_co2", "airq_co2_ppm"); err != nil || count != 1 {
+		t.Errorf("expected only the legacy CO2 series, got %d (%v)", count, err)
+	}
+}
+
+func TestPrometheusMetricsGateway_PreferredSensor(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	gateway := NewPrometheusMetricsGateway(registry, PrometheusMetricsOptions{
+		Naming:          MetricNamingConventional,
+		PreferredSensor: entity.SensorSCD40,
+	})
+
+	gateway.Update(&entity.AirQuality{Device: "airq-1", Temperature: 23.5, SCD40Temperature: 31, CO2: 725})
+
+	expected := `
+		# HELP airq_temperature_celsius Temperature in °C
+		# TYPE airq_temperature_celsius gauge
+		airq_temperature_celsius{device="airq-1",sensor="best"} 31
+		airq_temperature_celsius{device="airq-1",sensor="scd40"} 31
+		airq_temperature_celsius{device="airq-1",sensor="sen55"} 23.5
+	`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "airq_temperature_celsius"); err != nil {
+		t.Errorf("temperature metric mismatch: %v", err)
+	}
+
+	// Quantities measured by a single sensor have no best series
+	if count, err := testutil.GatherAndCount(registry, "airq_co2_ppm"); err != nil || count != 1 {
+		t.Errorf("expected a single CO2 series, got %d (%v)", count, err)
+	}
+}
+
+func TestPrometheusMetricsGateway_BothNamings(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	gateway := NewPrometheusMetricsGateway(registry, PrometheusMetricsOptions{Naming: MetricNamingBoth, SuppressWarmingUp: true})
+
+	gateway.Update(&entity.AirQuality{CO2: 725})
+	if count, err := testutil.GatherAndCount(registry, "airq_co2", "airq_co2_ppm"); err != nil || count != 2 {
+		t.Errorf("expected legacy and conventional CO2 series, got %d (%v)", count, err)
+	}
+
+	// Both schemes hide CO2 while the sensors are warming up
+	gateway.Update(&entity.AirQuality{CO2: 2000, WarmingUp: true})
+	if count, err := testutil.GatherAndCount(registry, "airq_co2", "airq_co2_ppm", "airq_voc_index"); err != nil || count != 0 {
+		t.Errorf("expected CO2 and VOC to be suppressed, got %d series (%v)", count, err)
+	}
+}
+
+func TestParseMetricNaming(t *testing.T) {
+	for _, value := range []string{"legacy", "conventional", "both"} {
+		if _, err := ParseMetricNaming(value); err != nil {
+			t.Errorf("expected %s to be valid, got %v", value, err)
+		}
+	}
+	if _, err := ParseMetricNaming("openmetrics"); err == nil {
+		t.Error("expected error for unknown naming, got nil")
+	}
+}
+
+func TestPrometheusMetricsGateway_Namespace(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	gateway := NewPrometheusMetricsGateway(registry, PrometheusMetricsOptions{Naming: MetricNamingBoth, Namespace: "office"})
+
+	gateway.Update(&entity.AirQuality{CO2: 725})
+	if count, err := testutil.GatherAndCount(registry, "office_co2", "office_co2_ppm", "office_sensor_warming_up"); err != nil || count != 3 {
+		t.Errorf("expected namespaced series, got %d (%v)", count, err)
+	}
+	if count, err := testutil.GatherAndCount(registry, "airq_co2", "airq_co2_ppm"); err != nil || count != 0 {
+		t.Errorf("expected no airq series, got %d (%v)", count, err)
+	}
+
+	if _, err := ParseMetricNamespace("office-1"); err == nil {
+		t.Error("expected error for invalid namespace, got nil")
+	}
+}
+
+func TestPrometheusMetricsGateway_DeviceInfo(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	gateway := NewPrometheusMetricsGateway(registry, PrometheusMetricsOptions{})
+
+	gateway.Update(&entity.AirQuality{Device: "airq-1", Nickname: "AirQ", Name: "raw", DataType: "string", SleepInterval: time.Minute})
+	gateway.Update(&entity.AirQuality{Device: "airq-1", Nickname: "Office", Name: "raw", DataType: "string", SleepInterval: time.Minute})
+
+	expected := `
+		# HELP airq_device_info Device metadata, always 1
+		# TYPE airq_device_info gauge
+		airq_device_info{data_type="string",device="airq-1",name="raw",nickname="Office"} 1
+		# HELP airq_device_sleep_interval_seconds Configured time between device uploads in seconds
+		# TYPE airq_device_sleep_interval_seconds gauge
+		airq_device_sleep_interval_seconds{device="airq-1"} 60
+	`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "airq_device_info", "airq_device_sleep_interval_seconds"); err != nil {
+		t.Errorf("device metrics mismatch: %v", err)
+	}
+}
@@ -0,0 +1,208 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// pushgatewayGroupingLabel is the label whose values split the series into one
+// Pushgateway group per device
+const pushgatewayGroupingLabel = "device"
+
+// PushgatewayOptions configures PushgatewayPublisher
+type PushgatewayOptions struct {
+	// URL of the Pushgateway; user info in the URL enables basic auth
+	URL string
+	Job string
+	// Password replaces the password of the URL when it is not empty
+	Password string
+	// Replace pushes with PUT, replacing every series of a device's group;
+	// otherwise POST only replaces the series of the pushed metric names
+	Replace bool
+}
+
+// PushgatewayPublisher pushes the series of a gatherer to a Prometheus
+// Pushgateway, one group per device, for deployments that cannot be scraped
+type PushgatewayPublisher struct {
+	gatherer prometheus.Gatherer
+	options  PushgatewayOptions
+	url      string
+	username string
+	password string
+	client   *http.Client
+
+	// groups holds the devices pushed so far, "" for the group without a device
+	mu     sync.Mutex
+	groups map[string]bool
+}
+
+// NewPushgatewayPublisher creates a new PushgatewayPublisher
+func NewPushgatewayPublisher(gatherer prometheus.Gatherer, options PushgatewayOptions, client *http.Client) (*PushgatewayPublisher, error) {
+	u, err := url.Parse(options.URL)
+	if err != nil || u.Host == "" || u.Scheme != "http" && u.Scheme != "https" {
+		return nil, errors.New("invalid Pushgateway URL")
+	}
+	if options.Job == "" {
+		return nil, errors.New("Pushgateway job is required")
+	}
+
+	p := &PushgatewayPublisher{
+		gatherer: gatherer,
+		options:  options,
+		client:   client,
+		groups:   make(map[string]bool),
+	}
+	if u.User != nil {
+		p.username = u.User.Username()
+		p.password, _ = u.User.Password()
+		u.User = nil
+	}
+	if options.Password != "" {
+		p.password = options.Password
+	}
+	p.url = u.String()
+	return p, nil
+}
+
+// Push gathers the series and pushes every device's group, together with the
+// series without a device label. Without any device, those are pushed to the
+// group of the job alone.
+func (p *PushgatewayPublisher) Push(ctx context.Context) error {
+	families, err := p.gatherer.Gather()
+	if err != nil {
+		return fmt.Errorf("failed to gather metrics: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var errs []error
+	for device, group := range groupByDevice(families) {
+		pusher := p.pusher(device).Gatherer(prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+			return group, nil
+		}))
+		if p.options.Replace {
+			err = pusher.PushContext(ctx)
+		} else {
+			err = pusher.AddContext(ctx)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to push metrics of %s: %w", groupName(device), err))
+			continue
+		}
+		p.groups[device] = true
+	}
+	return errors.Join(errs...)
+}
+
+// Delete deletes every group pushed so far from the Pushgateway
+func (p *PushgatewayPublisher) Delete() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var errs []error
+	for device := range p.groups {
+		if err := p.pusher(device).Delete(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete metrics of %s: %w", groupName(device), err))
+			continue
+		}
+		delete(p.groups, device)
+	}
+	return errors.Join(errs...)
+}
+
+// pusher returns a Pusher for the group of a device
+func (p *PushgatewayPublisher) pusher(device string) *push.Pusher {
+	pusher := push.New(p.url, p.options.Job).Client(p.client)
+	if p.username != "" || p.password != "" {
+		pusher.BasicAuth(p.username, p.password)
+	}
+	if device != "" {
+		pusher.Grouping(pushgatewayGroupingLabel, device)
+	}
+	return pusher
+}
+
+func groupName(device string) string {
+	if device == "" {
+		return "the job"
+	}
+	return "device " + device
+}
+
+// groupByDevice splits metric families by the device label, which is removed
+// because the Pushgateway adds it from the grouping key. Series without a
+// device label are added to every group.
+func groupByDevice(families []*dto.MetricFamily) map[string][]*dto.MetricFamily {
+	byDevice := make(map[string]map[string]*dto.MetricFamily)
+	var common []*dto.MetricFamily
+	for _, family := range families {
+		var shared []*dto.Metric
+		for _, metric := range family.Metric {
+			device, labels := splitDeviceLabel(metric.Label)
+			if device == "" {
+				shared = append(shared, metric)
+				continue
+			}
+			if byDevice[device] == nil {
+				byDevice[device] = make(map[string]*dto.MetricFamily)
+			}
+			out, ok := byDevice[device][family.GetName()]
+			if !ok {
+				out = &dto.MetricFamily{Name: family.Name, Help: family.Help, Type: family.Type, Unit: family.Unit}
+				byDevice[device][family.GetName()] = out
+			}
+			metric.Label = labels
+			out.Metric = append(out.Metric, metric)
+		}
+		if len(shared) > 0 {
+			common = append(common, &dto.MetricFamily{Name: family.Name, Help: family.Help, Type: family.Type, Unit: family.Unit, Metric: shared})
+		}
+	}
+
+	groups := make(map[string][]*dto.MetricFamily)
+	if len(byDevice) == 0 {
+		if len(common) > 0 {
+			groups[""] = common
+		}
+		return groups
+	}
+	for device, families := range byDevice {
+		group := make([]*dto.MetricFamily, 0, len(families)+len(common))
+		for _, family := range families {
+			group = append(group, family)
+		}
+		// A family with both kinds of series is pushed once
+		for _, family := range common {
+			if out, ok := families[family.GetName()]; ok {
+				out.Metric = append(out.Metric, family.Metric...)
+				continue
+			}
+			group = append(group, family)
+		}
+		sort.Slice(group, func(i, j int) bool { return group[i].GetName() < group[j].GetName() })
+		groups[device] = group
+	}
+	return groups
+}
+
+// splitDeviceLabel returns the device label value and the other labels
+func splitDeviceLabel(pairs []*dto.LabelPair) (string, []*dto.LabelPair) {
+	for i, pair := range pairs {
+		if pair.GetName() == pushgatewayGroupingLabel {
+			labels := make([]*dto.LabelPair, 0, len(pairs)-1)
+			labels = append(labels, pairs[:i]...)
+			return pair.GetValue(), append(labels, pairs[i+1:]...)
+		}
+	}
+	return "", pairs
+}
@@ -0,0 +1,196 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+)
+
+// pushgatewayRequest is a request received by the test Pushgateway
+type pushgatewayRequest struct {
+	method string
+	path   string
+	user   string
+	body   string
+}
+
+func newTestPushgateway(t *testing.T) (*httptest.Server, func() []pushgatewayRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var requests []pushgatewayRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := decodePushedMetrics(t, r)
+		user, password, _ := r.BasicAuth()
+		mu.Lock()
+		requests = append(requests, pushgatewayRequest{method: r.Method, path: r.URL.Path, user: user + ":" + password, body: body})
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []pushgatewayRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		sorted := append([]pushgatewayRequest(nil), requests...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i].path < sorted[j].path })
+		return sorted
+	}
+}
+
+// decodePushedMetrics returns the pushed metrics in the text format
+func decodePushedMetrics(t *testing.T, r *http.Request) string {
+	var b strings.Builder
+	decoder := expfmt.NewDecoder(r.Body, expfmt.ResponseFormat(r.Header))
+	encoder := expfmt.NewEncoder(&b, expfmt.NewFormat(expfmt.TypeTextPlain))
+	for {
+		var family dto.MetricFamily
+		if err := decoder.Decode(&family); err != nil {
+			if err != io.EOF {
+				t.Errorf("failed to decode pushed metrics: %v", err)
+			}
+			return b.String()
+		}
+		encoder.Encode(&family)
+	}
+}
+
+func newPushgatewayTestRegistry() *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	co2 := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "airq_co2", Help: "CO2"}, []string{"device"})
+	co2.WithLabelValues("airq-1").Set(725)
+	co2.WithLabelValues("airq-2").Set(410)
+	info := prometheus.NewGauge(prometheus.GaugeOpts{Name: "airq_build_info", Help: "Build"})
+	info.Set(1)
+	registry.MustRegister(co2, info)
+	return registry
+}
+
+func TestPushgatewayPublisher_PushPerDevice(t *testing.T) {
+	server, requests := newTestPushgateway(t)
+	publisher, err := NewPushgatewayPublisher(newPushgatewayTestRegistry(), PushgatewayOptions{
+		URL:     strings.Replace(server.URL, "http://", "http://user:secret@", 1),
+		Job:     "airq",
+		Replace: true,
+	}, server.Client())
+	if err != nil {
+		t.Fatalf("failed to create publisher: %v", err)
+	}
+
+	if err := publisher.Push(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	got := requests()
+	if len(got) != 2 {
+		t.Fatalf("expected a push per device, got %+v", got)
+	}
+	for i, device := range []string{"airq-1", "airq-2"} {
+		req := got[i]
+		if req.method != http.MethodPut || req.path != "/metrics/job/airq/device/"+device {
+			t.Errorf("expected PUT to the group of %s, got %s %s", device, req.method, req.path)
+		}
+		if req.user != "user:secret" {
+			t.Errorf("expected basic auth from the URL, got %q", req.user)
+		}
+	}
+	if !strings.Contains(got[0].body, "airq_co2 725") || strings.Contains(got[0].body, "410") {
+		t.Errorf("expected only the series of airq-1 without the device label, got %s", got[0].body)
+	}
+	if !strings.Contains(got[1].body, "airq_build_info 1") {
+		t.Errorf("expected series without a device in every group, got %s", got[1].body)
+	}
+}
+
+func TestPushgatewayPublisher_SingleDevice(t *testing.T) {
+	server, requests := newTestPushgateway(t)
+	registry := prometheus.NewRegistry()
+	gateway := NewPrometheusMetricsGateway(registry, PrometheusMetricsOptions{})
+	gateway.Update(&entity.AirQuality{Device: "airq-1", CO2: 725, Maintenance: true})
+	publisher, err := NewPushgatewayPublisher(registry, PushgatewayOptions{URL: server.URL, Job: "airq"}, server.Client())
+	if err != nil {
+		t.Fatalf("failed to create publisher: %v", err)
+	}
+
+	if err := publisher.Push(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	got := requests()
+	if len(got) != 1 || got[0].path != "/metrics/job/airq/device/airq-1" {
+		t.Fatalf("expected a push to the group of the device, got %+v", got)
+	}
+	for _, series := range []string{"airq_co2 725", "airq_maintenance 1", "airq_sensor_warming_up 0", "airq_duplicate_fetches_total 0"} {
+		if !strings.Contains(got[0].body, series) {
+			t.Errorf("expected %q in the group of the device, got %s", series, got[0].body)
+		}
+	}
+}
+
+func TestPushgatewayPublisher_AddAndDelete(t *testing.T) {
+	server, requests := newTestPushgateway(t)
+	publisher, err := NewPushgatewayPublisher(newPushgatewayTestRegistry(), PushgatewayOptions{
+		URL:      "http://admin@" + strings.TrimPrefix(server.URL, "http://"),
+		Job:      "airq",
+		Password: "from-file",
+	}, server.Client())
+	if err != nil {
+		t.Fatalf("failed to create publisher: %v", err)
+	}
+
+	if err := publisher.Push(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := publisher.Delete(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var methods []string
+	for _, req := range requests() {
+		methods = append(methods, req.method)
+		if req.user != "admin:from-file" {
+			t.Errorf("expected the password to be replaced, got %q", req.user)
+		}
+	}
+	sort.Strings(methods)
+	if strings.Join(methods, ",") != "DELETE,DELETE,POST,POST" {
+		t.Errorf("expected a POST and a DELETE per device, got %v", methods)
+	}
+}
+
+func TestPushgatewayPublisher_NoDevice(t *testing.T) {
+	server, requests := newTestPushgateway(t)
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(prometheus.NewCounter(prometheus.CounterOpts{Name: "airq_fetch_errors_total", Help: "Errors"}))
+	publisher, err := NewPushgatewayPublisher(registry, PushgatewayOptions{URL: server.URL, Job: "airq"}, server.Client())
+	if err != nil {
+		t.Fatalf("failed to create publisher: %v", err)
+	}
+
+	if err := publisher.Push(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	got := requests()
+	if len(got) != 1 || got[0].path != "/metrics/job/airq" {
+		t.Errorf("expected a push to the job group, got %+v", got)
+	}
+}
+
+func TestNewPushgatewayPublisher_Invalid(t *testing.T) {
+	for _, options := range []PushgatewayOptions{
+		{URL: "pushgateway:9091", Job: "airq"},
+		{URL: "ftp://pushgateway:9091", Job: "airq"},
+		{URL: "http://pushgateway:9091"},
+	} {
+		if _, err := NewPushgatewayPublisher(prometheus.NewRegistry(), options, http.DefaultClient); err == nil {
+			t.Errorf("expected an error for %+v", options)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package gateway
+
+import (
+	"sync"
+
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+)
+
+// subscriberBufferSize is the number of events buffered per subscriber before it is dropped
+const subscriberBufferSize = 16
+
+// ReadingEvent is a reading with a monotonically increasing event ID
+type ReadingEvent struct {
+	ID      uint64
+	Reading *entity.AirQuality
+}
+
+// ReadingBroker implements ReadingSink and fans out readings to live subscribers.
+// It keeps a buffer of recent events so that reconnecting clients can catch up.
+type ReadingBroker struct {
+	mu          sync.Mutex
+	nextID      uint64
+	recent      []ReadingEvent
+	capacity    int
+	subscribers map[chan ReadingEvent]struct{}
+	closed      bool
+}
+
+// NewReadingBroker creates a new ReadingBroker that keeps up to capacity recent events
+func NewReadingBroker(capacity int) *ReadingBroker {
+	return &ReadingBroker{
+		nextID:      1,
+		capacity:    capacity,
+		subscribers: make(map[chan ReadingEvent]struct{}),
+	}
+}
+
+// Publish assigns an event ID to the reading and delivers it to all subscribers.
+// Subscribers that cannot keep up are disconnected; they can resume with their last event ID.
+func (b *ReadingBroker) Publish(data *entity.AirQuality) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	event := ReadingEvent{ID: b.nextID, Reading: data}
+	b.nextID++
+
+	if b.capacity > 0 {
+		if len(b.recent) >= b.capacity {
+			b.recent = append(b.recent[:0], b.recent[1:]...)
+		}
+		b.recent = append(b.recent, event)
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribe registers a new subscriber. It returns the buffered events newer than
+// lastEventID, a channel for live events and a function to unsubscribe.
+// A lastEventID of 0 skips the replay. An ID newer than any published event
+// (e.g. from before an exporter restart) replays the whole buffer.
+// The channel is closed when the broker is closed or the subscriber falls behind.
+func (b *ReadingBroker) Subscribe(lastEventID uint64) ([]ReadingEvent, <-chan ReadingEvent, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan ReadingEvent, subscriberBufferSize)
+	if b.closed {
+		close(ch)
+		return nil, ch, func() {}
+	}
+	b.subscribers[ch] = struct{}{}
+
+	var replay []ReadingEvent
+	if lastEventID > 0 {
+		if lastEventID >= b.nextID {
+			lastEventID = 0
+		}
+		for _, event := range b.recent {
+			if event.ID > lastEventID {
+				replay = append(replay, event)
+			}
+		}
+	}
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+
+	return replay, ch, unsubscribe
+}
+
+// Close disconnects all subscribers and stops accepting new readings
+func (b *ReadingBroker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+	b.closed = true
+	for ch := range b.subscribers {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
@@ -0,0 +1,88 @@
+package gateway
+
+import (
+	"testing"
+
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+)
+
+func TestReadingBroker_PublishToSubscriber(t *testing.T) {
+	broker := NewReadingBroker(10)
+	_, ch, unsubscribe := broker.Subscribe(0)
+	defer unsubscribe()
+
+	broker.Publish(&entity.AirQuality{CO2: 725})
+
+	event := <-ch
+	if event.ID != 1 {
+		t.Errorf("expected event ID 1, got %d", event.ID)
+	}
+	if event.Reading.CO2 != 725 {
+		t.Errorf("expected CO2 725, got %d", event.Reading.CO2)
+	}
+}
+
+func TestReadingBroker_Replay(t *testing.T) {
+	broker := NewReadingBroker(3)
+	for i := 1; i <= 5; i++ {
+		broker.Publish(&entity.AirQuality{CO2: i})
+	}
+
+	// Events 3..5 are buffered; the client has seen up to 3
+	replay, _, unsubscribe := broker.Subscribe(3)
+	defer unsubscribe()
+	if len(replay) != 2 || replay[0].ID != 4 || replay[1].ID != 5 {
+		t.Errorf("expected replay of events 4 and 5, got %+v", replay)
+	}
+
+	// No replay without a last event ID
+	replay, _, unsubscribe2 := broker.Subscribe(0)
+	defer unsubscribe2()
+	if len(replay) != 0 {
+		t.Errorf("expected no replay, got %d events", len(replay))
+	}
+
+	// An unknown future ID replays the whole buffer
+	replay, _, unsubscribe3 := broker.Subscribe(100)
+	defer unsubscribe3()
+	if len(replay) != 3 || replay[0].ID != 3 {
+		t.Errorf("expected replay of the whole buffer, got %+v", replay)
+	}
+}
+
+func TestReadingBroker_SlowSubscriberIsDropped(t *testing.T) {
+	broker := NewReadingBroker(0)
+	_, ch, unsubscribe := broker.Subscribe(0)
+	defer unsubscribe()
+
+	for i := 0; i < subscriberBufferSize+1; i++ {
+		broker.Publish(&entity.AirQuality{CO2: i})
+	}
+
+	count := 0
+	for range ch {
+		count++
+	}
+	if count != subscriberBufferSize {
+		t.Errorf("expected %d buffered events before disconnect, got %d", subscriberBufferSize, count)
+	}
+}
+
+func TestReadingBroker_Close(t *testing.T) {
+	broker := NewReadingBroker(10)
+	_, ch, unsubscribe := broker.Subscribe(0)
+	defer unsubscribe()
+
+	broker.Close()
+
+	if _, ok := <-ch; ok {
+		t.Error("expected subscriber channel to be closed")
+	}
+
+	// Publishing and subscribing after close must not panic
+	broker.Publish(&entity.AirQuality{})
+	_, ch, _ = broker.Subscribe(0)
+	if _, ok := <-ch; ok {
+		t.Error("expected channel of late subscriber to be closed")
+	}
+}
@@ -0,0 +1,102 @@
+package gateway
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/suzutan/m5stack_airq_exporter/domain/repository"
+)
+
+// redacted replaces secrets in logged values
+const redacted = "REDACTED"
+
+// RedactURL returns the URL with the EzData token in /api/v2/{token}/..., the
+// password and all query values replaced, so that it can be logged
+func RedactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return redacted
+	}
+
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redacted)
+		}
+	}
+
+	segments := strings.Split(u.Path, "/")
+	for i := 2; i < len(segments); i++ {
+		if segments[i-2] == "api" && segments[i-1] == "v2" && segments[i] != "" {
+			segments[i] = redacted
+		}
+	}
+	u.Path = strings.Join(segments, "/")
+	u.RawPath = ""
+
+	if u.RawQuery != "" {
+		query := u.Query()
+		for key := range query {
+			query.Set(key, redacted)
+		}
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
+
+// DeviceID derives a stable device ID from an EzData data token. The token is
+// the secret of the device's API URL, so it must not be used as an ID that is
+// exposed in APIs, stores and logs. Recordings replace the token with its
+// device ID, which is therefore returned as is.
+func DeviceID(token string) string {
+	if token == "" || isDeviceID(token) {
+		return token
+	}
+	sum := sha256.Sum256([]byte(token))
+	return deviceIDPrefix + hex.EncodeToString(sum[:6])
+}
+
+// deviceIDPrefix starts every device ID derived from a token
+const deviceIDPrefix = "airq-"
+
+// isDeviceID reports whether s is a device ID rather than a token
+func isDeviceID(s string) bool {
+	id, ok := strings.CutPrefix(s, deviceIDPrefix)
+	if !ok || len(id) != 12 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+// EzDataURLTemplate is the default API URL for a token read from a file
+const EzDataURLTemplate = "https://ezdata2.m5stack.com/api/v2/{token}/dataMacByKey/raw"
+
+// DataURLFromTokenFile reads an EzData token from a file, such as a mounted
+// Kubernetes secret, and substitutes it for {token} in the URL template
+func DataURLFromTokenFile(path, template string) (string, error) {
+	if template == "" {
+		template = EzDataURLTemplate
+	}
+	if !strings.Contains(template, "{token}") {
+		return "", fmt.Errorf("URL template does not contain {token}: %s", RedactURL(template))
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read token file: %w", err)
+	}
+	token := strings.TrimSpace(string(content))
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", path)
+	}
+	return strings.ReplaceAll(template, "{token}", url.PathEscape(token)), nil
+}
+
+// classify attaches an error class to err
+func classify(class string, err error) error {
+	return &repository.ClassifiedError{Class: class, Err: err}
+}
@@ -0,0 +1,226 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.yaml.in/yaml/v2"
+)
+
+// Relabel actions, a subset of Prometheus metric_relabel_configs
+const (
+	RelabelReplace   = "replace"
+	RelabelKeep      = "keep"
+	RelabelDrop      = "drop"
+	RelabelLabelDrop = "labeldrop"
+	RelabelLabelKeep = "labelkeep"
+)
+
+// metricNameLabel holds the metric name while rules are applied
+const metricNameLabel = "__name__"
+
+// RelabelRule rewrites or filters series at scrape time, with the semantics of a
+// Prometheus metric_relabel_configs entry
+type RelabelRule struct {
+	SourceLabels []string `yaml:"source_labels"`
+	Separator    *string  `yaml:"separator"`
+	Regex        *string  `yaml:"regex"`
+	TargetLabel  string   `yaml:"target_label"`
+	Replacement  *string  `yaml:"replacement"`
+	Action       string   `yaml:"action"`
+
+	regex *regexp.Regexp
+}
+
+// LoadRelabelRules reads a YAML list of relabel rules
+func LoadRelabelRules(path string) ([]RelabelRule, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read relabel rules: %w", err)
+	}
+
+	var rules []RelabelRule
+	if err := yaml.UnmarshalStrict(content, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse relabel rules: %w", err)
+	}
+	for i := range rules {
+		if err := rules[i].compile(); err != nil {
+			return nil, fmt.Errorf("invalid relabel rule %d: %w", i+1, err)
+		}
+	}
+	return rules, nil
+}
+
+// compile validates the rule and fills in the defaults
+func (r *RelabelRule) compile() error {
+	if r.Action == "" {
+		r.Action = RelabelReplace
+	}
+	expr := "(.*)"
+	if r.Regex != nil {
+		expr = *r.Regex
+	}
+	regex, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return fmt.Errorf("invalid regex: %w", err)
+	}
+	r.regex = regex
+
+	switch r.Action {
+	case RelabelReplace:
+		if r.TargetLabel == "" {
+			return errors.New("replace requires target_label")
+		}
+	case RelabelKeep, RelabelDrop:
+		if len(r.SourceLabels) == 0 {
+			return fmt.Errorf("%s requires source_labels", r.Action)
+		}
+	case RelabelLabelDrop, RelabelLabelKeep:
+		if r.Regex == nil {
+			return fmt.Errorf("%s requires regex", r.Action)
+		}
+	default:
+		return fmt.Errorf("unsupported action: %s", r.Action)
+	}
+	return nil
+}
+
+// apply rewrites the labels of a series and reports whether it is kept
+func (r *RelabelRule) apply(labels map[string]string) bool {
+	separator := ";"
+	if r.Separator != nil {
+		separator = *r.Separator
+	}
+	values := make([]string, len(r.SourceLabels))
+	for i, name := range r.SourceLabels {
+		values[i] = labels[name]
+	}
+	value := strings.Join(values, separator)
+
+	switch r.Action {
+	case RelabelKeep:
+		return r.regex.MatchString(value)
+	case RelabelDrop:
+		return !r.regex.MatchString(value)
+	case RelabelLabelDrop, RelabelLabelKeep:
+		for name := range labels {
+			if name == metricNameLabel {
+				continue
+			}
+			if r.regex.MatchString(name) == (r.Action == RelabelLabelDrop) {
+				delete(labels, name)
+			}
+		}
+	case RelabelReplace:
+		match := r.regex.FindStringSubmatchIndex(value)
+		if match == nil {
+			return true
+		}
+		replacement := "$1"
+		if r.Replacement != nil {
+			replacement = *r.Replacement
+		}
+		result := string(r.regex.ExpandString(nil, replacement, value, match))
+		if result == "" {
+			delete(labels, r.TargetLabel)
+		} else {
+			labels[r.TargetLabel] = result
+		}
+	}
+	return true
+}
+
+// RelabelingGatherer applies relabel rules to every series of another gatherer
+type RelabelingGatherer struct {
+	gatherer prometheus.Gatherer
+	rules    []RelabelRule
+}
+
+// NewRelabelingGatherer creates a new RelabelingGatherer
+func NewRelabelingGatherer(gatherer prometheus.Gatherer, rules []RelabelRule) *RelabelingGatherer {
+	return &RelabelingGatherer{
+		gatherer: gatherer,
+		rules:    rules,
+	}
+}
+
+// Gather implements prometheus.Gatherer
+func (g *RelabelingGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := g.gatherer.Gather()
+	if err != nil {
+		return families, err
+	}
+
+	byName := make(map[string]*dto.MetricFamily)
+	seen := make(map[string]bool)
+	for _, family := range families {
+	series:
+		for _, metric := range family.Metric {
+			labels := map[string]string{metricNameLabel: family.GetName()}
+			for _, pair := range metric.Label {
+				labels[pair.GetName()] = pair.GetValue()
+			}
+			for i := range g.rules {
+				if !g.rules[i].apply(labels) {
+					continue series
+				}
+			}
+
+			name := labels[metricNameLabel]
+			if !metricNamePattern.MatchString(name) {
+				return nil, fmt.Errorf("relabeling produced an invalid metric name: %q", name)
+			}
+			delete(labels, metricNameLabel)
+			metric.Label = labelPairs(labels)
+
+			key := name + "{" + labelString(metric.Label) + "}"
+			if seen[key] {
+				return nil, fmt.Errorf("relabeling produced duplicate series %s", key)
+			}
+			seen[key] = true
+
+			out, ok := byName[name]
+			if !ok {
+				out = &dto.MetricFamily{Name: &name, Help: family.Help, Type: family.Type, Unit: family.Unit}
+				byName[name] = out
+			} else if out.GetType() != family.GetType() {
+				return nil, fmt.Errorf("relabeling merged %s and %s series into %s", out.GetType(), family.GetType(), name)
+			}
+			out.Metric = append(out.Metric, metric)
+		}
+	}
+
+	result := make([]*dto.MetricFamily, 0, len(byName))
+	for _, family := range byName {
+		sort.Slice(family.Metric, func(i, j int) bool {
+			return labelString(family.Metric[i].Label) < labelString(family.Metric[j].Label)
+		})
+		result = append(result, family)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].GetName() < result[j].GetName() })
+	return result, nil
+}
+
+// labelPairs converts labels to sorted label pairs
+func labelPairs(labels map[string]string) []*dto.LabelPair {
+	pairs := make([]*dto.LabelPair, 0, len(labels))
+	for name, value := range labels {
+		pairs = append(pairs, &dto.LabelPair{Name: &name, Value: &value})
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].GetName() < pairs[j].GetName() })
+	return pairs
+}
+
+func labelString(pairs []*dto.LabelPair) string {
+	parts := make([]string, len(pairs))
+	for i, pair := range pairs {
+		parts[i] = fmt.Sprintf("%s=%q", pair.GetName(), pair.GetValue())
+	}
+	return strings.Join(parts, ",")
+}
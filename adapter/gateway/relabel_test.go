@@ -0,0 +1,114 @@
+package gateway
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+)
+
+func writeRelabelRules(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "relabel.yml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write rules: %v", err)
+	}
+	return path
+}
+
+func TestRelabelingGatherer(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	gateway := NewPrometheusMetricsGateway(registry, PrometheusMetricsOptions{Naming: MetricNamingConventional})
+	gateway.Update(&entity.AirQuality{Device: "airq-1", Temperature: 23.5, SCD40Temperature: 31, CO2: 725})
+
+	rules, err := LoadRelabelRules(writeRelabelRules(t, `
+- source_labels: [__name__]
+  regex: airq_(pm|nox|voc).*
+  action: drop
+- source_labels: [__name__, sensor]
+  regex: airq_temperature_celsius;scd40
+  action: drop
+- source_labels: [sensor]
+  regex: sen55
+  target_label: sensor
+  replacement: particulate
+- source_labels: [__name__]
+  regex: airq_(.*)
+  target_label: __name__
+  replacement: office_$1
+`))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	gatherer := NewRelabelingGatherer(registry, rules)
+	expected := `
+		# HELP office_co2_ppm CO2 concentration in ppm
+		# TYPE office_co2_ppm gauge
+		office_co2_ppm{device="airq-1",sensor="scd40"} 725
+		# HELP office_temperature_celsius Temperature in °C
+		# TYPE office_temperature_celsius gauge
+		office_temperature_celsius{device="airq-1",sensor="best"} 23.5
+		office_temperature_celsius{device="airq-1",sensor="particulate"} 23.5
+	`
+	if err := testutil.GatherAndCompare(gatherer, strings.NewReader(expected), "office_co2_ppm", "office_temperature_celsius"); err != nil {
+		t.Errorf("relabeled metrics mismatch: %v", err)
+	}
+
+	if count, err := testutil.GatherAndCount(gatherer, "office_pm2_5_micrograms_per_cubic_meter", "airq_co2_ppm"); err != nil || count != 0 {
+		t.Errorf("expected dropped and renamed series to be gone, got %d (%v)", count, err)
+	}
+}
+
+func TestRelabelingGatherer_LabelDrop(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	gateway := NewPrometheusMetricsGateway(registry, PrometheusMetricsOptions{Naming: MetricNamingConventional})
+	gateway.Update(&entity.AirQuality{Temperature: 23.5, SCD40Temperature: 31})
+
+	rules, err := LoadRelabelRules(writeRelabelRules(t, "- action: labeldrop\n  regex: sensor\n"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// Dropping the sensor label makes the temperature series collide
+	if _, err := NewRelabelingGatherer(registry, rules).Gather(); err == nil {
+		t.Error("expected duplicate series error, got nil")
+	}
+}
+
+func TestLoadRelabelRules_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"unknown action":     "- action: hashmod\n  source_labels: [sensor]\n",
+		"invalid regex":      "- action: drop\n  source_labels: [sensor]\n  regex: '('\n",
+		"replace target":     "- source_labels: [sensor]\n",
+		"drop source labels": "- action: drop\n",
+		"unknown field":      "- action: drop\n  source_label: [sensor]\n",
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := LoadRelabelRules(writeRelabelRules(t, content)); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestParseConstLabels(t *testing.T) {
+	labels, err := ParseConstLabels("site=tokyo, room=101")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(labels) != 2 || labels["site"] != "tokyo" || labels["room"] != "101" {
+		t.Errorf("unexpected labels: %v", labels)
+	}
+
+	for _, value := range []string{"site", "site=", "1site=a", "__site=a", "sensor=a", "site=a,site=b"} {
+		if _, err := ParseConstLabels(value); err == nil {
+			t.Errorf("expected error for %q, got nil", value)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+)
+
+// WebhookReportGateway implements ReportPublisher by POSTing reports as JSON
+type WebhookReportGateway struct {
+	url    string
+	client HTTPClient
+}
+
+// NewWebhookReportGateway creates a new WebhookReportGateway posting to url
+func NewWebhookReportGateway(url string, client HTTPClient) *WebhookReportGateway {
+	return &WebhookReportGateway{
+		url:    url,
+		client: client,
+	}
+}
+
+// Publish posts the report to the webhook
+func (g *WebhookReportGateway) Publish(ctx context.Context, report *entity.Report) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
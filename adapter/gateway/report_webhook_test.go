@@ -0,0 +1,56 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+)
+
+func TestWebhookReportGateway_Publish(t *testing.T) {
+	var received entity.Report
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("expected POST, got %s", r.Method)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("expected application/json, got %s", ct)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("failed to decode report: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	report := &entity.Report{
+		Period:  entity.ReportPeriodWeekly,
+		From:    time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC),
+		To:      time.Date(2026, 1, 12, 0, 0, 0, 0, time.UTC),
+		Devices: []entity.DeviceReport{{Device: "dev-1", Readings: 42}},
+	}
+
+	gateway := NewWebhookReportGateway(server.URL, server.Client())
+	if err := gateway.Publish(context.Background(), report); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if received.Period != entity.ReportPeriodWeekly || len(received.Devices) != 1 || received.Devices[0].Readings != 42 {
+		t.Errorf("unexpected report received: %+v", received)
+	}
+}
+
+func TestWebhookReportGateway_Publish_HTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	gateway := NewWebhookReportGateway(server.URL, server.Client())
+	if err := gateway.Publish(context.Background(), &entity.Report{}); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
@@ -0,0 +1,121 @@
+package gateway
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+	"github.com/suzutan/m5stack_airq_exporter/usecase"
+)
+
+// rollingStatsSource computes rolling statistics at scrape time
+type rollingStatsSource interface {
+	Execute() []usecase.RollingStat
+}
+
+// rollingStatDescs holds the metric descriptions of one measurement
+type rollingStatDescs struct {
+	avg *prometheus.Desc
+	min *prometheus.Desc
+	max *prometheus.Desc
+}
+
+// rollingStatAggregates lists the exported aggregates with their help text
+var rollingStatAggregates = []struct{ suffix, help string }{
+	{"avg", "Time-weighted average"},
+	{"min", "Minimum"},
+	{"max", "Maximum"},
+}
+
+// RollingStatsCollector exports rolling averages, minimums and maximums of every
+// measurement with device and window labels: as <namespace>_<field>_avg, _min and _max with
+// legacy naming, and as <namespace>_<quantity>_avg_<unit> and so on with a sensor
+// label with conventional naming
+type RollingStatsCollector struct {
+	source  rollingStatsSource
+	options PrometheusMetricsOptions
+
+	legacy       map[string]rollingStatDescs
+	conventional map[string]rollingStatDescs
+	// all lists every description once, as sensors share conventional ones
+	all []*prometheus.Desc
+}
+
+// NewRollingStatsCollector creates a new RollingStatsCollector reading from
+// source, named like the gauges of PrometheusMetricsGateway with the same options
+func NewRollingStatsCollector(source rollingStatsSource, options PrometheusMetricsOptions) *RollingStatsCollector {
+	if options.PreferredSensor == "" {
+		options.PreferredSensor = entity.SensorSEN55
+	}
+	naming, namespace := options.Naming, options.namespace()
+	c := &RollingStatsCollector{
+		source:       source,
+		options:      options,
+		legacy:       make(map[string]rollingStatDescs),
+		conventional: make(map[string]rollingStatDescs),
+	}
+	byName := make(map[string]*prometheus.Desc)
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		if d, ok := byName[name]; ok {
+			return d
+		}
+		d := prometheus.NewDesc(name, help, append(append([]string{"device"}, labels...), "window"), nil)
+		byName[name] = d
+		c.all = append(c.all, d)
+		return d
+	}
+
+	for _, f := range entity.Fields {
+		var legacy, conventional [3]*prometheus.Desc
+		m := conventionalMetricOf(f)
+		for i, agg := range rollingStatAggregates {
+			if naming.Legacy() {
+				legacy[i] = desc(namespace+"_"+f.Key+"_"+agg.suffix, rollingStatHelp(agg.help, f.Description, f.Unit))
+			}
+			if naming.conventionalFor(f) {
+				conventional[i] = desc(m.name(namespace, agg.suffix), rollingStatHelp(agg.help, m.quantity, m.symbol), "sensor")
+			}
+		}
+		c.legacy[f.Key] = rollingStatDescs{avg: legacy[0], min: legacy[1], max: legacy[2]}
+		c.conventional[f.Key] = rollingStatDescs{avg: conventional[0], min: conventional[1], max: conventional[2]}
+	}
+	return c
+}
+
+func rollingStatHelp(aggregate, quantity, unit string) string {
+	help := fmt.Sprintf("%s of %s over the window", aggregate, quantity)
+	if unit != "" {
+		help += fmt.Sprintf(" in %s", unit)
+	}
+	return help
+}
+
+// Describe implements prometheus.Collector
+func (c *RollingStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.all {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector
+func (c *RollingStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, stat := range c.source.Execute() {
+		window := usecase.FormatWindow(stat.Window)
+		if d, ok := c.legacy[stat.Field.Key]; ok && c.options.Naming.Legacy() {
+			ch <- prometheus.MustNewConstMetric(d.avg, prometheus.GaugeValue, stat.Avg, stat.Device, window)
+			ch <- prometheus.MustNewConstMetric(d.min, prometheus.GaugeValue, stat.Min, stat.Device, window)
+			ch <- prometheus.MustNewConstMetric(d.max, prometheus.GaugeValue, stat.Max, stat.Device, window)
+		}
+		if d, ok := c.conventional[stat.Field.Key]; ok && c.options.Naming.conventionalFor(stat.Field) {
+			sensors := []string{stat.Field.Sensor}
+			if reportsBest(stat.Field, c.options.PreferredSensor) {
+				sensors = append(sensors, bestSensor)
+			}
+			for _, sensor := range sensors {
+				ch <- prometheus.MustNewConstMetric(d.avg, prometheus.GaugeValue, stat.Avg, stat.Device, sensor, window)
+				ch <- prometheus.MustNewConstMetric(d.min, prometheus.GaugeValue, stat.Min, stat.Device, sensor, window)
+				ch <- prometheus.MustNewConstMetric(d.max, prometheus.GaugeValue, stat.Max, stat.Device, sensor, window)
+			}
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package gateway
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+	"github.com/suzutan/m5stack_airq_exporter/usecase"
+)
+
+func TestRollingStatsCollector(t *testing.T) {
+	base := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	history := NewMemoryHistoryGateway(24 * time.Hour)
+	history.Publish(&entity.AirQuality{Device: "airq-1", CO2: 600, UpdateTime: base.Add(-time.Hour)})
+	history.Publish(&entity.AirQuality{Device: "airq-1", CO2: 1000, UpdateTime: base.Add(-30 * time.Minute)})
+	history.Publish(&entity.AirQuality{Device: "airq-1", CO2: 900, UpdateTime: base})
+
+	collector := NewRollingStatsCollector(usecase.NewRollingStatsUsecase(history, []time.Duration{time.Hour, 8 * time.Hour}), PrometheusMetricsOptions{})
+
+	expected := `
+		# HELP airq_co2_avg Time-weighted average of CO2 concentration over the window in ppm
+		# TYPE airq_co2_avg gauge
+		airq_co2_avg{device="airq-1",window="1h"} 800
+		airq_co2_avg{device="airq-1",window="8h"} 800
+		# HELP airq_co2_max Maximum of CO2 concentration over the window in ppm
+		# TYPE airq_co2_max gauge
+		airq_co2_max{device="airq-1",window="1h"} 1000
+		airq_co2_max{device="airq-1",window="8h"} 1000
+	`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(expected), "airq_co2_avg", "airq_co2_max"); err != nil {
+		t.Errorf("rolling stats mismatch: %v", err)
+	}
+
+	// The temperature and humidity families add three series each: one per sensor and the best one
+	if count := testutil.CollectAndCount(collector); count != 2*3*(len(entity.Fields)+6) {
+		t.Errorf("expected %d series, got %d", 2*3*(len(entity.Fields)+6), count)
+	}
+	if count := testutil.CollectAndCount(collector, "airq_temperature_avg_celsius"); count != 2*3 {
+		t.Errorf("expected grouped temperature series under legacy naming, got %d", count)
+	}
+}
+
+func TestRollingStatsCollector_EmptyHistory(t *testing.T) {
+	collector := NewRollingStatsCollector(usecase.NewRollingStatsUsecase(NewMemoryHistoryGateway(time.Hour), usecase.DefaultRollingWindows), PrometheusMetricsOptions{})
+
+	if count := testutil.CollectAndCount(collector); count != 0 {
+		t.Errorf("expected no series without readings, got %d", count)
+	}
+}
+
+func TestRollingStatsCollector_ConventionalNaming(t *testing.T) {
+	base := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	history := NewMemoryHistoryGateway(24 * time.Hour)
+	history.Publish(&entity.AirQuality{Device: "airq-1", Humidity: 40, SCD40Humidity: 20, UpdateTime: base.Add(-time.Hour)})
+	history.Publish(&entity.AirQuality{Device: "airq-1", Humidity: 50, SCD40Humidity: 30, UpdateTime: base})
+
+	collector := NewRollingStatsCollector(usecase.NewRollingStatsUsecase(history, []time.Duration{time.Hour}), PrometheusMetricsOptions{Naming: MetricNamingConventional})
+
+	expected := `
+		# HELP airq_relative_humidity_max_percent Maximum of Relative humidity over the window in %
+		# TYPE airq_relative_humidity_max_percent gauge
+		airq_relative_humidity_max_percent{device="airq-1",sensor="best",window="1h"} 50
+		airq_relative_humidity_max_percent{device="airq-1",sensor="scd40",window="1h"} 30
+		airq_relative_humidity_max_percent{device="airq-1",sensor="sen55",window="1h"} 50
+	`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(expected), "airq_relative_humidity_max_percent"); err != nil {
+		t.Errorf("rolling stats mismatch: %v", err)
+	}
+
+	if count := testutil.CollectAndCount(collector, "airq_humidity_max"); count != 0 {
+		t.Errorf("expected no legacy series, got %d", count)
+	}
+}
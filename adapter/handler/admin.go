@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+)
+
+// schedulerController controls the fetch scheduler
+type schedulerController interface {
+	Refresh(ctx context.Context, device string) (entity.SchedulerRun, error)
+	Pause()
+	Resume()
+	SetInterval(interval time.Duration) error
+	State() entity.SchedulerState
+}
+
+// AdminHandler handles the /admin endpoints, which control the fetch scheduler
+type AdminHandler struct {
+	scheduler schedulerController
+	devices   deviceLister
+}
+
+// NewAdminHandler creates a new AdminHandler with the given scheduler and device inventory
+func NewAdminHandler(scheduler schedulerController, devices deviceLister) *AdminHandler {
+	return &AdminHandler{
+		scheduler: scheduler,
+		devices:   devices,
+	}
+}
+
+// intervalRequest is the body of PUT /admin/scheduler/interval
+type intervalRequest struct {
+	Interval string `json:"interval"`
+}
+
+// HandleState returns the scheduler state
+func (h *AdminHandler) HandleState(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.scheduler.State())
+}
+
+// HandlePause pauses the scheduled fetches and returns the scheduler state
+func (h *AdminHandler) HandlePause(c echo.Context) error {
+	h.scheduler.Pause()
+	return c.JSON(http.StatusOK, h.scheduler.State())
+}
+
+// HandleResume resumes the scheduled fetches and returns the scheduler state
+func (h *AdminHandler) HandleResume(c echo.Context) error {
+	h.scheduler.Resume()
+	return c.JSON(http.StatusOK, h.scheduler.State())
+}
+
+// HandleInterval changes the interval of the scheduled fetches, given as a Go
+// duration such as 30s or 5m, and returns the scheduler state
+func (h *AdminHandler) HandleInterval(c echo.Context) error {
+	var req intervalRequest
+	if err := decodeJSONBody(c, &req); err != nil {
+		return err
+	}
+	interval, err := time.ParseDuration(req.Interval)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid interval")
+	}
+	if err := h.scheduler.SetInterval(interval); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return c.JSON(http.StatusOK, h.scheduler.State())
+}
+
+// HandleRefresh fetches a device, looked up by ID or nickname, right after the
+// fetch in progress and returns the run. A failed fetch is a 502 with the run.
+func (h *AdminHandler) HandleRefresh(c echo.Context) error {
+	device := c.Param("device")
+	id := ""
+	for _, info := range h.devices.List() {
+		if info.Device == device || info.Nickname == device {
+			id = info.Device
+			break
+		}
+	}
+	if id == "" {
+		return echo.NewHTTPError(http.StatusNotFound, "device not found")
+	}
+
+	run, err := h.scheduler.Refresh(c.Request().Context(), id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+	}
+	if run.Result == entity.RunResultError {
+		return c.JSON(http.StatusBadGateway, run)
+	}
+	return c.JSON(http.StatusOK, run)
+}
+
+// decodeJSONBody decodes the request body into v. The body is read as JSON
+// whatever its content type, as curl -d sends a form one that Bind would ignore.
+func decodeJSONBody(c echo.Context, v any) error {
+	if err := json.NewDecoder(c.Request().Body).Decode(v); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+)
+
+type mockSchedulerController struct {
+	state     entity.SchedulerState
+	run       entity.SchedulerRun
+	err       error
+	refreshed string
+}
+
+func (m *mockSchedulerController) Refresh(_ context.Context, device string) (entity.SchedulerRun, error) {
+	m.refreshed = device
+	return m.run, m.err
+}
+
+func (m *mockSchedulerController) Pause()  { m.state.Paused = true }
+func (m *mockSchedulerController) Resume() { m.state.Paused = false }
+
+func (m *mockSchedulerController) SetInterval(interval time.Duration) error {
+	if interval < 5*time.Second {
+		return errors.New("interval must be at least 5s")
+	}
+	m.state.Interval = interval.Seconds()
+	return nil
+}
+
+func (m *mockSchedulerController) State() entity.SchedulerState { return m.state }
+
+func newAdminTestHandler(scheduler *mockSchedulerController) *AdminHandler {
+	return NewAdminHandler(scheduler, &mockDeviceLister{devices: []entity.DeviceInfo{{Device: "airq-1", Nickname: "Office"}}})
+}
+
+func TestAdminHandler_Refresh(t *testing.T) {
+	tests := []struct {
+		name     string
+		device   string
+		run      entity.SchedulerRun
+		err      error
+		status   int
+		expected string
+	}{
+		{"by nickname", "Office", entity.SchedulerRun{Result: entity.RunResultOK}, nil, http.StatusOK, "airq-1"},
+		{"by ID", "airq-1", entity.SchedulerRun{Result: entity.RunResultDuplicate}, nil, http.StatusOK, "airq-1"},
+		{"unknown device", "Kitchen", entity.SchedulerRun{}, nil, http.StatusNotFound, ""},
+		{"failed fetch", "airq-1", entity.SchedulerRun{Result: entity.RunResultError, Error: "timeout"}, nil, http.StatusBadGateway, "airq-1"},
+		{"queue full", "airq-1", entity.SchedulerRun{}, errors.New("refresh queue is full"), http.StatusServiceUnavailable, "airq-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheduler := &mockSchedulerController{run: tt.run, err: tt.err}
+			handler := newAdminTestHandler(scheduler)
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/admin/refresh/"+tt.device, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("device")
+			c.SetParamValues(tt.device)
+
+			err := handler.HandleRefresh(c)
+			status := rec.Code
+			if err != nil {
+				var httpErr *echo.HTTPError
+				if !errors.As(err, &httpErr) {
+					t.Fatalf("expected an HTTP error, got %v", err)
+				}
+				status = httpErr.Code
+			}
+			if status != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, status)
+			}
+			if scheduler.refreshed != tt.expected {
+				t.Errorf("expected refresh of %q, got %q", tt.expected, scheduler.refreshed)
+			}
+		})
+	}
+}
+
+func TestAdminHandler_PauseResumeAndInterval(t *testing.T) {
+	scheduler := &mockSchedulerController{state: entity.SchedulerState{Interval: 60}}
+	handler := newAdminTestHandler(scheduler)
+	e := echo.New()
+
+	rec := httptest.NewRecorder()
+	if err := handler.HandlePause(e.NewContext(httptest.NewRequest(http.MethodPost, "/admin/scheduler/pause", nil), rec)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var state map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &state); err != nil {
+		t.Fatalf("expected valid JSON, got %v", err)
+	}
+	if state["paused"] != true {
+		t.Errorf("expected a paused scheduler, got %v", state)
+	}
+
+	req := httptest.NewRequest(http.MethodPut, "/admin/scheduler/interval", strings.NewReader(`{"interval":"30s"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	if err := handler.HandleInterval(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if scheduler.state.Interval != 30 {
+		t.Errorf("expected an interval of 30 seconds, got %v", scheduler.state.Interval)
+	}
+
+	// curl -d without a content type sends the JSON body as a form
+	req = httptest.NewRequest(http.MethodPut, "/admin/scheduler/interval", strings.NewReader(`{"interval":"45s"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	if err := handler.HandleInterval(e.NewContext(req, httptest.NewRecorder())); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if scheduler.state.Interval != 45 {
+		t.Errorf("expected an interval of 45 seconds, got %v", scheduler.state.Interval)
+	}
+	req = httptest.NewRequest(http.MethodPut, "/admin/scheduler/interval", strings.NewReader(`{"interval":"50s"}`))
+	if err := handler.HandleInterval(e.NewContext(req, httptest.NewRecorder())); err != nil {
+		t.Fatalf("expected no error without a content type, got %v", err)
+	}
+	if scheduler.state.Interval != 50 {
+		t.Errorf("expected an interval of 50 seconds, got %v", scheduler.state.Interval)
+	}
+
+	for _, body := range []string{`{"interval":"soon"}`, `{"interval":"1s"}`, `interval=30s`} {
+		req := httptest.NewRequest(http.MethodPut, "/admin/scheduler/interval", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		err := handler.HandleInterval(e.NewContext(req, httptest.NewRecorder()))
+		var httpErr *echo.HTTPError
+		if !errors.As(err, &httpErr) || httpErr.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for %s, got %v", body, err)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	if err := handler.HandleResume(e.NewContext(httptest.NewRequest(http.MethodPost, "/admin/scheduler/resume", nil), rec)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if scheduler.state.Paused {
+		t.Error("expected the scheduler to be resumed")
+	}
+}
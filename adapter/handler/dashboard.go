@@ -0,0 +1,26 @@
+package handler
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+//go:embed assets/dashboard.html
+var dashboardHTML []byte
+
+// DashboardHandler serves the built-in HTML dashboard
+type DashboardHandler struct{}
+
+// NewDashboardHandler creates a new DashboardHandler
+func NewDashboardHandler() *DashboardHandler {
+	return &DashboardHandler{}
+}
+
+// Handle serves the dashboard page.
+// The page is self-contained and reads its data from the exporter's JSON API.
+func (h *DashboardHandler) Handle(c echo.Context) error {
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-cache")
+	return c.HTMLBlob(http.StatusOK, dashboardHTML)
+}
@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestDashboardHandler_Handle(t *testing.T) {
+	e := echo.New()
+	handler := NewDashboardHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if err := handler.Handle(c); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("expected Content-Type text/html, got %s", ct)
+	}
+
+	body := rec.Body.String()
+	if !strings.Contains(body, "/api/v1/history") || !strings.Contains(body, "/api/v1/stream") {
+		t.Error("expected dashboard to use the JSON API")
+	}
+	// The dashboard must work offline
+	for _, external := range []string{"http://", "https://", "//cdn"} {
+		if strings.Contains(body, external) {
+			t.Errorf("expected no external assets, found %q", external)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+)
+
+// deviceLister lists the known devices
+type deviceLister interface {
+	List() []entity.DeviceInfo
+}
+
+// DevicesHandler handles the /api/v1/devices endpoint
+type DevicesHandler struct {
+	inventory deviceLister
+}
+
+// NewDevicesHandler creates a new DevicesHandler with the given device inventory
+func NewDevicesHandler(inventory deviceLister) *DevicesHandler {
+	return &DevicesHandler{
+		inventory: inventory,
+	}
+}
+
+// devicesResponse is the response of GET /api/v1/devices
+type devicesResponse struct {
+	SchemaVersion string              `json:"schema_version"`
+	Devices       []entity.DeviceInfo `json:"devices"`
+}
+
+// Handle returns every device seen since startup with its metadata and fetch statistics
+func (h *DevicesHandler) Handle(c echo.Context) error {
+	return c.JSON(http.StatusOK, devicesResponse{
+		SchemaVersion: readingsSchemaVersion,
+		Devices:       h.inventory.List(),
+	})
+}
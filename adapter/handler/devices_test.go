@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+)
+
+type mockDeviceLister struct {
+	devices []entity.DeviceInfo
+}
+
+func (m *mockDeviceLister) List() []entity.DeviceInfo {
+	return m.devices
+}
+
+func TestDevicesHandler_Handle(t *testing.T) {
+	base := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	e := echo.New()
+	handler := NewDevicesHandler(&mockDeviceLister{devices: []entity.DeviceInfo{{
+		Device:        "airq-1",
+		Nickname:      "Office",
+		Name:          "raw",
+		DataType:      "string",
+		SleepInterval: 60,
+		FirstSeen:     base,
+		LastSeen:      base.Add(time.Hour),
+		Fetches:       60,
+		Readings:      58,
+		Duplicates:    2,
+	}}})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/devices", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if err := handler.Handle(c); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	var resp struct {
+		SchemaVersion string           `json:"schema_version"`
+		Devices       []map[string]any `json:"devices"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("expected valid JSON, got %v", err)
+	}
+	if len(resp.Devices) != 1 {
+		t.Fatalf("expected 1 device, got %d", len(resp.Devices))
+	}
+	d := resp.Devices[0]
+	if d["device"] != "airq-1" || d["sleep_interval_seconds"] != 60.0 || d["duplicates"] != 2.0 {
+		t.Errorf("unexpected device: %v", d)
+	}
+	if _, ok := d["last_upload"]; ok {
+		t.Error("expected last_upload to be omitted when unknown")
+	}
+}
+
+func TestDevicesHandler_Handle_Empty(t *testing.T) {
+	e := echo.New()
+	handler := NewDevicesHandler(&mockDeviceLister{devices: []entity.DeviceInfo{}})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/devices", nil)
+	rec := httptest.NewRecorder()
+	if err := handler.Handle(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var resp struct {
+		Devices []entity.DeviceInfo `json:"devices"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("expected valid JSON, got %v", err)
+	}
+	if resp.Devices == nil || len(resp.Devices) != 0 {
+		t.Errorf("expected an empty device list, got %v", resp.Devices)
+	}
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/suzutan/m5stack_airq_exporter/usecase"
+)
+
+// defaultExportWindow is the range exported when no start time is requested
+const defaultExportWindow = 24 * time.Hour
+
+// ExportHandler handles the /api/v1/export endpoint
+type ExportHandler struct {
+	exportUsecase *usecase.ExportReadingsUsecase
+	now           func() time.Time
+}
+
+// NewExportHandler creates a new ExportHandler with the given usecase
+func NewExportHandler(exportUsecase *usecase.ExportReadingsUsecase) *ExportHandler {
+	return &ExportHandler{
+		exportUsecase: exportUsecase,
+		now:           time.Now,
+	}
+}
+
+// Handle streams recorded readings as CSV or JSON Lines.
+// Query parameters: format (csv|jsonl), from, to, device, columns (comma-separated)
+// and tz (IANA time zone used for timestamps and for from/to without an offset).
+func (h *ExportHandler) Handle(c echo.Context) error {
+	opts := usecase.ExportOptions{
+		Format:   usecase.ExportFormat(c.QueryParam("format")),
+		Devices:  parseDeviceFilter(c.QueryParams()["device"]),
+		Location: time.UTC,
+	}
+
+	if tz := c.QueryParam("tz"); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid tz")
+		}
+		opts.Location = loc
+	}
+
+	opts.To = h.now()
+	if value := c.QueryParam("to"); value != "" {
+		t, err := usecase.ParseExportTime(value, opts.Location)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid to")
+		}
+		opts.To = t
+	}
+	opts.From = opts.To.Add(-defaultExportWindow)
+	if value := c.QueryParam("from"); value != "" {
+		t, err := usecase.ParseExportTime(value, opts.Location)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid from")
+		}
+		opts.From = t
+	}
+
+	if value := c.QueryParam("columns"); value != "" {
+		for _, column := range strings.Split(value, ",") {
+			if column = strings.TrimSpace(column); column != "" {
+				opts.Columns = append(opts.Columns, column)
+			}
+		}
+	}
+
+	if err := opts.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if opts.Format == usecase.ExportFormatJSONL {
+		contentType = "application/x-ndjson"
+	}
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, contentType)
+	res.Header().Set(echo.HeaderContentDisposition,
+		fmt.Sprintf(`attachment; filename="airq-%s.%s"`, opts.From.In(opts.Location).Format("20060102"), opts.Format))
+	res.WriteHeader(http.StatusOK)
+
+	// The status line has been sent; errors can only abort the body
+	if err := h.exportUsecase.Execute(res, opts); err != nil {
+		slog.Error("Export failed", "error", err)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/suzutan/m5stack_airq_exporter/usecase"
+)
+
+func TestExportHandler_Handle(t *testing.T) {
+	e := echo.New()
+	base := time.Unix(1767573960, 0)
+	handler := NewExportHandler(usecase.NewExportReadingsUsecase(newTestHistory(base)))
+	handler.now = func() time.Time { return base }
+
+	tests := []struct {
+		query       string
+		contentType string
+		expected    string
+	}{
+		{
+			"?columns=device,co2",
+			"text/csv; charset=utf-8",
+			"device,co2\ndev-2,700\ndev-1,800\n",
+		},
+		{
+			"?format=jsonl&columns=time,co2&device=Office&tz=Asia/Tokyo",
+			"application/x-ndjson",
+			`{"time":"2026-01-05T08:46:00+09:00","co2":800}` + "\n",
+		},
+		{
+			"?columns=co2&from=2026-01-03&to=2026-01-05&tz=Asia/Tokyo",
+			"text/csv; charset=utf-8",
+			"co2\n600\n",
+		},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/export"+tt.query, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		if err := handler.Handle(c); err != nil {
+			t.Fatalf("%s: expected no error, got %v", tt.query, err)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != tt.contentType {
+			t.Errorf("%s: expected Content-Type %s, got %s", tt.query, tt.contentType, ct)
+		}
+		if rec.Body.String() != tt.expected {
+			t.Errorf("%s: unexpected body:\n%s\nexpected:\n%s", tt.query, rec.Body.String(), tt.expected)
+		}
+	}
+}
+
+func TestExportHandler_Handle_BadRequest(t *testing.T) {
+	e := echo.New()
+	handler := NewExportHandler(usecase.NewExportReadingsUsecase(newTestHistory(time.Now())))
+
+	for _, query := range []string{"?format=xlsx", "?columns=radon", "?tz=Mars/Olympus", "?from=yesterday"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/export"+query, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		err := handler.Handle(c)
+
+		httpErr, ok := err.(*echo.HTTPError)
+		if !ok || httpErr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400 error, got %v", query, err)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/suzutan/m5stack_airq_exporter/domain/repository"
+)
+
+// defaultHistoryWindow is the window returned when no range is requested
+const defaultHistoryWindow = 24 * time.Hour
+
+// HistoryHandler handles the /api/v1/history endpoint
+type HistoryHandler struct {
+	history repository.HistoryRepository
+	now     func() time.Time
+}
+
+// NewHistoryHandler creates a new HistoryHandler with the given history repository
+func NewHistoryHandler(history repository.HistoryRepository) *HistoryHandler {
+	return &HistoryHandler{
+		history: history,
+		now:     time.Now,
+	}
+}
+
+// historyResponse is the JSON response of the history endpoint
+type historyResponse struct {
+	From     time.Time         `json:"from"`
+	To       time.Time         `json:"to"`
+	Readings []readingResponse `json:"readings"`
+}
+
+// Handle returns the recorded readings within the requested window, oldest first.
+// The window is taken from the window query parameter (a duration ending now)
+// and can be narrowed by the device parameter.
+func (h *HistoryHandler) Handle(c echo.Context) error {
+	window := defaultHistoryWindow
+	if value := c.QueryParam("window"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid window")
+		}
+		window = d
+	}
+
+	devices := parseDeviceFilter(c.QueryParams()["device"])
+	to := h.now()
+	from := to.Add(-window)
+
+	resp := historyResponse{
+		From:     from,
+		To:       to,
+		Readings: []readingResponse{},
+	}
+	for _, data := range h.history.Range(from, to) {
+		if data.MatchesDevice(devices) {
+			resp.Readings = append(resp.Readings, newReadingResponse(data))
+		}
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/suzutan/m5stack_airq_exporter/adapter/gateway"
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+)
+
+func newTestHistory(base time.Time) *gateway.MemoryHistoryGateway {
+	history := gateway.NewMemoryHistoryGateway(48 * time.Hour)
+	history.Publish(&entity.AirQuality{Device: "dev-1", Nickname: "Office", CO2: 600, UpdateTime: base.Add(-30 * time.Hour)})
+	history.Publish(&entity.AirQuality{Device: "dev-2", Nickname: "Lobby", CO2: 700, UpdateTime: base.Add(-2 * time.Hour)})
+	history.Publish(&entity.AirQuality{Device: "dev-1", Nickname: "Office", CO2: 800, UpdateTime: base.Add(-time.Hour)})
+	return history
+}
+
+func TestHistoryHandler_Handle(t *testing.T) {
+	e := echo.New()
+	base := time.Unix(1767573960, 0)
+	handler := NewHistoryHandler(newTestHistory(base))
+	handler.now = func() time.Time { return base }
+
+	tests := []struct {
+		query    string
+		expected []int
+	}{
+		{"", []int{700, 800}},
+		{"?window=48h", []int{600, 700, 800}},
+		{"?window=90m", []int{800}},
+		{"?device=Office", []int{800}},
+		{"?device=dev-2", []int{700}},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/history"+tt.query, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		if err := handler.Handle(c); err != nil {
+			t.Fatalf("%s: expected no error, got %v", tt.query, err)
+		}
+
+		var resp historyResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: failed to parse response: %v", tt.query, err)
+		}
+
+		if len(resp.Readings) != len(tt.expected) {
+			t.Errorf("%s: expected %d readings, got %d", tt.query, len(tt.expected), len(resp.Readings))
+			continue
+		}
+		for i, co2 := range tt.expected {
+			if resp.Readings[i].CO2 != co2 {
+				t.Errorf("%s: expected CO2 %d at index %d, got %d", tt.query, co2, i, resp.Readings[i].CO2)
+			}
+		}
+	}
+}
+
+func TestHistoryHandler_Handle_InvalidWindow(t *testing.T) {
+	e := echo.New()
+	handler := NewHistoryHandler(gateway.NewMemoryHistoryGateway(time.Hour))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/history?window=yesterday", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := handler.Handle(c)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	if !ok || httpErr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 error, got %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+	"github.com/suzutan/m5stack_airq_exporter/usecase"
+)
+
+// maintenanceCalendar holds the maintenance windows
+type maintenanceCalendar interface {
+	Add(window entity.MaintenanceWindow) (entity.MaintenanceWindow, error)
+	Remove(id string) error
+	List() []entity.MaintenanceWindow
+}
+
+// MaintenanceHandler handles the /admin/maintenance endpoints
+type MaintenanceHandler struct {
+	calendar maintenanceCalendar
+	now      func() time.Time
+}
+
+// NewMaintenanceHandler creates a new MaintenanceHandler with the given calendar
+func NewMaintenanceHandler(calendar maintenanceCalendar) *MaintenanceHandler {
+	return &MaintenanceHandler{
+		calendar: calendar,
+		now:      time.Now,
+	}
+}
+
+// maintenanceRequest is the body of POST /admin/maintenance. A window with a
+// schedule recurs for duration; otherwise it lasts from start, by default now,
+// to end or for duration.
+type maintenanceRequest struct {
+	Device   string    `json:"device"`
+	Reason   string    `json:"reason"`
+	Schedule string    `json:"schedule"`
+	Duration string    `json:"duration"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+}
+
+// maintenanceResponse is the response of GET /admin/maintenance
+type maintenanceResponse struct {
+	Windows []entity.MaintenanceWindow `json:"windows"`
+}
+
+// HandleList returns the current and upcoming maintenance windows
+func (h *MaintenanceHandler) HandleList(c echo.Context) error {
+	return c.JSON(http.StatusOK, maintenanceResponse{Windows: h.calendar.List()})
+}
+
+// HandleCreate adds a maintenance window and returns it with its ID. The window
+// is kept in memory only, which the response tells with its ephemeral field.
+func (h *MaintenanceHandler) HandleCreate(c echo.Context) error {
+	var req maintenanceRequest
+	if err := decodeJSONBody(c, &req); err != nil {
+		return err
+	}
+	var duration time.Duration
+	if req.Duration != "" {
+		var err error
+		if duration, err = time.ParseDuration(req.Duration); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid duration")
+		}
+	}
+
+	window := entity.MaintenanceWindow{
+		Device:    req.Device,
+		Reason:    req.Reason,
+		Schedule:  req.Schedule,
+		Start:     req.Start,
+		End:       req.End,
+		Ephemeral: true,
+	}
+	if window.Recurring() {
+		window.Duration = duration.Seconds()
+	} else {
+		if window.Start.IsZero() {
+			window.Start = h.now()
+		}
+		if duration > 0 {
+			if !window.End.IsZero() {
+				return echo.NewHTTPError(http.StatusBadRequest, "give either end or duration")
+			}
+			window.End = window.Start.Add(duration)
+		}
+	}
+
+	window, err := h.calendar.Add(window)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return c.JSON(http.StatusCreated, window)
+}
+
+// HandleDelete removes a maintenance window, ending it if it is in effect
+func (h *MaintenanceHandler) HandleDelete(c echo.Context) error {
+	if err := h.calendar.Remove(c.Param("id")); err != nil {
+		if errors.Is(err, usecase.ErrMaintenanceWindowNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		}
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+	"github.com/suzutan/m5stack_airq_exporter/usecase"
+)
+
+func TestMaintenanceHandler_Create(t *testing.T) {
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		body   string
+		form   bool
+		status int
+		check  func(t *testing.T, window entity.MaintenanceWindow)
+	}{
+		{
+			name:   "from now for a duration",
+			body:   `{"device":"Office","duration":"2h","reason":"cleaning"}`,
+			status: http.StatusCreated,
+			check: func(t *testing.T, window entity.MaintenanceWindow) {
+				if !window.Start.Equal(now) || !window.End.Equal(now.Add(2*time.Hour)) || window.Reason != "cleaning" {
+					t.Errorf("unexpected window: %+v", window)
+				}
+			},
+		},
+		{
+			name:   "start and end",
+			body:   `{"device":"Office","start":"2026-10-20T08:00:00Z","end":"2026-10-20T09:00:00Z"}`,
+			status: http.StatusCreated,
+			check: func(t *testing.T, window entity.MaintenanceWindow) {
+				if window.End.Sub(window.Start) != time.Hour {
+					t.Errorf("unexpected window: %+v", window)
+				}
+			},
+		},
+		{
+			name:   "recurring",
+			body:   `{"device":"Office","schedule":"0 9 * * wed","duration":"90m"}`,
+			status: http.StatusCreated,
+			check: func(t *testing.T, window entity.MaintenanceWindow) {
+				if window.Duration != 5400 || !window.Start.IsZero() {
+					t.Errorf("unexpected window: %+v", window)
+				}
+			},
+		},
+		{
+			name:   "without a content type",
+			body:   `{"device":"Office","duration":"30m"}`,
+			form:   true,
+			status: http.StatusCreated,
+			check: func(t *testing.T, window entity.MaintenanceWindow) {
+				if !window.End.Equal(now.Add(30 * time.Minute)) {
+					t.Errorf("unexpected window: %+v", window)
+				}
+			},
+		},
+		{name: "not JSON", body: `device=Office`, form: true, status: http.StatusBadRequest},
+		{name: "end and duration", body: `{"end":"2026-10-20T09:00:00Z","duration":"1h"}`, status: http.StatusBadRequest},
+		{name: "invalid duration", body: `{"duration":"soon"}`, status: http.StatusBadRequest},
+		{name: "no end", body: `{"device":"Office"}`, status: http.StatusBadRequest},
+		{name: "invalid schedule", body: `{"schedule":"every day","duration":"1h"}`, status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewMaintenanceHandler(usecase.NewMaintenanceCalendar(time.UTC))
+			handler.now = func() time.Time { return now }
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/admin/maintenance", strings.NewReader(tt.body))
+			if tt.form {
+				// curl -d sends a form content type
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+			} else {
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			}
+			rec := httptest.NewRecorder()
+
+			err := handler.HandleCreate(e.NewContext(req, rec))
+			status := rec.Code
+			if err != nil {
+				var httpErr *echo.HTTPError
+				if !errors.As(err, &httpErr) {
+					t.Fatalf("expected an HTTP error, got %v", err)
+				}
+				status = httpErr.Code
+			}
+			if status != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, status)
+			}
+			if tt.check == nil {
+				return
+			}
+			var window entity.MaintenanceWindow
+			if err := json.Unmarshal(rec.Body.Bytes(), &window); err != nil {
+				t.Fatalf("expected valid JSON, got %v", err)
+			}
+			if window.ID == "" {
+				t.Error("expected an ID")
+			}
+			if !window.Ephemeral {
+				t.Error("expected a window added at runtime to be ephemeral")
+			}
+			tt.check(t, window)
+		})
+	}
+}
+
+func TestMaintenanceHandler_ListAndDelete(t *testing.T) {
+	calendar := usecase.NewMaintenanceCalendar(time.UTC)
+	window, err := calendar.Add(entity.MaintenanceWindow{Device: "Office", Schedule: "0 9 * * wed", Duration: 3600})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	handler := NewMaintenanceHandler(calendar)
+	e := echo.New()
+
+	rec := httptest.NewRecorder()
+	if err := handler.HandleList(e.NewContext(httptest.NewRequest(http.MethodGet, "/admin/maintenance", nil), rec)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var response maintenanceResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("expected valid JSON, got %v", err)
+	}
+	if len(response.Windows) != 1 || response.Windows[0].ID != window.ID || response.Windows[0].Ephemeral {
+		t.Errorf("expected the window, got %+v", response.Windows)
+	}
+
+	for _, expected := range []int{http.StatusNoContent, http.StatusNotFound} {
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodDelete, "/admin/maintenance/"+window.ID, nil), rec)
+		c.SetParamNames("id")
+		c.SetParamValues(window.ID)
+		err := handler.HandleDelete(c)
+		status := rec.Code
+		var httpErr *echo.HTTPError
+		if errors.As(err, &httpErr) {
+			status = httpErr.Code
+		}
+		if status != expected {
+			t.Errorf("expected status %d, got %d", expected, status)
+		}
+	}
+}
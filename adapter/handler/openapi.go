@@ -0,0 +1,24 @@
+package handler
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+//go:embed assets/openapi.json
+var openAPIDocument []byte
+
+// OpenAPIHandler serves the OpenAPI document describing the JSON API
+type OpenAPIHandler struct{}
+
+// NewOpenAPIHandler creates a new OpenAPIHandler
+func NewOpenAPIHandler() *OpenAPIHandler {
+	return &OpenAPIHandler{}
+}
+
+// Handle serves the OpenAPI document
+func (h *OpenAPIHandler) Handle(c echo.Context) error {
+	return c.Blob(http.StatusOK, echo.MIMEApplicationJSON, openAPIDocument)
+}
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestOpenAPIHandler_Handle(t *testing.T) {
+	e := echo.New()
+	handler := NewOpenAPIHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if err := handler.Handle(c); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Info    struct {
+			Version string `json:"version"`
+		} `json:"info"`
+		Paths map[string]any `json:"paths"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("expected valid JSON, got %v", err)
+	}
+
+	if doc.Info.Version != readingsSchemaVersion {
+		t.Errorf("expected document version %s to match schema version %s", doc.Info.Version, readingsSchemaVersion)
+	}
+	for _, path := range []string{"/api/v1/readings", "/api/v1/readings/{device}", "/api/v1/history", "/api/v1/stream", "/api/v1/reports", "/api/v1/devices"} {
+		if _, ok := doc.Paths[path]; !ok {
+			t.Errorf("expected path %s to be documented", path)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+)
+
+// readingResponse is the JSON representation of an air quality reading
+type readingResponse struct {
+	Device   string `json:"device"`
+	Nickname string `json:"nickname"`
+
+	PM1_0       float64 `json:"pm1_0"`
+	PM2_5       float64 `json:"pm2_5"`
+	PM4_0       float64 `json:"pm4_0"`
+	PM10_0      float64 `json:"pm10_0"`
+	Humidity    float64 `json:"humidity"`
+	Temperature float64 `json:"temperature"`
+	VOC         int     `json:"voc"`
+	NOx         int     `json:"nox"`
+
+	CO2              int     `json:"co2"`
+	SCD40Humidity    float64 `json:"scd40_humidity"`
+	SCD40Temperature float64 `json:"scd40_temperature"`
+
+	CreateTime  time.Time `json:"create_time,omitzero"`
+	UpdateTime  time.Time `json:"update_time,omitzero"`
+	FetchedAt   time.Time `json:"fetched_at,omitzero"`
+	WarmingUp   bool      `json:"warming_up"`
+	Maintenance bool      `json:"maintenance"`
+}
+
+// newReadingResponse converts an AirQuality entity to its JSON representation
+func newReadingResponse(data *entity.AirQuality) readingResponse {
+	return readingResponse{
+		Device:           data.Device,
+		Nickname:         data.Nickname,
+		PM1_0:            data.PM1_0,
+		PM2_5:            data.PM2_5,
+		PM4_0:            data.PM4_0,
+		PM10_0:           data.PM10_0,
+		Humidity:         data.Humidity,
+		Temperature:      data.Temperature,
+		VOC:              data.VOC,
+		NOx:              data.NOx,
+		CO2:              data.CO2,
+		SCD40Humidity:    data.SCD40Humidity,
+		SCD40Temperature: data.SCD40Temperature,
+		CreateTime:       data.CreateTime,
+		UpdateTime:       data.UpdateTime,
+		FetchedAt:        data.FetchedAt,
+		WarmingUp:        data.WarmingUp,
+		Maintenance:      data.Maintenance,
+	}
+}
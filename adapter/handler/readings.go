@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+	"github.com/suzutan/m5stack_airq_exporter/domain/repository"
+)
+
+// readingsSchemaVersion is the version of the /api/v1/readings response schema.
+// It changes whenever a field is removed or changes meaning.
+const readingsSchemaVersion = "1.0"
+
+// ReadingsHandler handles the /api/v1/readings endpoints
+type ReadingsHandler struct {
+	history repository.HistoryRepository
+}
+
+// NewReadingsHandler creates a new ReadingsHandler with the given history repository
+func NewReadingsHandler(history repository.HistoryRepository) *ReadingsHandler {
+	return &ReadingsHandler{
+		history: history,
+	}
+}
+
+// measurementResponse is a single measurement with its unit and description
+type measurementResponse struct {
+	Value       float64 `json:"value"`
+	Unit        string  `json:"unit"`
+	Description string  `json:"description"`
+	Quantity    string  `json:"quantity"`
+	Sensor      string  `json:"sensor"`
+}
+
+// currentReadingResponse is the latest reading of a device with metadata
+type currentReadingResponse struct {
+	Device       string                         `json:"device"`
+	Nickname     string                         `json:"nickname"`
+	DeviceTime   time.Time                      `json:"device_time,omitzero"`
+	FetchedAt    time.Time                      `json:"fetched_at,omitzero"`
+	WarmingUp    bool                           `json:"warming_up"`
+	Maintenance  bool                           `json:"maintenance"`
+	Measurements map[string]measurementResponse `json:"measurements"`
+}
+
+// readingsResponse is the response of GET /api/v1/readings
+type readingsResponse struct {
+	SchemaVersion string                   `json:"schema_version"`
+	Readings      []currentReadingResponse `json:"readings"`
+}
+
+// deviceReadingResponse is the response of GET /api/v1/readings/{device}
+type deviceReadingResponse struct {
+	SchemaVersion string                 `json:"schema_version"`
+	Reading       currentReadingResponse `json:"reading"`
+}
+
+// newCurrentReadingResponse converts an AirQuality entity to a reading with metadata
+func newCurrentReadingResponse(data *entity.AirQuality) currentReadingResponse {
+	measurements := make(map[string]measurementResponse, len(entity.Fields))
+	for _, f := range entity.Fields {
+		if !data.Measures(f.Key) {
+			continue
+		}
+		measurements[f.Key] = measurementResponse{
+			Value:       f.Value(data),
+			Unit:        f.Unit,
+			Description: f.Description,
+			Quantity:    f.Quantity,
+			Sensor:      f.Sensor,
+		}
+	}
+	return currentReadingResponse{
+		Device:       data.Device,
+		Nickname:     data.Nickname,
+		DeviceTime:   data.UpdateTime,
+		FetchedAt:    data.FetchedAt,
+		WarmingUp:    data.WarmingUp,
+		Maintenance:  data.Maintenance,
+		Measurements: measurements,
+	}
+}
+
+// NewDeviceReadingResponse returns the GET /api/v1/readings/{device} response
+// body of a reading, for command line output matching the API
+func NewDeviceReadingResponse(data *entity.AirQuality) any {
+	return deviceReadingResponse{
+		SchemaVersion: readingsSchemaVersion,
+		Reading:       newCurrentReadingResponse(data),
+	}
+}
+
+// HandleList returns the latest reading of every known device
+func (h *ReadingsHandler) HandleList(c echo.Context) error {
+	resp := readingsResponse{
+		SchemaVersion: readingsSchemaVersion,
+		Readings:      []currentReadingResponse{},
+	}
+	for _, data := range h.history.Latest() {
+		resp.Readings = append(resp.Readings, newCurrentReadingResponse(data))
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// HandleDevice returns the latest reading of a single device, looked up by ID or nickname
+func (h *ReadingsHandler) HandleDevice(c echo.Context) error {
+	device := c.Param("device")
+	for _, data := range h.history.Latest() {
+		if data.MatchesDevice([]string{device}) {
+			return c.JSON(http.StatusOK, NewDeviceReadingResponse(data))
+		}
+	}
+	return echo.NewHTTPError(http.StatusNotFound, "device not found")
+}
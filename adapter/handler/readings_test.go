@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/suzutan/m5stack_airq_exporter/adapter/gateway"
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+)
+
+func TestReadingsHandler_HandleList(t *testing.T) {
+	e := echo.New()
+	base := time.Unix(1767573960, 0)
+	handler := NewReadingsHandler(newTestHistory(base))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/readings", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if err := handler.HandleList(c); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var resp readingsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+
+	if resp.SchemaVersion != readingsSchemaVersion {
+		t.Errorf("expected schema version %s, got %s", readingsSchemaVersion, resp.SchemaVersion)
+	}
+	if len(resp.Readings) != 2 {
+		t.Fatalf("expected 2 readings, got %d", len(resp.Readings))
+	}
+
+	office := resp.Readings[0]
+	if office.Device != "dev-1" || office.Nickname != "Office" {
+		t.Errorf("unexpected device: %s (%s)", office.Device, office.Nickname)
+	}
+	if !office.DeviceTime.Equal(base.Add(-time.Hour)) {
+		t.Errorf("expected device time %v, got %v", base.Add(-time.Hour), office.DeviceTime)
+	}
+
+	co2 := office.Measurements["co2"]
+	if co2.Value != 800 || co2.Unit != "ppm" || co2.Sensor != entity.SensorSCD40 || co2.Description == "" {
+		t.Errorf("unexpected co2 measurement: %+v", co2)
+	}
+	if h := office.Measurements["scd40_humidity"]; h.Quantity != "relative_humidity" || h.Sensor != entity.SensorSCD40 {
+		t.Errorf("unexpected scd40_humidity measurement: %+v", h)
+	}
+	if len(office.Measurements) != len(entity.Fields) {
+		t.Errorf("expected %d measurements, got %d", len(entity.Fields), len(office.Measurements))
+	}
+}
+
+func TestReadingsHandler_HandleDevice(t *testing.T) {
+	e := echo.New()
+	handler := NewReadingsHandler(newTestHistory(time.Unix(1767573960, 0)))
+
+	for _, device := range []string{"dev-2", "Lobby"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/readings/"+device, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("device")
+		c.SetParamValues(device)
+
+		if err := handler.HandleDevice(c); err != nil {
+			t.Fatalf("%s: expected no error, got %v", device, err)
+		}
+
+		var resp deviceReadingResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: failed to parse response: %v", device, err)
+		}
+		if resp.Reading.Device != "dev-2" || resp.Reading.Measurements["co2"].Value != 700 {
+			t.Errorf("%s: unexpected reading: %+v", device, resp.Reading)
+		}
+	}
+}
+
+func TestReadingsHandler_HandleDevice_NotFound(t *testing.T) {
+	e := echo.New()
+	handler := NewReadingsHandler(gateway.NewMemoryHistoryGateway(time.Hour))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/readings/unknown", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("device")
+	c.SetParamValues("unknown")
+
+	err := handler.HandleDevice(c)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	if !ok || httpErr.Code != http.StatusNotFound {
+		t.Errorf("expected 404 error, got %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+	"github.com/suzutan/m5stack_airq_exporter/usecase"
+)
+
+//go:embed assets/report.html
+var reportHTML string
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"num": func(v float64) string { return fmt.Sprintf("%.1f", v) },
+	"time": func(t time.Time, loc *time.Location) string {
+		return t.In(loc).Format("2006-01-02 15:04")
+	},
+}).Parse(reportHTML))
+
+// ReportHandler handles the /api/v1/reports endpoint
+type ReportHandler struct {
+	reportUsecase *usecase.ReportUsecase
+	now           func() time.Time
+}
+
+// NewReportHandler creates a new ReportHandler with the given usecase
+func NewReportHandler(reportUsecase *usecase.ReportUsecase) *ReportHandler {
+	return &ReportHandler{
+		reportUsecase: reportUsecase,
+		now:           time.Now,
+	}
+}
+
+// reportPage is the data of the HTML report template
+type reportPage struct {
+	*entity.Report
+	Location *time.Location
+}
+
+// Handle returns an exposure report as JSON or HTML.
+// Query parameters: period (daily|weekly), date (2006-01-02, any day of the
+// requested period; default the last completed period) and format (json|html).
+func (h *ReportHandler) Handle(c echo.Context) error {
+	period := entity.ReportPeriodDaily
+	if value := c.QueryParam("period"); value != "" {
+		p, err := usecase.ParseReportPeriod(value)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid period")
+		}
+		period = p
+	}
+
+	loc := h.reportUsecase.Location()
+	var report *entity.Report
+	if value := c.QueryParam("date"); value != "" {
+		date, err := time.ParseInLocation("2006-01-02", value, loc)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid date")
+		}
+		from, to := usecase.PeriodContaining(period, date, loc)
+		report = h.reportUsecase.GenerateRange(period, from, to)
+	} else {
+		report = h.reportUsecase.Generate(period, h.now())
+	}
+
+	switch c.QueryParam("format") {
+	case "", "json":
+		return c.JSON(http.StatusOK, report)
+	case "html":
+		c.Response().Header().Set(echo.HeaderContentType, echo.MIMETextHTMLCharsetUTF8)
+		c.Response().WriteHeader(http.StatusOK)
+		return reportTemplate.Execute(c.Response(), reportPage{Report: report, Location: loc})
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid format")
+	}
+}
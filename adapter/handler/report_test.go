@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+	"github.com/suzutan/m5stack_airq_exporter/usecase"
+)
+
+func TestReportHandler_Handle(t *testing.T) {
+	e := echo.New()
+	base := time.Unix(1767573960, 0)
+	handler := NewReportHandler(usecase.NewReportUsecase(newTestHistory(base), usecase.DefaultReportConfig()))
+	handler.now = func() time.Time { return base }
+
+	tests := []struct {
+		query   string
+		period  entity.ReportPeriod
+		from    string
+		devices int
+	}{
+		{"", entity.ReportPeriodDaily, "2026-01-04T00:00:00Z", 2},
+		{"?period=daily&date=2026-01-03", entity.ReportPeriodDaily, "2026-01-03T00:00:00Z", 1},
+		{"?period=weekly", entity.ReportPeriodWeekly, "2025-12-29T00:00:00Z", 2},
+		{"?period=weekly&date=2026-01-05&format=json", entity.ReportPeriodWeekly, "2026-01-05T00:00:00Z", 0},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/reports"+tt.query, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		if err := handler.Handle(c); err != nil {
+			t.Fatalf("%s: expected no error, got %v", tt.query, err)
+		}
+
+		var report entity.Report
+		if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+			t.Fatalf("%s: expected valid JSON, got %v", tt.query, err)
+		}
+		if report.Period != tt.period || report.From.Format(time.RFC3339) != tt.from {
+			t.Errorf("%s: expected %s report from %s, got %s from %s", tt.query, tt.period, tt.from, report.Period, report.From.Format(time.RFC3339))
+		}
+		if len(report.Devices) != tt.devices {
+			t.Errorf("%s: expected %d devices, got %d", tt.query, tt.devices, len(report.Devices))
+		}
+	}
+}
+
+func TestReportHandler_Handle_HTML(t *testing.T) {
+	e := echo.New()
+	base := time.Unix(1767573960, 0)
+	handler := NewReportHandler(usecase.NewReportUsecase(newTestHistory(base), usecase.DefaultReportConfig()))
+	handler.now = func() time.Time { return base }
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/reports?format=html", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if err := handler.Handle(c); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if ct := rec.Header().Get(echo.HeaderContentType); ct != echo.MIMETextHTMLCharsetUTF8 {
+		t.Errorf("expected %s, got %s", echo.MIMETextHTMLCharsetUTF8, ct)
+	}
+	body := rec.Body.String()
+	for _, expected := range []string{"daily exposure report", "Office", "Lobby", "WHO 24h guideline", "0.0 – 1000.0"} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("expected report to contain %q", expected)
+		}
+	}
+}
+
+func TestReportHandler_Handle_InvalidParams(t *testing.T) {
+	e := echo.New()
+	handler := NewReportHandler(usecase.NewReportUsecase(newTestHistory(time.Now()), usecase.DefaultReportConfig()))
+
+	for _, query := range []string{"?period=monthly", "?date=yesterday", "?format=pdf"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/reports"+query, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		err := handler.Handle(c)
+		he, ok := err.(*echo.HTTPError)
+		if !ok || he.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %v", query, err)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/suzutan/m5stack_airq_exporter/adapter/gateway"
+)
+
+// streamRetryInterval is the reconnection delay suggested to SSE clients
+const streamRetryInterval = 5 * time.Second
+
+// readingSubscriber is the subset of ReadingBroker used by the stream handler
+type readingSubscriber interface {
+	Subscribe(lastEventID uint64) ([]gateway.ReadingEvent, <-chan gateway.ReadingEvent, func())
+}
+
+// StreamHandler handles the /api/v1/stream Server-Sent Events endpoint
+type StreamHandler struct {
+	broker    readingSubscriber
+	heartbeat time.Duration
+}
+
+// NewStreamHandler creates a new StreamHandler that sends a heartbeat comment at the given interval,
+// or none if it is not positive
+func NewStreamHandler(broker readingSubscriber, heartbeat time.Duration) *StreamHandler {
+	return &StreamHandler{
+		broker:    broker,
+		heartbeat: heartbeat,
+	}
+}
+
+// Handle streams readings as they are fetched.
+// The optional device query parameter (repeatable or comma-separated) filters by device ID or nickname.
+// Clients resuming with a Last-Event-ID header receive the buffered readings they missed.
+func (h *StreamHandler) Handle(c echo.Context) error {
+	devices := parseDeviceFilter(c.QueryParams()["device"])
+
+	lastEventID, err := parseLastEventID(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid Last-Event-ID")
+	}
+
+	replay, events, unsubscribe := h.broker.Subscribe(lastEventID)
+	defer unsubscribe()
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderConnection, "keep-alive")
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+
+	if _, err := fmt.Fprintf(res, "retry: %d\n\n", streamRetryInterval.Milliseconds()); err != nil {
+		return nil
+	}
+	for _, event := range replay {
+		if err := writeReadingEvent(res, event, devices); err != nil {
+			return nil
+		}
+	}
+	res.Flush()
+
+	// A nil channel never fires, so a non-positive interval disables heartbeats
+	var heartbeat <-chan time.Time
+	if h.heartbeat > 0 {
+		ticker := time.NewTicker(h.heartbeat)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+
+	ctx := c.Request().Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if err := writeReadingEvent(res, event, devices); err != nil {
+				return nil
+			}
+		case <-heartbeat:
+			if _, err := fmt.Fprint(res, ": heartbeat\n\n"); err != nil {
+				return nil
+			}
+		}
+		res.Flush()
+	}
+}
+
+// writeReadingEvent writes a single SSE reading event unless it is filtered out
+func writeReadingEvent(res *echo.Response, event gateway.ReadingEvent, devices []string) error {
+	if !event.Reading.MatchesDevice(devices) {
+		return nil
+	}
+	payload, err := json.Marshal(newReadingResponse(event.Reading))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(res, "id: %d\nevent: reading\ndata: %s\n\n", event.ID, payload)
+	return err
+}
+
+// parseLastEventID reads the event ID to resume from, either from the Last-Event-ID
+// header sent by EventSource on reconnect or from the lastEventId query parameter
+func parseLastEventID(c echo.Context) (uint64, error) {
+	value := c.Request().Header.Get("Last-Event-ID")
+	if value == "" {
+		value = c.QueryParam("lastEventId")
+	}
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
+
+// parseDeviceFilter flattens repeated and comma-separated device query parameters
+func parseDeviceFilter(values []string) []string {
+	var devices []string
+	for _, value := range values {
+		for _, d := range strings.Split(value, ",") {
+			if d = strings.TrimSpace(d); d != "" {
+				devices = append(devices, d)
+			}
+		}
+	}
+	return devices
+}
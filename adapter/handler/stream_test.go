@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/suzutan/m5stack_airq_exporter/adapter/gateway"
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+)
+
+func TestStreamHandler_Handle_Replay(t *testing.T) {
+	e := echo.New()
+	broker := gateway.NewReadingBroker(10)
+	broker.Publish(&entity.AirQuality{Device: "dev-1", Nickname: "Office", CO2: 700})
+	broker.Publish(&entity.AirQuality{Device: "dev-2", Nickname: "Lobby", CO2: 800})
+	broker.Publish(&entity.AirQuality{Device: "dev-1", Nickname: "Office", CO2: 900})
+
+	handler := NewStreamHandler(broker, time.Hour)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/stream?device=Office", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// Close the broker once the handler is streaming so that it returns
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		broker.Close()
+	}()
+
+	if err := handler.Handle(c); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("expected Content-Type text/event-stream, got %s", ct)
+	}
+
+	body := rec.Body.String()
+	if strings.Contains(body, `"co2":700`) {
+		t.Errorf("expected already seen event to be skipped, got %s", body)
+	}
+	if strings.Contains(body, `"co2":800`) {
+		t.Errorf("expected other device to be filtered out, got %s", body)
+	}
+	if !strings.Contains(body, "id: 3\nevent: reading\ndata: {") || !strings.Contains(body, `"co2":900`) {
+		t.Errorf("expected event 3 to be replayed, got %s", body)
+	}
+}
+
+func TestStreamHandler_Handle_Live(t *testing.T) {
+	e := echo.New()
+	broker := gateway.NewReadingBroker(10)
+	handler := NewStreamHandler(broker, 10*time.Millisecond)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/stream", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		broker.Publish(&entity.AirQuality{Device: "dev-1", CO2: 725})
+		time.Sleep(50 * time.Millisecond)
+		broker.Close()
+	}()
+
+	if err := handler.Handle(c); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	body := rec.Body.String()
+	if !strings.Contains(body, `"co2":725`) {
+		t.Errorf("expected live event, got %s", body)
+	}
+	if !strings.Contains(body, ": heartbeat\n\n") {
+		t.Errorf("expected heartbeat comment, got %s", body)
+	}
+}
+
+func TestStreamHandler_Handle_HeartbeatDisabled(t *testing.T) {
+	e := echo.New()
+	broker := gateway.NewReadingBroker(10)
+	handler := NewStreamHandler(broker, 0)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/stream", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		broker.Publish(&entity.AirQuality{Device: "dev-1", CO2: 725})
+		broker.Close()
+	}()
+
+	if err := handler.Handle(c); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	body := rec.Body.String()
+	if !strings.Contains(body, `"co2":725`) {
+		t.Errorf("expected live event, got %s", body)
+	}
+	if strings.Contains(body, ": heartbeat") {
+		t.Errorf("expected no heartbeat comment, got %s", body)
+	}
+}
+
+func TestStreamHandler_Handle_InvalidLastEventID(t *testing.T) {
+	e := echo.New()
+	handler := NewStreamHandler(gateway.NewReadingBroker(10), time.Hour)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/stream", nil)
+	req.Header.Set("Last-Event-ID", "abc")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := handler.Handle(c)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	if !ok || httpErr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 error, got %v", err)
+	}
+}
@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+)
+
+// VersionHandler handles the /version endpoint
+type VersionHandler struct {
+	info entity.BuildInfo
+}
+
+// NewVersionHandler creates a new VersionHandler reporting the given build information
+func NewVersionHandler(info entity.BuildInfo) *VersionHandler {
+	return &VersionHandler{
+		info: info,
+	}
+}
+
+// Handle returns the version, revision, build date, configuration hash and start time
+func (h *VersionHandler) Handle(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.info)
+}
@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+)
+
+func TestVersionHandler_Handle(t *testing.T) {
+	e := echo.New()
+	handler := NewVersionHandler(entity.BuildInfo{
+		Version:    "1.2.3",
+		Revision:   "abc123",
+		BuildDate:  "2026-10-19T07:00:00Z",
+		GoVersion:  "go1.25.4",
+		ConfigHash: "00000000ff01",
+		StartTime:  time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC),
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/version", nil)
+	rec := httptest.NewRecorder()
+	if err := handler.Handle(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var resp map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("expected valid JSON, got %v", err)
+	}
+	if resp["version"] != "1.2.3" || resp["revision"] != "abc123" || resp["config_hash"] != "00000000ff01" {
+		t.Errorf("unexpected version response: %v", resp)
+	}
+	if resp["start_time"] != "2026-10-19T08:00:00Z" {
+		t.Errorf("expected start time 2026-10-19T08:00:00Z, got %s", resp["start_time"])
+	}
+}
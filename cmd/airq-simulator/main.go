@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/suzutan/m5stack_airq_exporter/infrastructure/simulator"
+)
+
+func main() {
+	config := simulator.DefaultConfig()
+
+	listen := flag.String("listen", ":8081", "address to listen on")
+	devices := flag.String("devices", "SIMULATED0001:AirQ", "comma-separated TOKEN:Nickname pairs")
+	start := flag.String("start", "", "simulated start time in RFC 3339 (default now)")
+	escape := flag.String("escape", string(config.Escape), "value encoding: double (like ezdata2), single or mixed")
+	flag.Float64Var(&config.Speed, "speed", config.Speed, "simulated seconds per real second")
+	flag.DurationVar(&config.UploadInterval, "upload-interval", config.UploadInterval, "interval between simulated device uploads")
+	flag.IntVar(&config.Occupants, "occupants", config.Occupants, "number of people in the room during office hours")
+	flag.Float64Var(&config.PMSpikeRate, "pm-spike-rate", config.PMSpikeRate, "expected particle spikes per hour")
+	flag.Float64Var(&config.ErrorRate, "error-rate", 0, "probability of an API error response (code 500)")
+	flag.Float64Var(&config.HTTPErrorRate, "http-error-rate", 0, "probability of an HTTP 503 response")
+	flag.DurationVar(&config.Latency, "latency", 0, "response latency")
+	flag.DurationVar(&config.LatencyJitter, "latency-jitter", 0, "random latency added or subtracted")
+	flag.Int64Var(&config.Seed, "seed", config.Seed, "random seed")
+	flag.Parse()
+
+	config.Devices = nil
+	for _, pair := range strings.Split(*devices, ",") {
+		token, nickname, _ := strings.Cut(strings.TrimSpace(pair), ":")
+		if token == "" {
+			continue
+		}
+		config.Devices = append(config.Devices, simulator.Device{Token: token, Nickname: nickname})
+	}
+	if len(config.Devices) == 0 {
+		log.Fatal("at least one device is required")
+	}
+
+	switch mode := simulator.EscapeMode(*escape); mode {
+	case simulator.EscapeDouble, simulator.EscapeSingle, simulator.EscapeMixed:
+		config.Escape = mode
+	default:
+		log.Fatalf("invalid -escape: %s", *escape)
+	}
+
+	if *start != "" {
+		t, err := time.Parse(time.RFC3339, *start)
+		if err != nil {
+			log.Fatalf("invalid -start: %v", err)
+		}
+		config.Start = t
+	}
+
+	server := &http.Server{
+		Addr:    *listen,
+		Handler: simulator.New(config),
+	}
+
+	go func() {
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+		<-sigCh
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}()
+
+	for _, d := range config.Devices {
+		fmt.Printf("AIRQ_DATA_URL=http://localhost%s/api/v2/%s/dataMacByKey/raw  (%s)\n", *listen, d.Token, d.Nickname)
+	}
+	log.Printf("Simulator listening on %s", *listen)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("Failed to start simulator: %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/suzutan/m5stack_airq_exporter/adapter/gateway"
+	"github.com/suzutan/m5stack_airq_exporter/infrastructure/di"
+	"github.com/suzutan/m5stack_airq_exporter/infrastructure/http"
+)
+
+// runCheckConfig implements the check-config subcommand, which validates the
+// environment and the files it points to without starting the exporter
+func runCheckConfig(args []string) error {
+	fs := flag.NewFlagSet("check-config", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	config, err := readConfig()
+	problems := unwrapJoined(err)
+	problems = append(problems, checkConfig(config)...)
+	if len(problems) > 0 {
+		for _, problem := range problems {
+			fmt.Printf("invalid: %v\n", problem)
+		}
+		return errors.New("invalid configuration")
+	}
+
+	source := gateway.RedactURL(config.AirQDataURL)
+	if config.AirQTokenFile != "" {
+		source = "token file " + config.AirQTokenFile
+	}
+	// A decoder file applies to every response of the single data source
+	decoder := "EzData"
+	if path := os.Getenv("AIRQ_DECODER_FILE"); path != "" {
+		decoder = path + " (every device of the data source)"
+	}
+	reports := "disabled"
+	if config.ReportWebhookURL != "" {
+		reports = "enabled"
+	}
+	fmt.Println("Configuration is valid")
+	fmt.Printf("  data source:    %s\n", source)
+	fmt.Printf("  decoder:        %s\n", decoder)
+	fmt.Printf("  port:           %s\n", config.Port)
+	fmt.Printf("  metric naming:  %s (namespace %s)\n", config.MetricNaming, config.MetricNamespace)
+	fmt.Printf("  reading store:  %s\n", valueOr(config.StorePath, "disabled"))
+	if config.StorePath == "" {
+		fmt.Printf("  state snapshot: %s\n", valueOr(config.StatePath, "disabled"))
+	}
+	fmt.Printf("  reports:        %s\n", reports)
+	fmt.Printf("  MQTT:           %s\n", valueOr(gateway.RedactURL(config.MQTT.URL), "disabled"))
+	fmt.Printf("  config hash:    %s\n", configHash())
+	return nil
+}
+
+// checkConfig validates what readConfig cannot check on its own: the data
+// source, the files the configuration points to and the URLs
+func checkConfig(config *di.Config) []error {
+	var problems []error
+	if _, err := dataURL(config); err != nil {
+		problems = append(problems, err)
+	} else if config.AirQTokenFile == "" {
+		if err := checkURL(config.AirQDataURL); err != nil {
+			problems = append(problems, fmt.Errorf("AIRQ_DATA_URL: %w", err))
+		}
+	}
+
+	if config.WebConfigFile != "" {
+		webConfig, err := http.LoadWebConfig(config.WebConfigFile)
+		if err == nil {
+			// Load the certificate and client CA like the server does
+			_, err = webConfig.TLSConfig()
+		}
+		if err != nil {
+			problems = append(problems, fmt.Errorf("AIRQ_WEB_CONFIG_FILE: %w", err))
+		}
+	}
+
+	if config.StorePath != "" {
+		if info, err := os.Stat(filepath.Dir(config.StorePath)); err != nil || !info.IsDir() {
+			problems = append(problems, fmt.Errorf("AIRQ_STORE_PATH: directory %s does not exist", filepath.Dir(config.StorePath)))
+		}
+	}
+	if config.StatePath != "" {
+		if info, err := os.Stat(filepath.Dir(config.StatePath)); err != nil || !info.IsDir() {
+			problems = append(problems, fmt.Errorf("AIRQ_STATE_PATH: directory %s does not exist", filepath.Dir(config.StatePath)))
+		}
+	}
+
+	if config.ReportWebhookURL != "" {
+		if err := checkURL(config.ReportWebhookURL); err != nil {
+			problems = append(problems, fmt.Errorf("AIRQ_REPORT_WEBHOOK_URL: %w", err))
+		}
+	}
+
+	if config.MQTT.URL != "" {
+		if _, err := gateway.NewPahoMQTTClient(config.MQTT); err != nil {
+			problems = append(problems, fmt.Errorf("AIRQ_MQTT_URL: %w", err))
+		}
+	}
+	return problems
+}
+
+// checkURL reports whether a URL is an absolute http or https URL
+func checkURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return errors.New("invalid URL")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("expected an http or https URL, got %s", gateway.RedactURL(rawURL))
+	}
+	return nil
+}
+
+// unwrapJoined returns the errors joined by errors.Join, or err itself
+func unwrapJoined(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
+
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/suzutan/m5stack_airq_exporter/adapter/gateway"
+	"github.com/suzutan/m5stack_airq_exporter/usecase"
+)
+
+// runExport implements the export subcommand, which writes readings from the
+// local store as CSV or JSON Lines without going through the HTTP API
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	storePath := fs.String("store", os.Getenv("AIRQ_STORE_PATH"), "path of the reading store (default $AIRQ_STORE_PATH)")
+	format := fs.String("format", "csv", "output format: csv or jsonl")
+	from := fs.String("from", "", "start of the range (RFC 3339, 2006-01-02T15:04 or 2006-01-02; default 24h before -to)")
+	to := fs.String("to", "", "end of the range (default now)")
+	devices := fs.String("device", "", "comma-separated device IDs or nicknames (default all)")
+	columns := fs.String("columns", "", "comma-separated columns (default all: "+strings.Join(usecase.ExportColumns(), ",")+")")
+	tz := fs.String("tz", "UTC", "IANA time zone for timestamps and for -from/-to without an offset")
+	output := fs.String("output", "-", "output file, - for stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *storePath == "" {
+		return errors.New("the reading store is not configured: set -store or AIRQ_STORE_PATH")
+	}
+
+	loc, err := time.LoadLocation(*tz)
+	if err != nil {
+		return fmt.Errorf("invalid -tz: %w", err)
+	}
+
+	opts := usecase.ExportOptions{
+		Format:   usecase.ExportFormat(*format),
+		Devices:  splitList(*devices),
+		Columns:  splitList(*columns),
+		Location: loc,
+		To:       time.Now(),
+	}
+	if *to != "" {
+		if opts.To, err = usecase.ParseExportTime(*to, loc); err != nil {
+			return fmt.Errorf("invalid -to: %w", err)
+		}
+	}
+	opts.From = opts.To.Add(-24 * time.Hour)
+	if *from != "" {
+		if opts.From, err = usecase.ParseExportTime(*from, loc); err != nil {
+			return fmt.Errorf("invalid -from: %w", err)
+		}
+	}
+
+	out := os.Stdout
+	if *output != "-" {
+		if out, err = os.Create(*output); err != nil {
+			return err
+		}
+		defer out.Close()
+	}
+	w := bufio.NewWriter(out)
+
+	store := gateway.NewFileHistoryGateway(*storePath)
+	if err := usecase.NewExportReadingsUsecase(store).Execute(w, opts); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// splitList splits a comma-separated list, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+	"github.com/suzutan/m5stack_airq_exporter/adapter/gateway"
+	"github.com/suzutan/m5stack_airq_exporter/adapter/handler"
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+	"github.com/suzutan/m5stack_airq_exporter/infrastructure/di"
+)
+
+// runFetch implements the fetch subcommand, which fetches the current reading
+// once with the exporter configuration and prints it
+func runFetch(args []string) error {
+	fs := flag.NewFlagSet("fetch", flag.ContinueOnError)
+	format := fs.String("format", "table", "output format: table, json or prometheus")
+	timeout := fs.Duration("timeout", 30*time.Second, "timeout of the request")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var write func(io.Writer, *di.Config, *entity.AirQuality) error
+	switch *format {
+	case "table":
+		write = writeReadingTable
+	case "json":
+		write = writeReadingJSON
+	case "prometheus":
+		write = writeReadingMetrics
+	default:
+		return fmt.Errorf("unknown format: %s", *format)
+	}
+
+	config := loadConfig()
+	url, err := dataURL(config)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	httpGateway := gateway.NewAirQHTTPGateway(url, http.DefaultClient)
+	if config.Decoder != nil {
+		httpGateway.WithDecoder(config.Decoder)
+	}
+	data, err := httpGateway.Fetch(ctx)
+	if err != nil {
+		return err
+	}
+	return write(os.Stdout, config, data)
+}
+
+// dataURL returns the EzData URL of the configuration, reading the token file
+// when one is configured
+func dataURL(config *di.Config) (string, error) {
+	if config.AirQTokenFile != "" {
+		return gateway.DataURLFromTokenFile(config.AirQTokenFile, config.AirQDataURLTemplate)
+	}
+	if config.AirQDataURL == "" {
+		return "", errors.New("AIRQ_DATA_URL or AIRQ_TOKEN_FILE environment variable is required")
+	}
+	return config.AirQDataURL, nil
+}
+
+// writeReadingTable writes a reading as an aligned table
+func writeReadingTable(w io.Writer, _ *di.Config, data *entity.AirQuality) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Device:\t%s\n", data.Device)
+	if data.Nickname != "" {
+		fmt.Fprintf(tw, "Nickname:\t%s\n", data.Nickname)
+	}
+	if !data.UpdateTime.IsZero() {
+		fmt.Fprintf(tw, "Updated:\t%s (%s ago)\n", data.UpdateTime.Format(time.RFC3339), data.FetchedAt.Sub(data.UpdateTime).Truncate(time.Second))
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "FIELD\tSENSOR\tVALUE\tUNIT\tDESCRIPTION")
+	for _, f := range entity.Fields {
+		value := "-"
+		if data.Measures(f.Key) {
+			value = strconv.FormatFloat(f.Value(data), 'f', -1, 64)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", f.Key, f.Sensor, value, f.Unit, f.Description)
+	}
+	return tw.Flush()
+}
+
+// writeReadingJSON writes a reading like GET /api/v1/readings/{device}
+func writeReadingJSON(w io.Writer, _ *di.Config, data *entity.AirQuality) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(handler.NewDeviceReadingResponse(data))
+}
+
+// writeReadingMetrics writes the gauges a reading sets in the Prometheus text
+// format, with the configured naming, labels and relabel rules
+func writeReadingMetrics(w io.Writer, config *di.Config, data *entity.AirQuality) error {
+	registry := prometheus.NewRegistry()
+	metrics := gateway.NewPrometheusMetricsGateway(prometheus.WrapRegistererWith(config.MetricLabels, registry), gateway.PrometheusMetricsOptions{
+		SuppressWarmingUp: config.SuppressWarmingUp,
+		Naming:            config.MetricNaming,
+		PreferredSensor:   config.PreferredSensor,
+		Namespace:         config.MetricNamespace,
+	})
+	metrics.Update(data)
+
+	var gatherer prometheus.Gatherer = registry
+	if len(config.MetricRelabelRules) > 0 {
+		gatherer = gateway.NewRelabelingGatherer(registry, config.MetricRelabelRules)
+	}
+	families, err := gatherer.Gather()
+	if err != nil {
+		return err
+	}
+	encoder := expfmt.NewEncoder(w, expfmt.NewFormat(expfmt.TypeTextPlain))
+	for _, family := range families {
+		if err := encoder.Encode(family); err != nil {
+			return err
+		}
+	}
+	return nil
+}
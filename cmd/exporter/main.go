@@ -2,73 +2,396 @@ package main
 
 import (
 	"context"
-	"log"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
 	"os"
 	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/suzutan/m5stack_airq_exporter/adapter/gateway"
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+	"github.com/suzutan/m5stack_airq_exporter/infrastructure/buildinfo"
 	"github.com/suzutan/m5stack_airq_exporter/infrastructure/di"
 	"github.com/suzutan/m5stack_airq_exporter/infrastructure/http"
+	"github.com/suzutan/m5stack_airq_exporter/infrastructure/lifecycle"
+	"github.com/suzutan/m5stack_airq_exporter/infrastructure/logging"
 	"github.com/suzutan/m5stack_airq_exporter/infrastructure/scheduler"
+	"github.com/suzutan/m5stack_airq_exporter/usecase"
 )
 
 func main() {
-	// Load configuration from environment variables
-	config := &di.Config{
-		AirQDataURL: getEnv("AIRQ_DATA_URL", ""),
-		Port:        getEnv("PORT", "8080"),
+	var command string
+	if len(os.Args) > 1 {
+		command = os.Args[1]
 	}
 
-	if config.AirQDataURL == "" {
-		log.Fatal("AIRQ_DATA_URL environment variable is required")
+	logger, err := logging.New(os.Stderr, getEnv("LOG_LEVEL", "info"), getEnv("LOG_FORMAT", logging.FormatText))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
+	slog.SetDefault(logger)
 
-	// Create dependency injection container
-	container := di.NewContainer(config)
+	switch command {
+	case "version", "--version", "-version":
+		fmt.Println(buildinfo.String())
+		return
+	case "help", "--help", "-help", "-h":
+		fmt.Print(usage)
+		return
+	case "export":
+		if err = runExport(os.Args[2:]); err != nil {
+			fatal("Export failed", "error", err)
+		}
+		return
+	case "fetch":
+		if err = runFetch(os.Args[2:]); err != nil {
+			fatal("Fetch failed", "error", err)
+		}
+		return
+	case "check-config":
+		if err = runCheckConfig(os.Args[2:]); err != nil {
+			fatal("Configuration check failed", "error", err)
+		}
+		return
+	case "probe":
+		if err = runProbe(os.Args[2:]); err != nil {
+			fatal("Probe failed", "error", err)
+		}
+		return
+	case "push":
+		if err = runPush(os.Args[2:]); err != nil {
+			fatal("Push failed", "error", err)
+		}
+		return
+	case "record":
+		err = runRecord(os.Args[2:])
+	case "replay":
+		err = runReplay(os.Args[2:])
+	case "", "serve":
+		err = serve(loadConfig())
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n\n%s", command, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fatal("Exporter failed", "error", err)
+	}
+}
 
-	// Create HTTP server
-	server := http.NewServer(container)
+// usage describes the subcommands
+const usage = `Usage: m5stack_airq_exporter [command] [flags]
 
-	// Create scheduler for periodic data fetch (1 minute interval)
-	sched := scheduler.NewScheduler(container.FetchAirQUsecase, 1*time.Minute)
+Commands:
+  serve         run the exporter (default)
+  fetch         fetch the current reading once and print it
+  check-config  validate the configuration without starting the exporter
+  probe <url>   diagnose an EzData URL step by step with timings
+  push          push the metrics to a Prometheus Pushgateway, once or periodically
+  export        write readings from the local store as CSV or JSON Lines
+  record        run the exporter and record every API response
+  replay        run the exporter against a recording
+  version       print the build information
 
-	// Create context that will be canceled on shutdown
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+The exporter is configured with environment variables, see the README.
+Run a command with -h for its flags.
+`
 
-	// Start scheduler in background
-	go sched.Start(ctx)
+// fatal logs an error and exits
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
 
-	// Handle graceful shutdown
-	go func() {
-		sigCh := make(chan os.Signal, 1)
-		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-		<-sigCh
-		log.Println("Shutting down...")
+// loadConfig loads the configuration from environment variables and exits on
+// invalid values
+func loadConfig() *di.Config {
+	config, err := readConfig()
+	if err != nil {
+		fatal("Invalid configuration", "error", err)
+	}
+	return config
+}
 
-		// Create shutdown context with timeout
-		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer shutdownCancel()
+// readConfig reads the configuration from environment variables, reporting
+// every invalid variable at once
+func readConfig() (*di.Config, error) {
+	env := &envReader{}
+	config := &di.Config{
+		AirQDataURL:         getEnv("AIRQ_DATA_URL", ""),
+		Port:                getEnv("PORT", "8080"),
+		WebConfigFile:       getEnv("AIRQ_WEB_CONFIG_FILE", ""),
+		AirQTokenFile:       getEnv("AIRQ_TOKEN_FILE", ""),
+		AirQDataURLTemplate: getEnv("AIRQ_DATA_URL_TEMPLATE", ""),
+	}
+	if path := os.Getenv("AIRQ_DECODER_FILE"); path != "" {
+		decoder, err := gateway.LoadJSONPathDecoder(path)
+		if err != nil {
+			env.invalid("AIRQ_DECODER_FILE", err)
+		} else {
+			config.Decoder = decoder
+		}
+	}
 
-		// Shutdown HTTP server
-		if err := server.Shutdown(shutdownCtx); err != nil {
-			log.Printf("HTTP server shutdown error: %v", err)
+	warmUp := usecase.DefaultWarmUpConfig()
+	warmUp.Duration = env.duration("AIRQ_WARMUP_DURATION", warmUp.Duration)
+	warmUp.MaxUpdateGap = env.duration("AIRQ_WARMUP_MAX_UPDATE_GAP", warmUp.MaxUpdateGap)
+	if value := os.Getenv("AIRQ_WARMUP_SIGNALS"); value != "" {
+		signals, err := usecase.ParseWarmUpSignals(value)
+		if err != nil {
+			env.invalid("AIRQ_WARMUP_SIGNALS", err)
+		}
+		warmUp.Signals = signals
+	}
+	config.WarmUp = warmUp
+	config.SuppressWarmingUp = env.bool("AIRQ_WARMUP_SUPPRESS", false)
+	if path := os.Getenv("AIRQ_MAINTENANCE_FILE"); path != "" {
+		windows, err := gateway.LoadMaintenanceWindows(path)
+		if err != nil {
+			env.invalid("AIRQ_MAINTENANCE_FILE", err)
+		}
+		config.MaintenanceWindows = windows
+	}
+	config.SuppressMaintenance = env.bool("AIRQ_MAINTENANCE_SUPPRESS", true)
+	naming, err := gateway.ParseMetricNaming(getEnv("AIRQ_METRIC_NAMING", string(gateway.MetricNamingLegacy)))
+	if err != nil {
+		env.invalid("AIRQ_METRIC_NAMING", err)
+	}
+	config.MetricNaming = naming
+	preferred, err := entity.ParseSensor(getEnv("AIRQ_PREFERRED_SENSOR", entity.SensorSEN55))
+	if err != nil {
+		env.invalid("AIRQ_PREFERRED_SENSOR", err)
+	}
+	config.PreferredSensor = preferred
+	namespace, err := gateway.ParseMetricNamespace(getEnv("AIRQ_METRIC_NAMESPACE", gateway.DefaultMetricNamespace))
+	if err != nil {
+		env.invalid("AIRQ_METRIC_NAMESPACE", err)
+	}
+	config.MetricNamespace = namespace
+	labels, err := gateway.ParseConstLabels(getEnv("AIRQ_METRIC_LABELS", ""))
+	if err != nil {
+		env.invalid("AIRQ_METRIC_LABELS", err)
+	}
+	config.MetricLabels = labels
+	if path := os.Getenv("AIRQ_METRIC_RELABEL_FILE"); path != "" {
+		rules, err := gateway.LoadRelabelRules(path)
+		if err != nil {
+			env.invalid("AIRQ_METRIC_RELABEL_FILE", err)
+		}
+		config.MetricRelabelRules = rules
+	}
+	config.StreamBufferSize = env.int("AIRQ_STREAM_BUFFER_SIZE", 100)
+	config.StreamHeartbeat = env.duration("AIRQ_STREAM_HEARTBEAT", 15*time.Second)
+	config.HistoryRetention = env.duration("AIRQ_HISTORY_RETENTION", 24*time.Hour)
+	config.RollingWindows = usecase.DefaultRollingWindows
+	if value := os.Getenv("AIRQ_ROLLING_WINDOWS"); value != "" {
+		windows, err := usecase.ParseRollingWindows(value)
+		if err != nil {
+			env.invalid("AIRQ_ROLLING_WINDOWS", err)
+		}
+		config.RollingWindows = windows
+	}
+	config.StorePath = getEnv("AIRQ_STORE_PATH", "")
+	config.StoreRetention = env.duration("AIRQ_STORE_RETENTION", 90*24*time.Hour)
+	config.StatePath = getEnv("AIRQ_STATE_PATH", "")
+	config.FetchInterval = env.duration("AIRQ_FETCH_INTERVAL", time.Minute)
+	if config.FetchInterval < scheduler.MinInterval {
+		env.invalid("AIRQ_FETCH_INTERVAL", fmt.Errorf("must be at least %s", scheduler.MinInterval))
+	}
+	config.ShutdownTimeout = env.duration("AIRQ_SHUTDOWN_TIMEOUT", 10*time.Second)
+	config.Report = loadReportConfig(env)
+	for _, item := range splitList(os.Getenv("AIRQ_MAINTENANCE_EXCLUDE")) {
+		switch item {
+		case "rolling":
+			config.ExcludeMaintenanceFromRolling = true
+		case "reports":
+			config.Report.ExcludeMaintenance = true
+		default:
+			env.invalid("AIRQ_MAINTENANCE_EXCLUDE", fmt.Errorf("unknown statistics %q, expected rolling or reports", item))
+		}
+	}
+	config.ReportWebhookURL = getEnv("AIRQ_REPORT_WEBHOOK_URL", "")
+	config.ReportPeriods = []entity.ReportPeriod{entity.ReportPeriodDaily, entity.ReportPeriodWeekly}
+	if value := os.Getenv("AIRQ_REPORT_PERIODS"); value != "" {
+		config.ReportPeriods = nil
+		for _, item := range splitList(value) {
+			period, err := usecase.ParseReportPeriod(item)
+			if err != nil {
+				env.invalid("AIRQ_REPORT_PERIODS", err)
+			}
+			config.ReportPeriods = append(config.ReportPeriods, period)
+		}
+	}
+	reportTime, err := scheduler.ParseTimeOfDay(getEnv("AIRQ_REPORT_TIME", "07:00"))
+	if err != nil {
+		env.invalid("AIRQ_REPORT_TIME", err)
+	}
+	config.ReportTime = reportTime
+	config.RecordPath = getEnv("AIRQ_RECORD_PATH", "")
+	config.MQTT = gateway.MQTTOptions{
+		URL:      getEnv("AIRQ_MQTT_URL", ""),
+		ClientID: getEnv("AIRQ_MQTT_CLIENT_ID", "m5stack_airq_exporter"),
+	}
+	if path := os.Getenv("AIRQ_MQTT_PASSWORD_FILE"); path != "" {
+		password, err := os.ReadFile(path)
+		if err != nil {
+			env.invalid("AIRQ_MQTT_PASSWORD_FILE", err)
 		}
+		config.MQTT.Password = strings.TrimSpace(string(password))
+	}
+	config.HomeAssistant = gateway.HomeAssistantOptions{
+		TopicPrefix:     getEnv("AIRQ_MQTT_TOPIC_PREFIX", "airq"),
+		DiscoveryPrefix: getEnv("AIRQ_MQTT_DISCOVERY_PREFIX", "homeassistant"),
+	}
+	return config, env.err()
+}
 
-		// Cancel main context to stop scheduler
-		cancel()
-	}()
+// configHash returns a short hash of the configuration: the environment
+// variables read by the exporter and the configuration files they point to
+func configHash() string {
+	var env []string
+	for _, kv := range os.Environ() {
+		key, _, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(key, "AIRQ_") || key == "PORT" || key == "LOG_LEVEL" || key == "LOG_FORMAT" {
+			env = append(env, kv)
+		}
+	}
+	sort.Strings(env)
 
-	// Start HTTP server
-	log.Printf("Starting server on :%s", config.Port)
-	if err := server.Start(":" + config.Port); err != nil {
-		if err.Error() != "http: Server closed" {
-			log.Fatalf("Failed to start server: %v", err)
+	h := sha256.New()
+	for _, kv := range env {
+		fmt.Fprintln(h, kv)
+	}
+	for _, key := range []string{"AIRQ_WEB_CONFIG_FILE", "AIRQ_METRIC_RELABEL_FILE", "AIRQ_DECODER_FILE", "AIRQ_MAINTENANCE_FILE"} {
+		if path := os.Getenv(key); path != "" {
+			if content, err := os.ReadFile(path); err == nil {
+				h.Write(content)
+			}
 		}
 	}
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
 
-	log.Println("Server stopped")
+// loadReportConfig loads the exposure report configuration from environment variables
+func loadReportConfig(env *envReader) usecase.ReportConfig {
+	report := usecase.DefaultReportConfig()
+	loc, err := time.LoadLocation(getEnv("AIRQ_REPORT_TZ", "UTC"))
+	if err != nil {
+		env.invalid("AIRQ_REPORT_TZ", err)
+	}
+	report.Location = loc
+	if value := os.Getenv("AIRQ_REPORT_CO2_THRESHOLDS"); value != "" {
+		thresholds, err := usecase.ParseCO2Thresholds(value)
+		if err != nil {
+			env.invalid("AIRQ_REPORT_CO2_THRESHOLDS", err)
+		}
+		report.CO2Thresholds = thresholds
+	}
+	return report
+}
+
+// serve runs the exporter until it receives SIGINT or SIGTERM, then stops the
+// components in reverse start order within the shutdown timeout
+func serve(config *di.Config) error {
+	if config.AirQDataURL == "" && config.AirQTokenFile == "" && config.ReplayPath == "" {
+		return errors.New("AIRQ_DATA_URL or AIRQ_TOKEN_FILE environment variable is required")
+	}
+
+	var webConfig *http.WebConfig
+	if config.WebConfigFile != "" {
+		var err error
+		if webConfig, err = http.LoadWebConfig(config.WebConfigFile); err != nil {
+			return err
+		}
+	}
+
+	// Create dependency injection container
+	config.ConfigHash = configHash()
+	container, err := di.NewContainer(config)
+	if err != nil {
+		return err
+	}
+	lc := container.Lifecycle
+
+	// Create HTTP server
+	server := http.NewServer(container, webConfig)
+	serverErr := make(chan error, 1)
+	lc.Append(lifecycle.Hook{
+		Name: "HTTP server",
+		OnStart: func(context.Context) error {
+			slog.Info("Starting server", "port", config.Port, "url", gateway.RedactURL(config.AirQDataURL), "tls", webConfig != nil && webConfig.TLSServerConfig != nil)
+			go func() {
+				if err := server.Start(":" + config.Port); err != nil && err.Error() != "http: Server closed" {
+					serverErr <- fmt.Errorf("failed to start server: %w", err)
+				}
+			}()
+			return nil
+		},
+		OnStop: server.Shutdown,
+	})
+	// Disconnect stream clients before the server drains
+	lc.Append(lifecycle.Hook{
+		Name:   "reading stream",
+		OnStop: func(context.Context) error { container.ReadingBroker.Close(); return nil },
+	})
+
+	if config.ReplayPath != "" {
+		// Play the recording back as fast as its timestamps allow
+		replayCtx, stopReplay := context.WithCancel(context.Background())
+		lc.Append(lifecycle.Hook{
+			Name:    "replay",
+			OnStart: func(context.Context) error { go replay(replayCtx, container.FetchAirQUsecase); return nil },
+			OnStop:  func(context.Context) error { stopReplay(); return nil },
+		})
+	} else {
+		// On shutdown, the fetch in progress gets half of the shutdown timeout to
+		// finish so that its reading is still stored
+		sched := container.Scheduler
+		lc.Append(lifecycle.Hook{
+			Name:    "fetch scheduler",
+			OnStart: func(context.Context) error { go sched.Start(context.Background()); return nil },
+			OnStop: func(ctx context.Context) error {
+				ctx, cancel := context.WithTimeout(ctx, config.ShutdownTimeout/2)
+				defer cancel()
+				return sched.Stop(ctx)
+			},
+		})
+	}
+
+	if config.ReportWebhookURL != "" {
+		reportCtx, stopReports := context.WithCancel(context.Background())
+		reportScheduler := scheduler.NewReportScheduler(container.ReportUsecase, config.ReportPeriods, config.ReportTime)
+		lc.Append(lifecycle.Hook{
+			Name:    "report scheduler",
+			OnStart: func(context.Context) error { go reportScheduler.Start(reportCtx); return nil },
+			OnStop:  func(context.Context) error { stopReports(); return nil },
+		})
+	}
+
+	if err := lc.Start(context.Background()); err != nil {
+		return err
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case sig := <-sigCh:
+		slog.Info("Shutting down", "signal", sig.String(), "timeout", config.ShutdownTimeout)
+	case err = <-serverErr:
+		slog.Error("Shutting down after a server error", "error", err)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+	lc.Stop(shutdownCtx).Log(slog.Default())
+	return err
 }
 
 func getEnv(key, defaultValue string) string {
@@ -77,3 +400,57 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// envReader reads typed environment variables and collects the invalid ones
+type envReader struct {
+	errs []error
+}
+
+// invalid records an invalid environment variable
+func (e *envReader) invalid(key string, err error) {
+	e.errs = append(e.errs, fmt.Errorf("%s: %w", key, err))
+}
+
+// err returns the invalid environment variables as one error, or nil
+func (e *envReader) err() error {
+	return errors.Join(e.errs...)
+}
+
+func (e *envReader) duration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		e.invalid(key, err)
+		return defaultValue
+	}
+	return d
+}
+
+func (e *envReader) int(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		e.invalid(key, err)
+		return defaultValue
+	}
+	return n
+}
+
+func (e *envReader) bool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		e.invalid(key, err)
+		return defaultValue
+	}
+	return b
+}
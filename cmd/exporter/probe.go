@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/suzutan/m5stack_airq_exporter/adapter/gateway"
+	"github.com/suzutan/m5stack_airq_exporter/domain/repository"
+)
+
+// runProbe implements the probe subcommand, which requests an EzData URL and
+// reports every step of the request and of decoding the payload with timings
+func runProbe(args []string) error {
+	fs := flag.NewFlagSet("probe", flag.ContinueOnError)
+	timeout := fs.Duration("timeout", 30*time.Second, "timeout of the request")
+	decoderFile := fs.String("decoder", os.Getenv("AIRQ_DECODER_FILE"), "JSONPath decoder config for non-EzData sources (default $AIRQ_DECODER_FILE)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: probe [flags] [url]\n\nThe url defaults to the configured data URL.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var url string
+	switch fs.NArg() {
+	case 0:
+		config := loadConfig()
+		var err error
+		if url, err = dataURL(config); err != nil {
+			return err
+		}
+	case 1:
+		url = fs.Arg(0)
+	default:
+		fs.Usage()
+		return fmt.Errorf("expected one URL, got %d arguments", fs.NArg())
+	}
+
+	var decoder gateway.PayloadDecoder = gateway.EzDataDecoder{}
+	if *decoderFile != "" {
+		var err error
+		if decoder, err = gateway.LoadJSONPathDecoder(*decoderFile); err != nil {
+			return err
+		}
+	}
+
+	p := &prober{out: os.Stdout, start: time.Now()}
+	fmt.Fprintf(p.out, "Probing %s\n\n", gateway.RedactURL(url))
+	err := p.run(url, decoder, *timeout)
+	if err != nil {
+		fmt.Fprintf(p.out, "\nFAILED after %s: %v (error class %s)\n", p.elapsed(), err, repository.ErrorClass(err))
+		return err
+	}
+	fmt.Fprintf(p.out, "\nOK in %s\n", p.elapsed())
+	return nil
+}
+
+// prober prints the steps of a probe with the time elapsed since its start
+type prober struct {
+	mu    sync.Mutex
+	out   io.Writer
+	start time.Time
+}
+
+func (p *prober) elapsed() time.Duration {
+	return time.Since(p.start).Round(time.Millisecond)
+}
+
+// step prints a completed step
+func (p *prober) step(name, format string, args ...any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintf(p.out, "%8s  %-22s %s\n", p.elapsed(), name, fmt.Sprintf(format, args...))
+}
+
+// run requests the URL on a new connection and decodes the response
+func (p *prober) run(url string, decoder gateway.PayloadDecoder, timeout time.Duration) error {
+	trace := &httptrace.ClientTrace{
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			if info.Err != nil {
+				p.step("DNS lookup", "failed: %v", info.Err)
+				return
+			}
+			addrs := make([]string, len(info.Addrs))
+			for i, addr := range info.Addrs {
+				addrs[i] = addr.String()
+			}
+			p.step("DNS lookup", "%s", strings.Join(addrs, ", "))
+		},
+		ConnectDone: func(network, addr string, err error) {
+			if err != nil {
+				p.step("connect", "%s failed: %v", addr, err)
+				return
+			}
+			p.step("connect", "%s %s", network, addr)
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			if err != nil {
+				p.step("TLS handshake", "failed: %v", err)
+				return
+			}
+			p.step("TLS handshake", "%s, server name %s", tls.VersionName(state.Version), state.ServerName)
+		},
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			if info.Err == nil {
+				p.step("send request", "GET")
+			}
+		},
+		GotFirstResponseByte: func() {
+			p.step("first response byte", "")
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	// A new connection for every probe, so that DNS, connect and TLS are measured
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DisableKeepAlives = true
+	client := &http.Client{Transport: transport}
+
+	body, err := gateway.NewAirQHTTPGateway(url, client).FetchRaw(httptrace.WithClientTrace(ctx, trace))
+	if err != nil {
+		return err
+	}
+	p.step("read body", "%d bytes", len(body))
+
+	data, err := decoder.Decode(body, func(step gateway.DecodeStep) {
+		p.step(step.Name, "%s", step.Detail)
+	})
+	if err != nil {
+		return err
+	}
+	data.FetchedAt = time.Now()
+	if !data.UpdateTime.IsZero() {
+		p.step("reading age", "%s, updated %s", data.FetchedAt.Sub(data.UpdateTime).Round(time.Second), data.UpdateTime.Format(time.RFC3339))
+	}
+
+	fmt.Fprintln(p.out)
+	return writeReadingTable(p.out, nil, data)
+}
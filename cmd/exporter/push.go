@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/suzutan/m5stack_airq_exporter/adapter/gateway"
+	"github.com/suzutan/m5stack_airq_exporter/domain/repository"
+	"github.com/suzutan/m5stack_airq_exporter/infrastructure/di"
+	"github.com/suzutan/m5stack_airq_exporter/infrastructure/lifecycle"
+)
+
+// runPush implements the push subcommand, which fetches the current reading and
+// pushes the metrics to a Prometheus Pushgateway, once or periodically, for
+// hosts that cannot be scraped
+func runPush(args []string) error {
+	fs := flag.NewFlagSet("push", flag.ContinueOnError)
+	pushURL := fs.String("url", getEnv("AIRQ_PUSHGATEWAY_URL", ""), "Pushgateway URL; user info enables basic auth")
+	job := fs.String("job", getEnv("AIRQ_PUSHGATEWAY_JOB", "m5stack_airq_exporter"), "job label of the pushed groups")
+	method := fs.String("method", getEnv("AIRQ_PUSHGATEWAY_METHOD", "put"), "put replaces every series of a device's group, post only those pushed")
+	interval := fs.Duration("interval", 0, "push interval; 0 pushes once and exits")
+	deleteOnShutdown := fs.Bool("delete-on-shutdown", true, "delete the pushed groups on SIGINT or SIGTERM (with -interval only)")
+	timeout := fs.Duration("timeout", 30*time.Second, "timeout of each fetch and push")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *pushURL == "" {
+		return errors.New("-url or AIRQ_PUSHGATEWAY_URL is required")
+	}
+	if *method != "put" && *method != "post" {
+		return fmt.Errorf("unknown method: %s", *method)
+	}
+	if *interval < 0 {
+		return errors.New("-interval must not be negative")
+	}
+
+	options := gateway.PushgatewayOptions{URL: *pushURL, Job: *job, Replace: *method == "put"}
+	if path := os.Getenv("AIRQ_PUSHGATEWAY_PASSWORD_FILE"); path != "" {
+		password, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read Pushgateway password: %w", err)
+		}
+		options.Password = strings.TrimSpace(string(password))
+	}
+
+	config := loadConfig()
+	if config.AirQDataURL == "" && config.AirQTokenFile == "" {
+		return errors.New("AIRQ_DATA_URL or AIRQ_TOKEN_FILE environment variable is required")
+	}
+	config.ConfigHash = configHash()
+	container, err := di.NewContainer(config)
+	if err != nil {
+		return err
+	}
+	publisher, err := gateway.NewPushgatewayPublisher(container.Gatherer, options, &http.Client{Timeout: *timeout})
+	if err != nil {
+		return err
+	}
+
+	lc := container.Lifecycle
+	if *interval > 0 && *deleteOnShutdown {
+		lc.Append(lifecycle.Hook{
+			Name:   "Pushgateway groups",
+			OnStop: func(context.Context) error { return publisher.Delete() },
+		})
+	}
+	if err := lc.Start(context.Background()); err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+		defer cancel()
+		lc.Stop(ctx).Log(slog.Default())
+	}()
+
+	if *interval == 0 {
+		return fetchAndPush(context.Background(), container, publisher, *timeout)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	slog.Info("Pushing metrics", "url", gateway.RedactURL(*pushURL), "job", *job, "interval", *interval)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		if err := fetchAndPush(ctx, container, publisher, *timeout); err != nil {
+			slog.Error("Push failed", "error", err, "error_class", repository.ErrorClass(err))
+		}
+		select {
+		case <-ctx.Done():
+			slog.Info("Shutting down")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// fetchAndPush fetches the current reading and pushes the metrics. The metrics
+// are pushed even when the fetch fails, so that the error counters reach
+// Prometheus; the fetch error is returned afterwards.
+func fetchAndPush(ctx context.Context, container *di.Container, publisher *gateway.PushgatewayPublisher, timeout time.Duration) error {
+	fetchCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	result, fetchErr := container.FetchAirQUsecase.Execute(fetchCtx)
+	if fetchErr == nil {
+		slog.Debug("Fetched air quality data", "device", result.Reading.Device, "duplicate", result.Duplicate)
+	}
+
+	pushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+	if err := publisher.Push(pushCtx); err != nil {
+		return errors.Join(fetchErr, err)
+	}
+	return fetchErr
+}
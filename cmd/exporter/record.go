@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+
+	"github.com/suzutan/m5stack_airq_exporter/adapter/gateway"
+	"github.com/suzutan/m5stack_airq_exporter/domain/repository"
+	"github.com/suzutan/m5stack_airq_exporter/usecase"
+)
+
+// runRecord implements the record subcommand, which runs the exporter as usual
+// and also writes every raw API response to a recording file
+func runRecord(args []string) error {
+	fs := flag.NewFlagSet("record", flag.ContinueOnError)
+	output := fs.String("output", getEnv("AIRQ_RECORD_PATH", "airq-recording.jsonl"), "recording file, appended to if it exists")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	config := loadConfig()
+	config.RecordPath = *output
+	slog.Info("Recording API responses", "path", *output)
+	return serve(config)
+}
+
+// runReplay implements the replay subcommand, which runs the exporter against
+// a recording instead of the API, reproducing the recorded readings and errors
+func runReplay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	file := fs.String("file", getEnv("AIRQ_RECORD_PATH", "airq-recording.jsonl"), "recording file to play back")
+	speed := fs.Float64("speed", 1, "playback speed: 1 for real time, 60 for an hour per minute, 0 for as fast as possible")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	config := loadConfig()
+	config.ReplayPath = *file
+	config.ReplaySpeed = *speed
+	return serve(config)
+}
+
+// replay executes the fetch usecase for every recorded response and keeps the
+// server running afterwards so that the resulting state can be inspected
+func replay(ctx context.Context, fetchUsecase *usecase.FetchAirQUsecase) {
+	for n := 0; ; n++ {
+		result, err := fetchUsecase.Execute(ctx)
+		switch {
+		case errors.Is(err, gateway.ErrReplayFinished):
+			slog.Info("Replay finished", "responses", n)
+			return
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			slog.Error("Failed to fetch air quality data", "error", err, "error_class", repository.ErrorClass(err))
+		default:
+			slog.Debug("Replayed reading", "time", result.Reading.FetchedAt, "duplicate", result.Duplicate)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package entity
 
+import "time"
+
 // AirQuality represents air quality measurement data from M5Stack AirQ device
 type AirQuality struct {
 	// SEN55 sensor data (particle and environmental)
@@ -18,5 +20,53 @@ type AirQuality struct {
 	SCD40Temperature float64
 
 	// Device info
+	Device   string
 	Nickname string
+	// Name and DataType describe the EzData entry the reading was uploaded to
+	Name     string
+	DataType string
+	// SleepInterval is the configured time between device uploads, zero if unknown
+	SleepInterval time.Duration
+
+	// Timestamps reported by the data source and the time the reading was fetched
+	CreateTime time.Time
+	UpdateTime time.Time
+	FetchedAt  time.Time
+
+	// WarmingUp is true while the sensors are settling after a device restart
+	WarmingUp bool
+	// Maintenance is true for readings taken during a maintenance window of the device
+	Maintenance bool
+
+	// Measured holds the keys of the fields the data source provides; nil means
+	// all of them, as for AirQ devices
+	Measured map[string]bool
+}
+
+// Measures reports whether the reading provides the field with the given key
+func (a *AirQuality) Measures(key string) bool {
+	return a.Measured == nil || a.Measured[key]
+}
+
+// Timestamp returns the time the reading was taken: the device update time if known,
+// otherwise the time it was fetched
+func (a *AirQuality) Timestamp() time.Time {
+	if !a.UpdateTime.IsZero() {
+		return a.UpdateTime
+	}
+	return a.FetchedAt
+}
+
+// MatchesDevice reports whether the reading belongs to one of the given devices,
+// referenced by device ID or nickname. An empty list matches every device.
+func (a *AirQuality) MatchesDevice(devices []string) bool {
+	if len(devices) == 0 {
+		return true
+	}
+	for _, d := range devices {
+		if d == a.Device || (a.Nickname != "" && d == a.Nickname) {
+			return true
+		}
+	}
+	return false
 }
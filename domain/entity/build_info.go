@@ -0,0 +1,16 @@
+package entity
+
+import "time"
+
+// BuildInfo describes the running exporter: its release, the configuration it
+// was started with and when it started
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Revision  string `json:"revision"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+
+	// ConfigHash identifies the configuration, so that sites can be compared
+	ConfigHash string    `json:"config_hash,omitempty"`
+	StartTime  time.Time `json:"start_time,omitzero"`
+}
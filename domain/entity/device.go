@@ -0,0 +1,25 @@
+package entity
+
+import "time"
+
+// DeviceInfo describes a known device with its metadata and fetch statistics
+type DeviceInfo struct {
+	Device   string `json:"device"`
+	Nickname string `json:"nickname"`
+	Name     string `json:"name"`
+	DataType string `json:"data_type"`
+	// SleepInterval is the configured time between uploads in seconds, zero if unknown
+	SleepInterval float64 `json:"sleep_interval_seconds"`
+
+	// FirstSeen and LastSeen are the first and last fetch that returned the device
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	// LastUpload is the device time of the newest reading
+	LastUpload time.Time `json:"last_upload,omitzero"`
+
+	// Fetches counts the fetches that returned the device, Readings the new
+	// uploads among them and Duplicates the repeated ones
+	Fetches    int `json:"fetches"`
+	Readings   int `json:"readings"`
+	Duplicates int `json:"duplicates"`
+}
@@ -0,0 +1,121 @@
+package entity
+
+import (
+	"fmt"
+	"math"
+)
+
+// Sensor names of the AirQ device
+const (
+	SensorSEN55 = "sen55"
+	SensorSCD40 = "scd40"
+)
+
+// Field describes a single numeric measurement of AirQuality
+type Field struct {
+	// Key is the stable identifier used in APIs and metric names
+	Key string
+	// Unit is the unit of measurement, empty for dimensionless indices
+	Unit string
+	// Description is a human readable description
+	Description string
+	// Quantity is the physical quantity measured; fields of different sensors
+	// measuring the same quantity share it
+	Quantity string
+	// Sensor is the sensor that provides the measurement
+	Sensor string
+	// Value extracts the measurement from a reading
+	Value func(a *AirQuality) float64
+	// Set stores the measurement in a reading, rounding indices and CO2
+	Set func(a *AirQuality, v float64)
+}
+
+// Fields lists all measurements of AirQuality in display order
+var Fields = []Field{
+	{
+		Key: "pm1_0", Unit: "µg/m³", Description: "PM1.0 concentration",
+		Quantity: "pm1_0", Sensor: SensorSEN55,
+		Value: func(a *AirQuality) float64 { return a.PM1_0 },
+		Set:   func(a *AirQuality, v float64) { a.PM1_0 = v },
+	},
+	{
+		Key: "pm2_5", Unit: "µg/m³", Description: "PM2.5 concentration",
+		Quantity: "pm2_5", Sensor: SensorSEN55,
+		Value: func(a *AirQuality) float64 { return a.PM2_5 },
+		Set:   func(a *AirQuality, v float64) { a.PM2_5 = v },
+	},
+	{
+		Key: "pm4_0", Unit: "µg/m³", Description: "PM4.0 concentration",
+		Quantity: "pm4_0", Sensor: SensorSEN55,
+		Value: func(a *AirQuality) float64 { return a.PM4_0 },
+		Set:   func(a *AirQuality, v float64) { a.PM4_0 = v },
+	},
+	{
+		Key: "pm10_0", Unit: "µg/m³", Description: "PM10.0 concentration",
+		Quantity: "pm10_0", Sensor: SensorSEN55,
+		Value: func(a *AirQuality) float64 { return a.PM10_0 },
+		Set:   func(a *AirQuality, v float64) { a.PM10_0 = v },
+	},
+	{
+		Key: "humidity", Unit: "%", Description: "Relative humidity (SEN55)",
+		Quantity: "relative_humidity", Sensor: SensorSEN55,
+		Value: func(a *AirQuality) float64 { return a.Humidity },
+		Set:   func(a *AirQuality, v float64) { a.Humidity = v },
+	},
+	{
+		Key: "temperature", Unit: "°C", Description: "Temperature (SEN55)",
+		Quantity: "temperature", Sensor: SensorSEN55,
+		Value: func(a *AirQuality) float64 { return a.Temperature },
+		Set:   func(a *AirQuality, v float64) { a.Temperature = v },
+	},
+	{
+		Key: "voc", Unit: "", Description: "VOC index",
+		Quantity: "voc", Sensor: SensorSEN55,
+		Value: func(a *AirQuality) float64 { return float64(a.VOC) },
+		Set:   func(a *AirQuality, v float64) { a.VOC = int(math.Round(v)) },
+	},
+	{
+		Key: "nox", Unit: "", Description: "NOx index",
+		Quantity: "nox", Sensor: SensorSEN55,
+		Value: func(a *AirQuality) float64 { return float64(a.NOx) },
+		Set:   func(a *AirQuality, v float64) { a.NOx = int(math.Round(v)) },
+	},
+	{
+		Key: "co2", Unit: "ppm", Description: "CO2 concentration",
+		Quantity: "co2", Sensor: SensorSCD40,
+		Value: func(a *AirQuality) float64 { return float64(a.CO2) },
+		Set:   func(a *AirQuality, v float64) { a.CO2 = int(math.Round(v)) },
+	},
+	{
+		Key: "scd40_humidity", Unit: "%", Description: "Relative humidity (SCD40)",
+		Quantity: "relative_humidity", Sensor: SensorSCD40,
+		Value: func(a *AirQuality) float64 { return a.SCD40Humidity },
+		Set:   func(a *AirQuality, v float64) { a.SCD40Humidity = v },
+	},
+	{
+		Key: "scd40_temperature", Unit: "°C", Description: "Temperature (SCD40)",
+		Quantity: "temperature", Sensor: SensorSCD40,
+		Value: func(a *AirQuality) float64 { return a.SCD40Temperature },
+		Set:   func(a *AirQuality, v float64) { a.SCD40Temperature = v },
+	},
+}
+
+// ParseSensor parses a sensor name
+func ParseSensor(value string) (string, error) {
+	switch value {
+	case SensorSEN55, SensorSCD40:
+		return value, nil
+	default:
+		return "", fmt.Errorf("unknown sensor: %s", value)
+	}
+}
+
+// FieldByKey returns the field with the given key
+func FieldByKey(key string) (Field, bool) {
+	for _, f := range Fields {
+		if f.Key == key {
+			return f, true
+		}
+	}
+	return Field{}, false
+}
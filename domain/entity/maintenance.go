@@ -0,0 +1,31 @@
+package entity
+
+import "time"
+
+// MaintenanceWindow is a period during which a device is serviced, e.g. cleaned
+// or recalibrated, so that its readings are not representative. A recurring
+// window lasts Duration from every time matching Schedule; a one-off window
+// lasts from Start to End.
+type MaintenanceWindow struct {
+	ID string `json:"id"`
+	// Device is the device ID or nickname; empty applies to every device
+	Device string `json:"device,omitempty"`
+	Reason string `json:"reason,omitempty"`
+	// Schedule is a cron expression with minute, hour, day of month, month and
+	// day of week fields, evaluated in the time zone of reports
+	Schedule string `json:"schedule,omitempty"`
+	// Duration is the length of every recurring window in seconds
+	Duration float64   `json:"duration_seconds,omitempty"`
+	Start    time.Time `json:"start,omitzero"`
+	End      time.Time `json:"end,omitzero"`
+	// Active reports whether the window was in effect when it was listed
+	Active bool `json:"active"`
+	// Ephemeral reports whether the window was added at runtime, so that it is
+	// lost on restart unlike the windows of the maintenance file
+	Ephemeral bool `json:"ephemeral"`
+}
+
+// Recurring reports whether the window repeats on a schedule
+func (w *MaintenanceWindow) Recurring() bool {
+	return w.Schedule != ""
+}
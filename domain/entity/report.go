@@ -0,0 +1,69 @@
+package entity
+
+import "time"
+
+// ReportPeriod is the period an exposure report summarises
+type ReportPeriod string
+
+const (
+	// ReportPeriodDaily summarises one local calendar day
+	ReportPeriodDaily ReportPeriod = "daily"
+	// ReportPeriodWeekly summarises one local week starting on Monday
+	ReportPeriodWeekly ReportPeriod = "weekly"
+)
+
+// Report summarises the exposure of every device over a period.
+// It is published as is to the API and to webhooks, hence the JSON tags.
+type Report struct {
+	Period      ReportPeriod   `json:"period"`
+	From        time.Time      `json:"from"`
+	To          time.Time      `json:"to"`
+	GeneratedAt time.Time      `json:"generated_at"`
+	Devices     []DeviceReport `json:"devices"`
+}
+
+// DeviceReport summarises the exposure measured by a single device
+type DeviceReport struct {
+	Device   string `json:"device"`
+	Nickname string `json:"nickname"`
+	Readings int    `json:"readings"`
+	// CoveredHours is the time covered by readings; gaps in uploads are not counted
+	CoveredHours float64             `json:"covered_hours"`
+	CO2          []ThresholdExposure `json:"co2"`
+	PM25         PM25Summary         `json:"pm2_5"`
+	Peaks        []Peak              `json:"peaks"`
+	Comfort      []ComfortShare      `json:"comfort"`
+}
+
+// ThresholdExposure is the time spent above a threshold
+type ThresholdExposure struct {
+	Threshold float64 `json:"threshold"`
+	Hours     float64 `json:"hours"`
+}
+
+// PM25Summary compares PM2.5 against the WHO 24-hour guideline
+type PM25Summary struct {
+	Mean      float64 `json:"mean"`
+	Guideline float64 `json:"guideline"`
+	// DaysAboveGuideline counts the days of the period whose 24h mean exceeded the guideline
+	DaysAboveGuideline int `json:"days_above_guideline"`
+	Days               int `json:"days"`
+}
+
+// Peak is the highest value of a measurement and when it was reached
+type Peak struct {
+	Field string    `json:"field"`
+	Unit  string    `json:"unit"`
+	Value float64   `json:"value"`
+	Time  time.Time `json:"time"`
+}
+
+// ComfortShare is the share of covered time a measurement stayed within a comfort band.
+// The combined share of all bands has no field, min or max.
+type ComfortShare struct {
+	Name    string   `json:"name"`
+	Field   string   `json:"field,omitempty"`
+	Min     *float64 `json:"min,omitempty"`
+	Max     *float64 `json:"max,omitempty"`
+	Percent float64  `json:"percent"`
+}
@@ -0,0 +1,48 @@
+package entity
+
+import "time"
+
+// Scheduler run triggers
+const (
+	TriggerSchedule = "schedule"
+	TriggerRefresh  = "refresh"
+)
+
+// Scheduler run results
+const (
+	RunResultOK        = "ok"
+	RunResultDuplicate = "duplicate"
+	RunResultError     = "error"
+)
+
+// SchedulerState is a snapshot of the fetch scheduler
+type SchedulerState struct {
+	Paused bool `json:"paused"`
+	// Interval is the time between scheduled fetches in seconds
+	Interval float64 `json:"interval_seconds"`
+	// NextRun is the time of the next scheduled fetch, zero while paused
+	NextRun    time.Time        `json:"next_run,omitzero"`
+	InProgress *SchedulerRun    `json:"in_progress"`
+	LastRun    *SchedulerRun    `json:"last_run"`
+	Queue      []RefreshRequest `json:"queue"`
+}
+
+// SchedulerRun is a fetch run by the scheduler, on schedule or on request
+type SchedulerRun struct {
+	Trigger string `json:"trigger"`
+	// Device is the device of the reading, or the requested device of a refresh
+	// that did not return one
+	Device    string    `json:"device,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	// Duration is the fetch duration in seconds, zero while in progress
+	Duration   float64 `json:"duration_seconds,omitempty"`
+	Result     string  `json:"result,omitempty"`
+	Error      string  `json:"error,omitempty"`
+	ErrorClass string  `json:"error_class,omitempty"`
+}
+
+// RefreshRequest is a queued request to fetch a device immediately
+type RefreshRequest struct {
+	Device      string    `json:"device"`
+	RequestedAt time.Time `json:"requested_at"`
+}
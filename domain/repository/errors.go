@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+// Error classes group repository failures in logs independent of their message
+const (
+	ErrorClassTimeout    = "timeout"
+	ErrorClassCanceled   = "canceled"
+	ErrorClassNetwork    = "network"
+	ErrorClassHTTPStatus = "http_status"
+	ErrorClassAPI        = "api"
+	ErrorClassDecode     = "decode"
+	ErrorClassUnknown    = "unknown"
+)
+
+// ClassifiedError attaches an error class to an error without changing its message
+type ClassifiedError struct {
+	Class string
+	Err   error
+}
+
+// Error returns the message of the wrapped error
+func (e *ClassifiedError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error
+func (e *ClassifiedError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorClass returns the class of an error: timeouts, cancellation and network
+// errors are detected from the error chain, other classes must be attached by
+// the repository with ClassifiedError
+func ErrorClass(err error) string {
+	var netErr net.Error
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorClassTimeout
+	case errors.Is(err, context.Canceled):
+		return ErrorClassCanceled
+	}
+
+	var classified *ClassifiedError
+	if errors.As(err, &classified) {
+		return classified.Class
+	}
+	if errors.As(err, &netErr) {
+		return ErrorClassNetwork
+	}
+	return ErrorClassUnknown
+}
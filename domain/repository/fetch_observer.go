@@ -0,0 +1,12 @@
+package repository
+
+import "github.com/suzutan/m5stack_airq_exporter/domain/entity"
+
+// FetchObserver defines the interface for consumers of the outcome of every fetch
+type FetchObserver interface {
+	// FetchSucceeded reports a successful fetch, of a new or an already seen reading
+	FetchSucceeded(data *entity.AirQuality)
+
+	// FetchFailed reports a failed fetch
+	FetchFailed(err error)
+}
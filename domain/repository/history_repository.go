@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+)
+
+// HistoryRepository defines the interface for querying recorded air quality readings
+type HistoryRepository interface {
+	// Range returns the readings of all devices taken within [from, to], oldest first
+	Range(from, to time.Time) []*entity.AirQuality
+
+	// Each calls fn for every reading taken within [from, to], oldest first for
+	// every device, without holding them all in memory where the storage allows.
+	// It stops at and returns the first error of fn.
+	Each(from, to time.Time, fn func(data *entity.AirQuality) error) error
+
+	// Latest returns the most recent reading of every known device
+	Latest() []*entity.AirQuality
+}
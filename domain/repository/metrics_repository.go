@@ -6,4 +6,7 @@ import "github.com/suzutan/m5stack_airq_exporter/domain/entity"
 type MetricsRepository interface {
 	// Update updates the metrics with the given air quality data
 	Update(data *entity.AirQuality)
+
+	// RecordDuplicate counts a fetch that returned a reading the device had already uploaded
+	RecordDuplicate(data *entity.AirQuality)
 }
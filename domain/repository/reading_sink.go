@@ -0,0 +1,9 @@
+package repository
+
+import "github.com/suzutan/m5stack_airq_exporter/domain/entity"
+
+// ReadingSink defines the interface for consumers of new air quality readings
+type ReadingSink interface {
+	// Publish delivers a newly fetched air quality reading to the sink
+	Publish(data *entity.AirQuality)
+}
@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+)
+
+// ReportPublisher defines the interface for delivering exposure reports
+type ReportPublisher interface {
+	// Publish delivers the report
+	Publish(ctx context.Context, report *entity.Report) error
+}
@@ -0,0 +1,55 @@
+// Package buildinfo holds the version of the exporter, set at link time:
+//
+//	go build -ldflags "-X github.com/suzutan/m5stack_airq_exporter/infrastructure/buildinfo.Version=1.2.3 ..."
+package buildinfo
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+)
+
+// Set with -ldflags -X at build time
+var (
+	Version   = "dev"
+	Revision  = ""
+	BuildDate = ""
+)
+
+// Get returns the build information, falling back to the VCS details Go
+// records in the binary when they were not set at link time
+func Get() entity.BuildInfo {
+	info := entity.BuildInfo{
+		Version:   Version,
+		Revision:  Revision,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range bi.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Revision == "":
+				info.Revision = setting.Value
+			case setting.Key == "vcs.time" && info.BuildDate == "":
+				info.BuildDate = setting.Value
+			}
+		}
+	}
+	return info
+}
+
+// String formats the build information for --version
+func String() string {
+	info := Get()
+	s := fmt.Sprintf("m5stack_airq_exporter %s", info.Version)
+	if info.Revision != "" {
+		s += fmt.Sprintf(" (revision %s", info.Revision)
+		if info.BuildDate != "" {
+			s += fmt.Sprintf(", built %s", info.BuildDate)
+		}
+		s += ")"
+	}
+	return s + ", " + info.GoVersion
+}
@@ -1,13 +1,20 @@
 package di
 
 import (
+	"context"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/suzutan/m5stack_airq_exporter/adapter/gateway"
 	"github.com/suzutan/m5stack_airq_exporter/adapter/handler"
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
 	"github.com/suzutan/m5stack_airq_exporter/domain/repository"
+	"github.com/suzutan/m5stack_airq_exporter/infrastructure/buildinfo"
+	"github.com/suzutan/m5stack_airq_exporter/infrastructure/lifecycle"
+	"github.com/suzutan/m5stack_airq_exporter/infrastructure/scheduler"
 	"github.com/suzutan/m5stack_airq_exporter/usecase"
 )
 
@@ -15,6 +22,88 @@ import (
 type Config struct {
 	AirQDataURL string
 	Port        string
+
+	// WebConfigFile enables TLS and authentication of the HTTP server
+	WebConfigFile string
+
+	// ConfigHash identifies the configuration in the build info metrics and /version
+	ConfigHash string
+
+	// AirQTokenFile holds the EzData token; when set, AirQDataURL is built from
+	// AirQDataURLTemplate with {token} replaced by the file content
+	AirQTokenFile       string
+	AirQDataURLTemplate string
+
+	// Decoder converts the response bodies of the data URL into readings; nil
+	// decodes EzData responses
+	Decoder gateway.PayloadDecoder
+
+	// Sensor warm-up detection
+	WarmUp            usecase.WarmUpConfig
+	SuppressWarmingUp bool
+
+	// Maintenance windows loaded on startup; readings taken during a window
+	// are marked, their gauges hidden with SuppressMaintenance, and they are
+	// left out of rolling statistics with ExcludeMaintenanceFromRolling and
+	// of reports with Report.ExcludeMaintenance
+	MaintenanceWindows            []entity.MaintenanceWindow
+	SuppressMaintenance           bool
+	ExcludeMaintenanceFromRolling bool
+
+	// Metric names: legacy, conventional or both, and the sensor providing the
+	// sensor="best" series of temperature and humidity
+	MetricNaming    gateway.MetricNaming
+	PreferredSensor string
+
+	// Metric name prefix, labels added to every series and rules applied to the
+	// series at scrape time
+	MetricNamespace    string
+	MetricLabels       map[string]string
+	MetricRelabelRules []gateway.RelabelRule
+
+	// Live reading stream
+	StreamBufferSize int
+	StreamHeartbeat  time.Duration
+
+	// In-memory reading history
+	HistoryRetention time.Duration
+
+	// Rolling statistics windows (disabled when empty)
+	RollingWindows []time.Duration
+
+	// Persistent reading store (disabled when StorePath is empty)
+	StorePath      string
+	StoreRetention time.Duration
+
+	// StatePath keeps the in-memory history across restarts without a reading
+	// store: it is written on shutdown and read on startup
+	StatePath string
+
+	// FetchInterval is the initial time between scheduled fetches; the admin
+	// API can change it at runtime
+	FetchInterval time.Duration
+
+	// ShutdownTimeout bounds the graceful shutdown; half of it is given to the
+	// fetch in progress
+	ShutdownTimeout time.Duration
+
+	// Exposure reports, delivered to ReportWebhookURL at ReportTime when it is set
+	Report           usecase.ReportConfig
+	ReportWebhookURL string
+	ReportPeriods    []entity.ReportPeriod
+	ReportTime       time.Duration
+
+	// Record every raw API response to RecordPath, or replay a recording from
+	// ReplayPath instead of calling the API; a replay disables the store, the
+	// state snapshot, the report webhook and MQTT
+	RecordPath  string
+	ReplayPath  string
+	ReplaySpeed float64
+
+	// Home Assistant MQTT discovery and state publishing (disabled when MQTT.URL
+	// is empty)
+	MQTT          gateway.MQTTOptions
+	HomeAssistant gateway.HomeAssistantOptions
 }
 
 // Container holds all dependencies for the application
@@ -22,25 +111,66 @@ type Container struct {
 	// Config
 	Config *Config
 
+	// Lifecycle starts and stops the components; callers append their own
+	// hooks, such as the HTTP server and the schedulers, before starting it
+	Lifecycle *lifecycle.Lifecycle
+
 	// Repositories
 	AirQRepository    repository.AirQRepository
 	MetricsRepository repository.MetricsRepository
+	ReadingBroker     *gateway.ReadingBroker
+	HistoryRepository repository.HistoryRepository
+	StoreRepository   repository.HistoryRepository
 
 	// Usecases
-	FetchAirQUsecase *usecase.FetchAirQUsecase
+	FetchAirQUsecase      *usecase.FetchAirQUsecase
+	ExportReadingsUsecase *usecase.ExportReadingsUsecase
+	RollingStatsUsecase   *usecase.RollingStatsUsecase
+	ReportUsecase         *usecase.ReportUsecase
+	DeviceInventory       *usecase.DeviceInventory
+	MaintenanceCalendar   *usecase.MaintenanceCalendar
+
+	// Scheduler fetches every FetchInterval; nil when replaying a recording.
+	// Callers start and stop it.
+	Scheduler *scheduler.Scheduler
 
 	// Handlers
-	MetricsHandler *handler.MetricsHandler
-	HealthHandler  *handler.HealthHandler
+	MetricsHandler   *handler.MetricsHandler
+	HealthHandler    *handler.HealthHandler
+	StreamHandler    *handler.StreamHandler
+	HistoryHandler   *handler.HistoryHandler
+	DashboardHandler *handler.DashboardHandler
+	ReadingsHandler  *handler.ReadingsHandler
+	OpenAPIHandler   *handler.OpenAPIHandler
+	ExportHandler    *handler.ExportHandler
+	ReportHandler    *handler.ReportHandler
+	DevicesHandler   *handler.DevicesHandler
+	VersionHandler   *handler.VersionHandler
+	// AdminHandler controls Scheduler; nil without one
+	AdminHandler       *handler.AdminHandler
+	MaintenanceHandler *handler.MaintenanceHandler
 
-	// Prometheus
+	// Prometheus: Gatherer serves the series of Registry with the relabel rules applied
 	Registry *prometheus.Registry
+	Gatherer prometheus.Gatherer
 }
 
 // NewContainer creates a new dependency injection container
-func NewContainer(config *Config) *Container {
-	// Create Prometheus registry
+func NewContainer(config *Config) (*Container, error) {
+	if config.ReplayPath != "" {
+		isolateReplay(config)
+	}
+
+	// Create Prometheus registry; every series carries the constant labels
 	registry := prometheus.NewRegistry()
+	registerer := prometheus.WrapRegistererWith(config.MetricLabels, registry)
+	metricsOptions := gateway.PrometheusMetricsOptions{
+		SuppressWarmingUp:   config.SuppressWarmingUp,
+		SuppressMaintenance: config.SuppressMaintenance,
+		Naming:              config.MetricNaming,
+		PreferredSensor:     config.PreferredSensor,
+		Namespace:           config.MetricNamespace,
+	}
 
 	// Create HTTP client with timeout
 	httpClient := &http.Client{
@@ -48,23 +178,225 @@ func NewContainer(config *Config) *Container {
 	}
 
 	// Create repositories
-	airqRepo := gateway.NewAirQHTTPGateway(config.AirQDataURL, httpClient)
-	metricsRepo := gateway.NewPrometheusMetricsGateway(registry)
+	airqRepo, err := newAirQRepository(config, httpClient)
+	if err != nil {
+		return nil, err
+	}
+	metricsRepo := gateway.NewPrometheusMetricsGateway(registerer, metricsOptions)
+	buildInfo := buildinfo.Get()
+	buildInfo.ConfigHash = config.ConfigHash
+	buildInfo.StartTime = time.Now()
+	gateway.RegisterBuildInfoMetrics(registerer, metricsOptions, buildInfo)
+	readingBroker := gateway.NewReadingBroker(config.StreamBufferSize)
+	historyRepo := gateway.NewMemoryHistoryGateway(historyRetention(config))
+
+	lc := lifecycle.New()
+
+	// Without a persistent store, exports are served from the in-memory history,
+	// which a state snapshot can carry across restarts
+	var storeRepo repository.HistoryRepository = historyRepo
+	var fileStore *gateway.FileHistoryGateway
+	if config.StorePath != "" {
+		fileStore = gateway.NewFileHistoryGateway(config.StorePath).WithRetention(config.StoreRetention)
+		storeRepo = fileStore
+		loadStore(fileStore, historyRepo, config)
+	} else if config.StatePath != "" {
+		snapshot := gateway.NewFileHistoryGateway(config.StatePath)
+		now := time.Now()
+		for _, data := range snapshot.Range(now.Add(-historyRetention(config)), now) {
+			historyRepo.Publish(data)
+		}
+		lc.Append(lifecycle.Hook{
+			Name: "state snapshot",
+			OnStop: func(context.Context) error {
+				return snapshot.Replace(historyRepo.Range(time.Time{}, time.Now()))
+			},
+		})
+	}
 
 	// Create usecases
-	fetchAirQUsecase := usecase.NewFetchAirQUsecase(airqRepo, metricsRepo)
+	warmUpDetector := usecase.NewWarmUpDetector(config.WarmUp)
+	deviceInventory := usecase.NewDeviceInventory()
+	maintenanceCalendar := usecase.NewMaintenanceCalendar(config.Report.Location)
+	for _, window := range config.MaintenanceWindows {
+		if _, err := maintenanceCalendar.Add(window); err != nil {
+			return nil, fmt.Errorf("invalid maintenance window: %w", err)
+		}
+	}
+	fetchAirQUsecase := usecase.NewFetchAirQUsecase(airqRepo, metricsRepo).
+		WithWarmUpDetector(warmUpDetector).
+		WithInventory(deviceInventory).
+		WithMaintenance(maintenanceCalendar).
+		WithSink(historyRepo).
+		WithSink(readingBroker)
+	if fileStore != nil {
+		fetchAirQUsecase.WithSink(fileStore)
+		lc.Append(lifecycle.Hook{
+			Name:   "reading store",
+			OnStop: func(context.Context) error { return fileStore.Sync() },
+		})
+	}
+	if config.MQTT.URL != "" {
+		mqttOptions := config.MQTT
+		mqttOptions.StatusTopic = config.HomeAssistant.StatusTopic()
+		mqttClient, err := gateway.NewPahoMQTTClient(mqttOptions)
+		if err != nil {
+			return nil, err
+		}
+		homeAssistant := gateway.NewHomeAssistantGateway(mqttClient, config.HomeAssistant)
+		fetchAirQUsecase.WithSink(homeAssistant).WithObserver(homeAssistant)
+		lc.Append(lifecycle.Hook{
+			Name:    "MQTT",
+			OnStart: func(context.Context) error { mqttClient.Connect(); return nil },
+			OnStop:  func(context.Context) error { mqttClient.Close(time.Second); return nil },
+		})
+	}
+	exportReadingsUsecase := usecase.NewExportReadingsUsecase(storeRepo)
+	rollingStatsUsecase := usecase.NewRollingStatsUsecase(historyRepo, config.RollingWindows)
+	if config.ExcludeMaintenanceFromRolling {
+		rollingStatsUsecase.WithoutMaintenance()
+	}
+	if len(config.RollingWindows) > 0 {
+		registerer.MustRegister(gateway.NewRollingStatsCollector(rollingStatsUsecase, metricsOptions))
+	}
+	reportUsecase := usecase.NewReportUsecase(storeRepo, config.Report)
+	if config.ReportWebhookURL != "" {
+		reportUsecase.WithPublisher(gateway.NewWebhookReportGateway(config.ReportWebhookURL, httpClient))
+	}
+
+	var fetchScheduler *scheduler.Scheduler
+	if config.ReplayPath == "" {
+		fetchScheduler = scheduler.NewScheduler(fetchAirQUsecase, config.FetchInterval).
+			WithLogger(slog.With("url", gateway.RedactURL(config.AirQDataURL)))
+	}
 
 	// Create handlers
-	metricsHandler := handler.NewMetricsHandler(registry)
+	var gatherer prometheus.Gatherer = registry
+	if len(config.MetricRelabelRules) > 0 {
+		gatherer = gateway.NewRelabelingGatherer(registry, config.MetricRelabelRules)
+	}
+	metricsHandler := handler.NewMetricsHandler(gatherer)
 	healthHandler := handler.NewHealthHandler()
+	streamHandler := handler.NewStreamHandler(readingBroker, config.StreamHeartbeat)
+	historyHandler := handler.NewHistoryHandler(historyRepo)
+	dashboardHandler := handler.NewDashboardHandler()
+	readingsHandler := handler.NewReadingsHandler(historyRepo)
+	openAPIHandler := handler.NewOpenAPIHandler()
+	exportHandler := handler.NewExportHandler(exportReadingsUsecase)
+	reportHandler := handler.NewReportHandler(reportUsecase)
+	devicesHandler := handler.NewDevicesHandler(deviceInventory)
+	versionHandler := handler.NewVersionHandler(buildInfo)
+	maintenanceHandler := handler.NewMaintenanceHandler(maintenanceCalendar)
+	var adminHandler *handler.AdminHandler
+	if fetchScheduler != nil {
+		adminHandler = handler.NewAdminHandler(fetchScheduler, deviceInventory)
+	}
 
 	return &Container{
-		Config:            config,
-		AirQRepository:    airqRepo,
-		MetricsRepository: metricsRepo,
-		FetchAirQUsecase:  fetchAirQUsecase,
-		MetricsHandler:    metricsHandler,
-		HealthHandler:     healthHandler,
-		Registry:          registry,
+		Config:                config,
+		Lifecycle:             lc,
+		AirQRepository:        airqRepo,
+		MetricsRepository:     metricsRepo,
+		ReadingBroker:         readingBroker,
+		HistoryRepository:     historyRepo,
+		StoreRepository:       storeRepo,
+		FetchAirQUsecase:      fetchAirQUsecase,
+		ExportReadingsUsecase: exportReadingsUsecase,
+		RollingStatsUsecase:   rollingStatsUsecase,
+		ReportUsecase:         reportUsecase,
+		DeviceInventory:       deviceInventory,
+		MaintenanceCalendar:   maintenanceCalendar,
+		Scheduler:             fetchScheduler,
+		MetricsHandler:        metricsHandler,
+		HealthHandler:         healthHandler,
+		StreamHandler:         streamHandler,
+		HistoryHandler:        historyHandler,
+		DashboardHandler:      dashboardHandler,
+		ReadingsHandler:       readingsHandler,
+		OpenAPIHandler:        openAPIHandler,
+		ExportHandler:         exportHandler,
+		ReportHandler:         reportHandler,
+		DevicesHandler:        devicesHandler,
+		VersionHandler:        versionHandler,
+		AdminHandler:          adminHandler,
+		MaintenanceHandler:    maintenanceHandler,
+		Registry:              registry,
+		Gatherer:              gatherer,
+	}, nil
+}
+
+// isolateReplay keeps a replay from touching what the recorded site writes to:
+// its recording, store and state snapshot, its report webhook and its MQTT broker
+func isolateReplay(config *Config) {
+	config.RecordPath = ""
+	config.StorePath = ""
+	config.StatePath = ""
+	config.ReportWebhookURL = ""
+	config.MQTT = gateway.MQTTOptions{}
+}
+
+// newAirQRepository creates the AirQ repository for the configured mode:
+// replaying a recording, recording the API or just calling the API
+func newAirQRepository(config *Config, httpClient *http.Client) (repository.AirQRepository, error) {
+	decoder := config.Decoder
+	if decoder == nil {
+		decoder = gateway.EzDataDecoder{}
+	}
+
+	if config.ReplayPath != "" {
+		replay, err := gateway.NewReplayAirQGateway(config.ReplayPath, config.ReplaySpeed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load replay: %w", err)
+		}
+		return replay.WithDecoder(decoder), nil
+	}
+
+	if config.AirQTokenFile != "" {
+		dataURL, err := gateway.DataURLFromTokenFile(config.AirQTokenFile, config.AirQDataURLTemplate)
+		if err != nil {
+			return nil, err
+		}
+		config.AirQDataURL = dataURL
+	}
+
+	httpGateway := gateway.NewAirQHTTPGateway(config.AirQDataURL, httpClient).WithDecoder(decoder)
+	if config.RecordPath != "" {
+		return gateway.NewRecordingAirQGateway(httpGateway, config.RecordPath).WithDecoder(decoder), nil
+	}
+	return httpGateway, nil
+}
+
+// historyRetention returns how long readings are kept in memory: at least the
+// longest rolling statistics window and, when scheduled reports are built from
+// memory, the longest report period up to the time it is posted
+func historyRetention(config *Config) time.Duration {
+	retention := config.HistoryRetention
+	for _, w := range config.RollingWindows {
+		retention = max(retention, w)
+	}
+	if config.ReportWebhookURL != "" && config.StorePath == "" {
+		for _, period := range config.ReportPeriods {
+			// An extra hour covers days lengthened by daylight saving time
+			length := 24 * time.Hour
+			if period == entity.ReportPeriodWeekly {
+				length = 7 * 24 * time.Hour
+			}
+			retention = max(retention, length+config.ReportTime+time.Hour)
+		}
+	}
+	return retention
+}
+
+// loadStore drops expired readings from the persistent store and fills the
+// in-memory history with the recent ones, so that charts survive restarts
+func loadStore(store *gateway.FileHistoryGateway, history *gateway.MemoryHistoryGateway, config *Config) {
+	now := time.Now()
+	if config.StoreRetention > 0 {
+		if err := store.Compact(now.Add(-config.StoreRetention)); err != nil {
+			slog.Error("Failed to compact reading store", "path", config.StorePath, "error", err)
+		}
+	}
+	for _, data := range store.Range(now.Add(-historyRetention(config)), now) {
+		history.Publish(data)
 	}
 }
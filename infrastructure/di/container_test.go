@@ -0,0 +1,103 @@
+package di
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/suzutan/m5stack_airq_exporter/adapter/gateway"
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+	"github.com/suzutan/m5stack_airq_exporter/usecase"
+)
+
+func TestHistoryRetention_DailyReport(t *testing.T) {
+	config := &Config{
+		HistoryRetention: 24 * time.Hour,
+		ReportWebhookURL: "http://localhost/report",
+		ReportPeriods:    []entity.ReportPeriod{entity.ReportPeriodDaily},
+		ReportTime:       7 * time.Hour,
+	}
+	history := gateway.NewMemoryHistoryGateway(historyRetention(config))
+
+	// A reading every 15 minutes from midnight until the report is posted at 07:00 the next day
+	day := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	posted := day.Add(31 * time.Hour)
+	for ts := day; !ts.After(posted); ts = ts.Add(15 * time.Minute) {
+		history.Publish(&entity.AirQuality{Device: "airq-1", UpdateTime: ts, CO2: 600})
+	}
+
+	report := usecase.NewReportUsecase(history, usecase.DefaultReportConfig()).Generate(entity.ReportPeriodDaily, posted)
+	if len(report.Devices) != 1 {
+		t.Fatalf("expected 1 device, got %d", len(report.Devices))
+	}
+	if d := report.Devices[0]; d.Readings != 96 || d.CoveredHours != 24 {
+		t.Errorf("expected the whole day from its first reading, got %d readings covering %fh", d.Readings, d.CoveredHours)
+	}
+}
+
+func TestHistoryRetention(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   Config
+		expected time.Duration
+	}{
+		{"default", Config{HistoryRetention: 24 * time.Hour}, 24 * time.Hour},
+		{"rolling window", Config{HistoryRetention: time.Hour, RollingWindows: []time.Duration{8 * time.Hour}}, 8 * time.Hour},
+		{"weekly report", Config{HistoryRetention: 24 * time.Hour, ReportWebhookURL: "http://localhost/report", ReportPeriods: []entity.ReportPeriod{entity.ReportPeriodDaily, entity.ReportPeriodWeekly}, ReportTime: 7 * time.Hour}, 176 * time.Hour},
+		{"report from the store", Config{HistoryRetention: 24 * time.Hour, StorePath: "readings.jsonl", ReportWebhookURL: "http://localhost/report", ReportPeriods: []entity.ReportPeriod{entity.ReportPeriodWeekly}}, 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := historyRetention(&tt.config); got != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestNewContainer_ReplayLeavesStateUntouched(t *testing.T) {
+	dir := t.TempDir()
+	recording := filepath.Join(dir, "recording.jsonl")
+	body := `{"code":200,"msg":"OK","data":{"dataToken":"test-token","dataType":"string","name":"raw","value":"{\"scd40\":{\"co2\":725},\"profile\":{\"nickname\":\"AirQ\"}}","createTime":"1703591914","updateTime":"1767573960"}}`
+	line, _ := json.Marshal(map[string]string{"time": "2026-01-05T00:00:00Z", "body": body})
+	if err := os.WriteFile(recording, append(line, '\n'), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	state := filepath.Join(dir, "state.jsonl")
+	snapshot := []byte(`{"device":"airq-production","update_time":"2026-01-04T23:59:00Z"}` + "\n")
+	if err := os.WriteFile(state, snapshot, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &Config{
+		ReplayPath:       recording,
+		StatePath:        state,
+		ReportWebhookURL: "http://localhost/report",
+		MQTT:             gateway.MQTTOptions{URL: "tcp://localhost:1883"},
+		HistoryRetention: 24 * time.Hour,
+		StreamBufferSize: 1,
+	}
+	container, err := NewContainer(config)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if config.StatePath != "" || config.ReportWebhookURL != "" || config.MQTT.URL != "" {
+		t.Errorf("expected replay to disable the state, webhook and MQTT, got %+v", config)
+	}
+
+	if _, err := container.FetchAirQUsecase.Execute(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := container.Lifecycle.Stop(context.Background()).Err(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	content, err := os.ReadFile(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != string(snapshot) {
+		t.Errorf("expected the state snapshot to be untouched, got %s", content)
+	}
+}
@@ -0,0 +1,132 @@
+package http
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// authRealm is the realm announced to clients asked for basic auth
+const authRealm = "airq-exporter"
+
+// dummyPasswordHash is compared against the password of unknown users, so that
+// they take as long to refuse as wrong passwords and timing does not reveal
+// which user names exist. It has the default cost of the hashes htpasswd and
+// the Prometheus tooling generate.
+const dummyPasswordHash = "$2a$10$DNowRJDjGdOlZAu.XSDDkuNMBO7oCI8L/tt5eEMmzqqgbdqVXlmfW"
+
+// authenticator checks the client certificate and credentials of requests
+type authenticator struct {
+	requireClientCert bool
+	// users maps user names to bcrypt password hashes
+	users  map[string]string
+	tokens []string
+
+	// verified caches successful bcrypt comparisons, keyed by a hash of the
+	// user and password, as every scrape would otherwise pay for one
+	mu       sync.Mutex
+	verified map[[sha256.Size]byte]bool
+}
+
+// newAuthMiddleware returns a middleware enforcing the client certificate,
+// basic auth and bearer token settings of the web configuration
+func newAuthMiddleware(config *WebConfig) echo.MiddlewareFunc {
+	a := &authenticator{
+		requireClientCert: config.RequireClientCert(),
+		users:             config.BasicAuthUsers,
+		tokens:            config.BearerTokens,
+		verified:          make(map[[sha256.Size]byte]bool),
+	}
+	return a.middleware
+}
+
+// newAdminAuthMiddleware returns a middleware enforcing the client certificate
+// setting and the admin credentials of the web configuration, which must have
+// an admin section
+func newAdminAuthMiddleware(config *WebConfig) echo.MiddlewareFunc {
+	a := &authenticator{
+		requireClientCert: config.RequireClientCert(),
+		users:             config.Admin.BasicAuthUsers,
+		tokens:            config.Admin.BearerTokens,
+		verified:          make(map[[sha256.Size]byte]bool),
+	}
+	return a.middleware
+}
+
+func (a *authenticator) middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		if a.requireClientCert && (req.TLS == nil || len(req.TLS.VerifiedChains) == 0) {
+			return echo.NewHTTPError(http.StatusForbidden, "client certificate required")
+		}
+		if len(a.users) == 0 && len(a.tokens) == 0 {
+			return next(c)
+		}
+		if a.authorized(req) {
+			return next(c)
+		}
+
+		var challenges []string
+		if len(a.users) > 0 {
+			challenges = append(challenges, `Basic realm="`+authRealm+`"`)
+		}
+		if len(a.tokens) > 0 {
+			challenges = append(challenges, `Bearer realm="`+authRealm+`"`)
+		}
+		for _, challenge := range challenges {
+			c.Response().Header().Add(echo.HeaderWWWAuthenticate, challenge)
+		}
+		return echo.ErrUnauthorized
+	}
+}
+
+// authorized reports whether the request carries valid basic auth credentials
+// or a valid bearer token
+func (a *authenticator) authorized(req *http.Request) bool {
+	if user, password, ok := req.BasicAuth(); ok {
+		return a.checkPassword(user, password)
+	}
+
+	header := req.Header.Get(echo.HeaderAuthorization)
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return false
+	}
+	valid := false
+	for _, t := range a.tokens {
+		// Compare every token so that timing does not reveal which one matched
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
+
+func (a *authenticator) checkPassword(user, password string) bool {
+	hash, ok := a.users[user]
+	if !ok {
+		_ = bcrypt.CompareHashAndPassword([]byte(dummyPasswordHash), []byte(password))
+		return false
+	}
+
+	key := sha256.Sum256([]byte(user + "\x00" + password + "\x00" + hash))
+	a.mu.Lock()
+	cached := a.verified[key]
+	a.mu.Unlock()
+	if cached {
+		return true
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return false
+	}
+	a.mu.Lock()
+	a.verified[key] = true
+	a.mu.Unlock()
+	return true
+}
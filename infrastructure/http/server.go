@@ -2,6 +2,9 @@ package http
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -12,31 +15,120 @@ import (
 type Server struct {
 	echo      *echo.Echo
 	container *di.Container
+	webConfig *WebConfig
 }
 
-// NewServer creates a new HTTP server with the given container
-func NewServer(container *di.Container) *Server {
+// NewServer creates a new HTTP server with the given container. The web
+// configuration, which may be nil, protects every route but the health checks;
+// its admin section enables the admin API.
+func NewServer(container *di.Container, webConfig *WebConfig) *Server {
+	if webConfig == nil {
+		webConfig = &WebConfig{}
+	}
+
 	e := echo.New()
 	e.HideBanner = true
+	e.HidePort = true
 
 	// Middleware
-	e.Use(middleware.Logger())
+	e.Use(requestLogger(slog.Default()))
 	e.Use(middleware.Recover())
 
-	// Routes
-	e.GET("/metrics", container.MetricsHandler.Handle)
+	// Health checks stay open for probes
 	e.GET("/healthz", container.HealthHandler.HandleLiveness)
 	e.GET("/readyz", container.HealthHandler.HandleReadiness)
 
+	// Routes
+	protected := e.Group("", newAuthMiddleware(webConfig))
+	protected.GET("/", container.DashboardHandler.Handle)
+	protected.GET("/metrics", container.MetricsHandler.Handle)
+	protected.GET("/version", container.VersionHandler.Handle)
+
+	// API
+	protected.GET("/api/openapi.json", container.OpenAPIHandler.Handle)
+	api := protected.Group("/api/v1")
+	api.GET("/readings", container.ReadingsHandler.HandleList)
+	api.GET("/readings/:device", container.ReadingsHandler.HandleDevice)
+	api.GET("/stream", container.StreamHandler.Handle)
+	api.GET("/history", container.HistoryHandler.Handle)
+	api.GET("/export", container.ExportHandler.Handle)
+	api.GET("/reports", container.ReportHandler.Handle)
+	api.GET("/devices", container.DevicesHandler.Handle)
+
+	// Admin API, only with admin credentials
+	if webConfig.Admin != nil {
+		admin := e.Group("/admin", newAdminAuthMiddleware(webConfig))
+		if container.AdminHandler != nil {
+			admin.GET("/scheduler", container.AdminHandler.HandleState)
+			admin.POST("/scheduler/pause", container.AdminHandler.HandlePause)
+			admin.POST("/scheduler/resume", container.AdminHandler.HandleResume)
+			admin.PUT("/scheduler/interval", container.AdminHandler.HandleInterval)
+			admin.POST("/refresh/:device", container.AdminHandler.HandleRefresh)
+		}
+		admin.GET("/maintenance", container.MaintenanceHandler.HandleList)
+		admin.POST("/maintenance", container.MaintenanceHandler.HandleCreate)
+		admin.DELETE("/maintenance/:id", container.MaintenanceHandler.HandleDelete)
+	}
+
 	return &Server{
 		echo:      e,
 		container: container,
+		webConfig: webConfig,
 	}
 }
 
-// Start starts the HTTP server
+// requestLogger logs every request through the application logger: server
+// errors at error level, client errors at warn level and the rest at info level
+func requestLogger(logger *slog.Logger) echo.MiddlewareFunc {
+	return middleware.RequestLoggerWithConfig(middleware.RequestLoggerConfig{
+		LogMethod:    true,
+		LogURI:       true,
+		LogStatus:    true,
+		LogLatency:   true,
+		LogRemoteIP:  true,
+		LogUserAgent: true,
+		LogError:     true,
+		HandleError:  true,
+		LogValuesFunc: func(c echo.Context, v middleware.RequestLoggerValues) error {
+			level := slog.LevelInfo
+			switch {
+			case v.Status >= http.StatusInternalServerError:
+				level = slog.LevelError
+			case v.Status >= http.StatusBadRequest:
+				level = slog.LevelWarn
+			}
+
+			attrs := []slog.Attr{
+				slog.String("method", v.Method),
+				slog.String("uri", v.URI),
+				slog.Int("status", v.Status),
+				slog.Duration("duration", v.Latency),
+				slog.String("remote_ip", v.RemoteIP),
+				slog.String("user_agent", v.UserAgent),
+			}
+			if v.Error != nil {
+				attrs = append(attrs, slog.String("error", v.Error.Error()))
+			}
+			logger.LogAttrs(c.Request().Context(), level, "HTTP request", attrs...)
+			return nil
+		},
+	})
+}
+
+// Start starts the HTTP server, over TLS if the web configuration enables it
 func (s *Server) Start(address string) error {
-	return s.echo.Start(address)
+	tlsConfig, err := s.webConfig.TLSConfig()
+	if err != nil {
+		return fmt.Errorf("failed to configure TLS: %w", err)
+	}
+	if tlsConfig == nil {
+		return s.echo.Start(address)
+	}
+
+	// Serve through echo's TLS server so that Shutdown stops it
+	s.echo.TLSServer.Addr = address
+	s.echo.TLSServer.TLSConfig = tlsConfig
+	return s.echo.StartServer(s.echo.TLSServer)
 }
 
 // Shutdown gracefully shuts down the server
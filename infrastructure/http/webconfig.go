@@ -0,0 +1,228 @@
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"go.yaml.in/yaml/v2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Client certificate policies of tls_server_config.client_auth_type
+const (
+	ClientAuthNone             = "NoClientCert"
+	ClientAuthVerifyIfGiven    = "VerifyClientCertIfGiven"
+	ClientAuthRequireAndVerify = "RequireAndVerifyClientCert"
+)
+
+// WebConfig is the web configuration file, compatible with the TLS and basic
+// auth settings of the Prometheus exporter-toolkit, plus bearer tokens and the
+// credentials of the admin API
+type WebConfig struct {
+	TLSServerConfig *TLSServerConfig `yaml:"tls_server_config"`
+	// BasicAuthUsers maps user names to bcrypt password hashes
+	BasicAuthUsers map[string]string `yaml:"basic_auth_users"`
+	BearerTokens   []string          `yaml:"bearer_tokens"`
+	// Admin enables the /admin routes; without it they are not served
+	Admin *AdminConfig `yaml:"admin"`
+}
+
+// AdminConfig holds the credentials of the admin API, which are separate from
+// those of the read-only routes
+type AdminConfig struct {
+	// BasicAuthUsers maps user names to bcrypt password hashes
+	BasicAuthUsers map[string]string `yaml:"basic_auth_users"`
+	BearerTokens   []string          `yaml:"bearer_tokens"`
+}
+
+// TLSServerConfig holds the TLS settings of the web configuration
+type TLSServerConfig struct {
+	CertFile       string `yaml:"cert_file"`
+	KeyFile        string `yaml:"key_file"`
+	ClientAuthType string `yaml:"client_auth_type"`
+	ClientCAFile   string `yaml:"client_ca_file"`
+	MinVersion     string `yaml:"min_version"`
+}
+
+var tlsVersions = map[string]uint16{
+	"TLS10": tls.VersionTLS10,
+	"TLS11": tls.VersionTLS11,
+	"TLS12": tls.VersionTLS12,
+	"TLS13": tls.VersionTLS13,
+}
+
+// LoadWebConfig reads and validates a web configuration file
+func LoadWebConfig(path string) (*WebConfig, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read web config: %w", err)
+	}
+
+	var config WebConfig
+	if err := yaml.UnmarshalStrict(content, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse web config: %w", err)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid web config: %w", err)
+	}
+	return &config, nil
+}
+
+// Validate checks the web configuration
+func (c *WebConfig) Validate() error {
+	if err := validateCredentials("", c.BasicAuthUsers, c.BearerTokens); err != nil {
+		return err
+	}
+	if a := c.Admin; a != nil {
+		if len(a.BasicAuthUsers) == 0 && len(a.BearerTokens) == 0 {
+			return errors.New("admin requires basic_auth_users or bearer_tokens")
+		}
+		if err := validateCredentials("admin.", a.BasicAuthUsers, a.BearerTokens); err != nil {
+			return err
+		}
+	}
+
+	t := c.TLSServerConfig
+	if t == nil {
+		return nil
+	}
+	if t.CertFile == "" || t.KeyFile == "" {
+		return errors.New("tls_server_config requires cert_file and key_file")
+	}
+	switch t.ClientAuthType {
+	case "", ClientAuthNone:
+	case ClientAuthVerifyIfGiven, ClientAuthRequireAndVerify:
+		if t.ClientCAFile == "" {
+			return fmt.Errorf("client_auth_type %s requires client_ca_file", t.ClientAuthType)
+		}
+	default:
+		return fmt.Errorf("unsupported client_auth_type: %s", t.ClientAuthType)
+	}
+	if _, ok := tlsVersions[t.MinVersion]; t.MinVersion != "" && !ok {
+		return fmt.Errorf("unsupported min_version: %s", t.MinVersion)
+	}
+	return nil
+}
+
+// validateCredentials checks basic auth users and bearer tokens; prefix is
+// the path of their section in error messages
+func validateCredentials(prefix string, users map[string]string, tokens []string) error {
+	for user, hash := range users {
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return fmt.Errorf("%sbasic_auth_users.%s is not a bcrypt hash: %w", prefix, user, err)
+		}
+	}
+	for _, token := range tokens {
+		if token == "" {
+			return fmt.Errorf("%sbearer_tokens must not be empty", prefix)
+		}
+	}
+	return nil
+}
+
+// RequireClientCert reports whether protected routes require a verified client certificate
+func (c *WebConfig) RequireClientCert() bool {
+	return c.TLSServerConfig != nil && c.TLSServerConfig.ClientAuthType == ClientAuthRequireAndVerify
+}
+
+// TLSConfig builds the server TLS configuration, or nil when TLS is disabled.
+// Certificates are reloaded when their files change. Client certificates are
+// verified during the handshake but only required by the auth middleware, so
+// that probes on unprotected routes can connect without one.
+func (c *WebConfig) TLSConfig() (*tls.Config, error) {
+	t := c.TLSServerConfig
+	if t == nil {
+		return nil, nil
+	}
+
+	reloader := &certReloader{certFile: t.CertFile, keyFile: t.KeyFile}
+	if _, err := reloader.load(); err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+	if v, ok := tlsVersions[t.MinVersion]; ok {
+		config.MinVersion = v
+	}
+
+	if t.ClientCAFile != "" {
+		pem, err := os.ReadFile(t.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in client CA file %s", t.ClientCAFile)
+		}
+		config.ClientCAs = pool
+	}
+	switch t.ClientAuthType {
+	case ClientAuthVerifyIfGiven, ClientAuthRequireAndVerify:
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return config, nil
+}
+
+// certReloader serves a certificate and reloads it when the files change,
+// e.g. after cert-manager renewed it
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	certTime time.Time
+	keyTime  time.Time
+}
+
+// GetCertificate implements tls.Config.GetCertificate
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert, err := r.load()
+	if err != nil {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.cert == nil {
+			return nil, err
+		}
+		// Keep serving the previous certificate while the files are being replaced
+		slog.Error("Failed to reload TLS certificate", "cert_file", r.certFile, "error", err)
+		return r.cert, nil
+	}
+	return cert, nil
+}
+
+// load returns the current certificate, reading the files again if they changed
+func (r *certReloader) load() (*tls.Certificate, error) {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TLS certificate: %w", err)
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TLS key: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cert != nil && certInfo.ModTime().Equal(r.certTime) && keyInfo.ModTime().Equal(r.keyTime) {
+		return r.cert, nil
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	r.cert = &cert
+	r.certTime = certInfo.ModTime()
+	r.keyTime = keyInfo.ModTime()
+	return r.cert, nil
+}
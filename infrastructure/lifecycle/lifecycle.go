@@ -0,0 +1,144 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Hook starts and stops a component; either function may be nil
+type Hook struct {
+	Name string
+	// OnStart starts the component and returns; long-running work belongs in a goroutine
+	OnStart func(ctx context.Context) error
+	// OnStop stops the component, giving up when the context ends
+	OnStop func(ctx context.Context) error
+}
+
+// Lifecycle starts components in the order their hooks were appended and stops
+// them in reverse order, so that a component stops before those it depends on
+type Lifecycle struct {
+	mu      sync.Mutex
+	hooks   []Hook
+	started int
+}
+
+// New creates a new Lifecycle without hooks
+func New() *Lifecycle {
+	return &Lifecycle{}
+}
+
+// Append registers a hook
+func (l *Lifecycle) Append(hook Hook) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hooks = append(l.hooks, hook)
+}
+
+// Start runs the start hooks in order. When one fails, the components already
+// started are stopped again and the error is returned.
+func (l *Lifecycle) Start(ctx context.Context) error {
+	l.mu.Lock()
+	hooks := l.hooks[l.started:]
+	l.mu.Unlock()
+
+	for _, hook := range hooks {
+		if hook.OnStart != nil {
+			if err := hook.OnStart(ctx); err != nil {
+				l.Stop(ctx).Log(slog.Default())
+				return fmt.Errorf("failed to start %s: %w", hook.Name, err)
+			}
+		}
+		l.mu.Lock()
+		l.started++
+		l.mu.Unlock()
+	}
+	return nil
+}
+
+// StepResult is the outcome of a stop hook
+type StepResult struct {
+	Name     string
+	Duration time.Duration
+	Err      error
+}
+
+// Report describes a shutdown, with the steps in the order they ran
+type Report struct {
+	Steps    []StepResult
+	Duration time.Duration
+}
+
+// Err returns the errors of the failed steps
+func (r Report) Err() error {
+	var errs []error
+	for _, step := range r.Steps {
+		if step.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", step.Name, step.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Log writes a line per step and a summary
+func (r Report) Log(logger *slog.Logger) {
+	failed := 0
+	for _, step := range r.Steps {
+		if step.Err != nil {
+			failed++
+			logger.Error("Shutdown step failed", "step", step.Name, "duration", step.Duration, "error", step.Err)
+		} else {
+			logger.Info("Shutdown step done", "step", step.Name, "duration", step.Duration)
+		}
+	}
+	if failed > 0 {
+		logger.Warn("Shutdown finished with errors", "steps", len(r.Steps), "failed", failed, "duration", r.Duration)
+		return
+	}
+	logger.Info("Shutdown complete", "steps", len(r.Steps), "duration", r.Duration)
+}
+
+// Stop runs the stop hooks of the started components in reverse order, all
+// within the deadline of ctx. A hook still running when ctx ends is reported
+// as failed and left behind, so that the remaining hooks still get to run.
+func (l *Lifecycle) Stop(ctx context.Context) Report {
+	l.mu.Lock()
+	hooks := l.hooks[:l.started]
+	l.started = 0
+	l.mu.Unlock()
+
+	start := time.Now()
+	var report Report
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hook := hooks[i]
+		if hook.OnStop == nil {
+			continue
+		}
+		stepStart := time.Now()
+		err := runStop(ctx, hook)
+		report.Steps = append(report.Steps, StepResult{Name: hook.Name, Duration: time.Since(stepStart), Err: err})
+	}
+	report.Duration = time.Since(start)
+	return report
+}
+
+// runStop runs a stop hook until it returns or ctx ends
+func runStop(ctx context.Context, hook Hook) error {
+	done := make(chan error, 1)
+	go func() { done <- hook.OnStop(ctx) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		// Give a hook that honors ctx the chance to report why it gave up
+		select {
+		case err := <-done:
+			return err
+		case <-time.After(100 * time.Millisecond):
+			return fmt.Errorf("did not stop in time: %w", ctx.Err())
+		}
+	}
+}
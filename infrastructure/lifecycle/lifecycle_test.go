@@ -0,0 +1,82 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLifecycle_StopsInReverseOrder(t *testing.T) {
+	var events []string
+	l := New()
+	for _, name := range []string{"store", "server", "scheduler"} {
+		l.Append(Hook{
+			Name:    name,
+			OnStart: func(context.Context) error { events = append(events, "start "+name); return nil },
+			OnStop:  func(context.Context) error { events = append(events, "stop "+name); return nil },
+		})
+	}
+
+	if err := l.Start(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	report := l.Stop(context.Background())
+
+	expected := "start store,start server,start scheduler,stop scheduler,stop server,stop store"
+	if got := strings.Join(events, ","); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+	if len(report.Steps) != 3 || report.Steps[0].Name != "scheduler" || report.Err() != nil {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
+
+func TestLifecycle_StartFailureStopsStartedComponents(t *testing.T) {
+	var stopped []string
+	l := New()
+	l.Append(Hook{Name: "store", OnStop: func(context.Context) error { stopped = append(stopped, "store"); return nil }})
+	l.Append(Hook{
+		Name:    "server",
+		OnStart: func(context.Context) error { return errors.New("address in use") },
+		OnStop:  func(context.Context) error { stopped = append(stopped, "server"); return nil },
+	})
+
+	err := l.Start(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "server") {
+		t.Fatalf("expected start error naming the server, got %v", err)
+	}
+	if strings.Join(stopped, ",") != "store" {
+		t.Errorf("expected only the store to be stopped, got %v", stopped)
+	}
+}
+
+func TestLifecycle_StopDeadline(t *testing.T) {
+	l := New()
+	block := make(chan struct{})
+	defer close(block)
+	l.Append(Hook{Name: "store", OnStop: func(context.Context) error { return nil }})
+	l.Append(Hook{Name: "stuck", OnStop: func(context.Context) error { <-block; return nil }})
+	l.Append(Hook{Name: "failing", OnStop: func(context.Context) error { return errors.New("flush failed") }})
+	if err := l.Start(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	report := l.Stop(ctx)
+
+	if len(report.Steps) != 3 {
+		t.Fatalf("expected every step to run, got %+v", report.Steps)
+	}
+	if report.Steps[0].Err == nil || report.Steps[1].Err == nil || report.Steps[2].Err != nil {
+		t.Errorf("expected the failing and stuck steps to fail, got %+v", report.Steps)
+	}
+	if !errors.Is(report.Steps[1].Err, context.DeadlineExceeded) {
+		t.Errorf("expected the stuck step to exceed the deadline, got %v", report.Steps[1].Err)
+	}
+	if err := report.Err(); err == nil || !strings.Contains(err.Error(), "failing: flush failed") {
+		t.Errorf("expected the report error to name the failed steps, got %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+	"github.com/suzutan/m5stack_airq_exporter/domain/repository"
+	"github.com/suzutan/m5stack_airq_exporter/usecase"
+)
+
+// ReportScheduler delivers reports at a fixed local time: daily reports every day
+// and weekly reports on Mondays
+type ReportScheduler struct {
+	reportUsecase *usecase.ReportUsecase
+	periods       []entity.ReportPeriod
+	at            time.Duration
+}
+
+// NewReportScheduler creates a new report scheduler delivering the given periods
+// at the given time of day, in the time zone of the report usecase
+func NewReportScheduler(reportUsecase *usecase.ReportUsecase, periods []entity.ReportPeriod, at time.Duration) *ReportScheduler {
+	return &ReportScheduler{
+		reportUsecase: reportUsecase,
+		periods:       periods,
+		at:            at,
+	}
+}
+
+// ParseTimeOfDay parses a local time of day such as "07:30"
+func ParseTimeOfDay(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q: %w", value, err)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Start delivers the reports every day until the context is canceled
+func (s *ReportScheduler) Start(ctx context.Context) {
+	for {
+		next := s.next(time.Now())
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			slog.Info("Report scheduler stopped")
+			return
+		case <-timer.C:
+			s.execute(ctx, next)
+		}
+	}
+}
+
+// next returns the first delivery time after now
+func (s *ReportScheduler) next(now time.Time) time.Time {
+	now = now.In(s.reportUsecase.Location())
+	hour, minute := int(s.at/time.Hour), int(s.at%time.Hour/time.Minute)
+	for day := 0; ; day++ {
+		// Build the wall clock time so that DST changes keep the local time of day
+		at := time.Date(now.Year(), now.Month(), now.Day()+day, hour, minute, 0, 0, now.Location())
+		if at.After(now) {
+			return at
+		}
+	}
+}
+
+func (s *ReportScheduler) execute(ctx context.Context, at time.Time) {
+	for _, period := range s.periods {
+		if period == entity.ReportPeriodWeekly && at.Weekday() != time.Monday {
+			continue
+		}
+		if err := s.reportUsecase.Deliver(ctx, period); err != nil {
+			slog.Error("Failed to deliver report", "period", period, "error", err, "error_class", repository.ErrorClass(err))
+		} else {
+			slog.Info("Delivered report", "period", period)
+		}
+	}
+}
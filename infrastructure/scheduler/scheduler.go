@@ -2,49 +2,345 @@ package scheduler
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
 	"time"
 
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+	"github.com/suzutan/m5stack_airq_exporter/domain/repository"
 	"github.com/suzutan/m5stack_airq_exporter/usecase"
 )
 
-// Scheduler handles periodic task execution
+// MinInterval is the shortest interval accepted by SetInterval
+const MinInterval = 5 * time.Second
+
+// maxQueuedRefreshes bounds the refresh requests waiting for the scheduler
+const maxQueuedRefreshes = 10
+
+var (
+	// ErrRefreshQueueFull is returned by Refresh when too many requests are waiting
+	ErrRefreshQueueFull = errors.New("refresh queue is full")
+	// ErrStopped is returned by Refresh after Stop
+	ErrStopped = errors.New("scheduler is stopped")
+)
+
+// Scheduler handles periodic task execution. Scheduled fetches can be paused
+// and their interval changed at runtime; refresh requests are fetched one at a
+// time between them, also while paused.
 type Scheduler struct {
 	fetchUsecase *usecase.FetchAirQUsecase
-	interval     time.Duration
+	logger       *slog.Logger
+
+	mu          sync.Mutex
+	interval    time.Duration
+	paused      bool
+	nextRun     time.Time
+	lastStarted time.Time
+	inProgress  *entity.SchedulerRun
+	lastRun     *entity.SchedulerRun
+	queue       []*refreshRequest
+	// wake tells the loop that the schedule or the queue changed
+	wake chan struct{}
+
+	// Stop closes stop to end the schedule, and cancels abort to abort the
+	// fetch in progress when it takes too long; done is closed when Start returns
+	stop     chan struct{}
+	stopOnce sync.Once
+	abort    context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// refreshRequest is a queued refresh; requests for the same device share it
+type refreshRequest struct {
+	entity.RefreshRequest
+	done chan struct{}
+	run  entity.SchedulerRun
+	err  error
 }
 
 // NewScheduler creates a new scheduler with the given usecase and interval
 func NewScheduler(fetchUsecase *usecase.FetchAirQUsecase, interval time.Duration) *Scheduler {
+	abort, cancel := context.WithCancel(context.Background())
 	return &Scheduler{
 		fetchUsecase: fetchUsecase,
+		logger:       slog.Default(),
 		interval:     interval,
+		wake:         make(chan struct{}, 1),
+		stop:         make(chan struct{}),
+		abort:        abort,
+		cancel:       cancel,
+		done:         make(chan struct{}),
 	}
 }
 
-// Start begins the periodic execution of the fetch task
+// WithLogger sets the logger used to report fetch results
+func (s *Scheduler) WithLogger(logger *slog.Logger) *Scheduler {
+	s.logger = logger
+	return s
+}
+
+// Start begins the periodic execution of the fetch task until the context is
+// canceled, which aborts a fetch in progress, or Stop is called
 func (s *Scheduler) Start(ctx context.Context) {
-	// Execute immediately on start
-	s.execute(ctx)
+	defer close(s.done)
+	defer s.dropQueue()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer context.AfterFunc(s.abort, cancel)()
 
-	ticker := time.NewTicker(s.interval)
-	defer ticker.Stop()
+	// Execute immediately on start
+	s.mu.Lock()
+	s.nextRun = time.Now()
+	s.mu.Unlock()
 
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
 	for {
+		req, wait := s.next()
+		if req != nil || wait == 0 {
+			s.execute(ctx, req)
+			continue
+		}
+
+		var due <-chan time.Time
+		if wait > 0 {
+			timer.Reset(wait)
+			due = timer.C
+		}
 		select {
 		case <-ctx.Done():
-			log.Println("Scheduler stopped")
+			s.logger.Info("Scheduler stopped")
+			return
+		case <-s.stop:
+			s.logger.Info("Scheduler stopped")
 			return
-		case <-ticker.C:
-			s.execute(ctx)
+		case <-s.wake:
+		case <-due:
+		}
+		timer.Stop()
+	}
+}
+
+// next returns the refresh request to run next, or else how long to wait for
+// the next scheduled fetch: zero when it is due, -1 while paused or stopping
+func (s *Scheduler) next() (*refreshRequest, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-s.stop:
+		return nil, -1
+	default:
+	}
+	if len(s.queue) > 0 {
+		req := s.queue[0]
+		s.queue = s.queue[1:]
+		return req, 0
+	}
+	if s.paused {
+		return nil, -1
+	}
+	return nil, max(time.Until(s.nextRun), 0)
+}
+
+// Stop ends the schedule started with Start and waits for the fetch in
+// progress, so that its reading still reaches the sinks. When ctx ends first,
+// the fetch is aborted.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.stopOnce.Do(func() { close(s.stop) })
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		<-s.done
+		return fmt.Errorf("aborted the fetch in progress: %w", ctx.Err())
+	}
+}
+
+// Refresh queues a fetch of the device, to run as soon as the fetch in
+// progress finishes, and waits for it. Requests for a device already queued
+// share its fetch.
+func (s *Scheduler) Refresh(ctx context.Context, device string) (entity.SchedulerRun, error) {
+	s.mu.Lock()
+	select {
+	case <-s.stop:
+		s.mu.Unlock()
+		return entity.SchedulerRun{}, ErrStopped
+	default:
+	}
+	var req *refreshRequest
+	for _, queued := range s.queue {
+		if queued.Device == device {
+			req = queued
+		}
+	}
+	if req == nil {
+		if len(s.queue) >= maxQueuedRefreshes {
+			s.mu.Unlock()
+			return entity.SchedulerRun{}, ErrRefreshQueueFull
 		}
+		req = &refreshRequest{
+			RefreshRequest: entity.RefreshRequest{Device: device, RequestedAt: time.Now()},
+			done:           make(chan struct{}),
+		}
+		s.queue = append(s.queue, req)
+	}
+	s.mu.Unlock()
+	s.notify()
+
+	select {
+	case <-req.done:
+		return req.run, req.err
+	case <-ctx.Done():
+		return entity.SchedulerRun{}, ctx.Err()
+	}
+}
+
+// Pause stops the scheduled fetches; a fetch in progress and refreshes still run
+func (s *Scheduler) Pause() {
+	s.mu.Lock()
+	s.paused = true
+	s.mu.Unlock()
+	s.logger.Info("Scheduler paused")
+	s.notify()
+}
+
+// Resume restarts the scheduled fetches with one right away
+func (s *Scheduler) Resume() {
+	s.mu.Lock()
+	s.paused = false
+	s.nextRun = time.Now()
+	s.mu.Unlock()
+	s.logger.Info("Scheduler resumed")
+	s.notify()
+}
+
+// SetInterval changes the time between scheduled fetches, counted from the
+// start of the last one
+func (s *Scheduler) SetInterval(interval time.Duration) error {
+	if interval < MinInterval {
+		return fmt.Errorf("interval must be at least %s", MinInterval)
+	}
+	s.mu.Lock()
+	s.interval = interval
+	s.nextRun = s.lastStarted.Add(interval)
+	s.mu.Unlock()
+	s.logger.Info("Scheduler interval changed", "interval", interval)
+	s.notify()
+	return nil
+}
+
+// State returns a snapshot of the schedule, the fetch in progress, the last
+// fetch and the queued refreshes
+func (s *Scheduler) State() entity.SchedulerState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := entity.SchedulerState{
+		Paused:   s.paused,
+		Interval: s.interval.Seconds(),
+		Queue:    make([]entity.RefreshRequest, 0, len(s.queue)),
+	}
+	if !s.paused {
+		state.NextRun = s.nextRun
+	}
+	if s.inProgress != nil {
+		run := *s.inProgress
+		state.InProgress = &run
+	}
+	if s.lastRun != nil {
+		run := *s.lastRun
+		state.LastRun = &run
+	}
+	for _, req := range s.queue {
+		state.Queue = append(state.Queue, req.RefreshRequest)
+	}
+	return state
+}
+
+// dropQueue fails the refresh requests still queued when the loop ends
+func (s *Scheduler) dropQueue() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, req := range s.queue {
+		req.err = ErrStopped
+		close(req.done)
+	}
+	s.queue = nil
+}
+
+// notify wakes the loop without blocking; one pending wake-up is enough
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
 	}
 }
 
-func (s *Scheduler) execute(ctx context.Context) {
-	if err := s.fetchUsecase.Execute(ctx); err != nil {
-		log.Printf("Failed to fetch air quality data: %v", err)
-	} else {
-		log.Println("Successfully fetched air quality data")
+// execute runs the fetch usecase once, for a refresh request or on schedule,
+// and logs the outcome: failures at error level with their class, successful
+// fetches at debug level
+func (s *Scheduler) execute(ctx context.Context, req *refreshRequest) {
+	start := time.Now()
+	run := entity.SchedulerRun{Trigger: entity.TriggerSchedule, StartedAt: start}
+	if req != nil {
+		run.Trigger = entity.TriggerRefresh
+		run.Device = req.Device
 	}
+	s.mu.Lock()
+	if req == nil {
+		s.lastStarted = start
+		s.nextRun = start.Add(s.interval)
+	}
+	inProgress := run
+	s.inProgress = &inProgress
+	s.mu.Unlock()
+
+	result, err := s.fetchUsecase.Execute(ctx)
+	duration := time.Since(start)
+
+	run.Duration = duration.Seconds()
+	switch {
+	case err != nil:
+		run.Result = entity.RunResultError
+		run.Error = err.Error()
+		run.ErrorClass = repository.ErrorClass(err)
+	case result.Duplicate:
+		run.Result = entity.RunResultDuplicate
+		run.Device = result.Reading.Device
+	default:
+		run.Result = entity.RunResultOK
+		run.Device = result.Reading.Device
+	}
+	s.mu.Lock()
+	s.inProgress = nil
+	s.lastRun = &run
+	s.mu.Unlock()
+	if req != nil {
+		req.run = run
+		close(req.done)
+	}
+
+	if err != nil {
+		s.logger.Error("Failed to fetch air quality data",
+			"error", err,
+			"error_class", repository.ErrorClass(err),
+			"trigger", run.Trigger,
+			"duration", duration,
+		)
+		return
+	}
+
+	s.logger.Debug("Fetched air quality data",
+		"device", result.Reading.Device,
+		"nickname", result.Reading.Nickname,
+		"duplicate", result.Duplicate,
+		"warming_up", result.Reading.WarmingUp,
+		"trigger", run.Trigger,
+		"duration", duration,
+	)
 }
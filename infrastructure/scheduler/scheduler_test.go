@@ -0,0 +1,143 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+	"github.com/suzutan/m5stack_airq_exporter/usecase"
+)
+
+// slowAirQRepository returns a reading after delay, or the context error when
+// the context ends first
+type slowAirQRepository struct {
+	delay   time.Duration
+	started chan struct{}
+}
+
+func (r *slowAirQRepository) Fetch(ctx context.Context) (*entity.AirQuality, error) {
+	r.started <- struct{}{}
+	select {
+	case <-time.After(r.delay):
+		return &entity.AirQuality{Device: "dev", CO2: 725}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// countingMetricsRepository counts the readings it receives
+type countingMetricsRepository struct {
+	updates chan *entity.AirQuality
+}
+
+func (m *countingMetricsRepository) Update(data *entity.AirQuality) { m.updates <- data }
+
+func (m *countingMetricsRepository) RecordDuplicate(*entity.AirQuality) {}
+
+func TestScheduler_StopWaitsForFetchInProgress(t *testing.T) {
+	repo := &slowAirQRepository{delay: 50 * time.Millisecond, started: make(chan struct{}, 1)}
+	metrics := &countingMetricsRepository{updates: make(chan *entity.AirQuality, 1)}
+	sched := NewScheduler(usecase.NewFetchAirQUsecase(repo, metrics), time.Hour)
+	go sched.Start(context.Background())
+	<-repo.started
+
+	if err := sched.Stop(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	select {
+	case <-metrics.updates:
+	default:
+		t.Error("expected the reading of the fetch in progress to be kept")
+	}
+}
+
+func TestScheduler_StopAbortsAfterDeadline(t *testing.T) {
+	repo := &slowAirQRepository{delay: time.Hour, started: make(chan struct{}, 1)}
+	metrics := &countingMetricsRepository{updates: make(chan *entity.AirQuality, 1)}
+	sched := NewScheduler(usecase.NewFetchAirQUsecase(repo, metrics), time.Hour)
+	go sched.Start(context.Background())
+	<-repo.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := sched.Stop(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the fetch to be aborted at the deadline, got %v", err)
+	}
+}
+
+func TestScheduler_RefreshWhilePaused(t *testing.T) {
+	repo := &slowAirQRepository{started: make(chan struct{}, 10)}
+	metrics := &countingMetricsRepository{updates: make(chan *entity.AirQuality, 10)}
+	sched := NewScheduler(usecase.NewFetchAirQUsecase(repo, metrics), time.Hour)
+	sched.Pause()
+	go sched.Start(context.Background())
+	defer sched.Stop(context.Background())
+
+	state := sched.State()
+	if !state.Paused || !state.NextRun.IsZero() {
+		t.Errorf("expected a paused schedule without a next run, got %+v", state)
+	}
+
+	run, err := sched.Refresh(context.Background(), "dev")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if run.Trigger != entity.TriggerRefresh || run.Result != entity.RunResultOK || run.Device != "dev" {
+		t.Errorf("unexpected refresh run: %+v", run)
+	}
+	if last := sched.State().LastRun; last == nil || last.Trigger != entity.TriggerRefresh {
+		t.Errorf("expected the refresh as last run, got %+v", last)
+	}
+	if len(repo.started) != 1 {
+		t.Errorf("expected only the refresh to fetch, got %d fetches", len(repo.started))
+	}
+
+	sched.Resume()
+	select {
+	case <-metrics.updates:
+	case <-time.After(time.Second):
+	}
+	select {
+	case <-metrics.updates:
+	case <-time.After(time.Second):
+		t.Error("expected a scheduled fetch right after resuming")
+	}
+}
+
+func TestScheduler_SetInterval(t *testing.T) {
+	repo := &slowAirQRepository{started: make(chan struct{}, 10)}
+	metrics := &countingMetricsRepository{updates: make(chan *entity.AirQuality, 10)}
+	sched := NewScheduler(usecase.NewFetchAirQUsecase(repo, metrics), time.Hour)
+
+	if err := sched.SetInterval(time.Second); err == nil {
+		t.Error("expected an interval below the minimum to be rejected")
+	}
+	if err := sched.SetInterval(10 * time.Minute); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	go sched.Start(context.Background())
+	defer sched.Stop(context.Background())
+	<-metrics.updates
+
+	state := sched.State()
+	if state.Interval != 600 {
+		t.Errorf("expected an interval of 600 seconds, got %v", state.Interval)
+	}
+	if wait := time.Until(state.NextRun); wait < 9*time.Minute || wait > 10*time.Minute {
+		t.Errorf("expected the next run in 10 minutes, got %v", wait)
+	}
+}
+
+func TestScheduler_RefreshAfterStop(t *testing.T) {
+	sched := NewScheduler(usecase.NewFetchAirQUsecase(&slowAirQRepository{started: make(chan struct{}, 1)}, &countingMetricsRepository{updates: make(chan *entity.AirQuality, 1)}), time.Hour)
+	go sched.Start(context.Background())
+	sched.Stop(context.Background())
+
+	if _, err := sched.Refresh(context.Background(), "dev"); !errors.Is(err, ErrStopped) {
+		t.Errorf("expected ErrStopped, got %v", err)
+	}
+}
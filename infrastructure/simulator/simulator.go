@@ -0,0 +1,365 @@
+package simulator
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EscapeMode controls how the sensor payload is encoded in the value field
+type EscapeMode string
+
+const (
+	// EscapeDouble encodes the payload with escaped quotes, as ezdata2.m5stack.com does
+	EscapeDouble EscapeMode = "double"
+	// EscapeSingle encodes the payload as a plain JSON string
+	EscapeSingle EscapeMode = "single"
+	// EscapeMixed alternates randomly between both encodings
+	EscapeMixed EscapeMode = "mixed"
+)
+
+// simulationStep is the resolution of the simulated time series
+const simulationStep = time.Minute
+
+// Device describes a simulated AirQ device
+type Device struct {
+	Token    string
+	Nickname string
+}
+
+// Config holds the configuration of the simulator
+type Config struct {
+	Devices []Device
+
+	// Start is the simulated time at startup; Speed is simulated seconds per real second
+	Start time.Time
+	Speed float64
+
+	// UploadInterval is how often the simulated device uploads a reading
+	UploadInterval time.Duration
+
+	// Occupants is the number of people in the room during office hours
+	Occupants int
+	// PMSpikeRate is the expected number of particle spikes (cooking, cleaning) per hour
+	PMSpikeRate float64
+
+	Escape EscapeMode
+
+	// ErrorRate is the probability of an API-level error (HTTP 200 with a non-200 code);
+	// HTTPErrorRate is the probability of an HTTP 503 response
+	ErrorRate     float64
+	HTTPErrorRate float64
+
+	// Latency and LatencyJitter delay every response by Latency ± LatencyJitter
+	Latency       time.Duration
+	LatencyJitter time.Duration
+
+	Seed int64
+}
+
+// DefaultConfig returns a simulator configuration with a single device in real time
+func DefaultConfig() Config {
+	return Config{
+		Devices:        []Device{{Token: "SIMULATED0001", Nickname: "AirQ"}},
+		Start:          time.Now(),
+		Speed:          1,
+		UploadInterval: time.Minute,
+		Occupants:      4,
+		PMSpikeRate:    0.2,
+		Escape:         EscapeDouble,
+		Seed:           1,
+	}
+}
+
+// deviceState holds the evolving physical state of a simulated room
+type deviceState struct {
+	device     Device
+	createTime time.Time
+	rng        *rand.Rand
+
+	t       time.Time
+	co2     float64
+	pmSpike float64
+	nox     float64
+
+	// The last upload is repeated until the next upload interval
+	lastUpload  time.Time
+	lastPayload sensorPayload
+}
+
+// Simulator serves EzData API responses with synthetic readings
+type Simulator struct {
+	config    Config
+	realStart time.Time
+	now       func() time.Time
+
+	mu      sync.Mutex
+	rng     *rand.Rand
+	devices map[string]*deviceState
+	order   []string
+}
+
+// New creates a new Simulator with the given configuration
+func New(config Config) *Simulator {
+	if config.Speed <= 0 {
+		config.Speed = 1
+	}
+	if config.UploadInterval <= 0 {
+		config.UploadInterval = time.Minute
+	}
+	if config.Start.IsZero() {
+		config.Start = time.Now()
+	}
+
+	s := &Simulator{
+		config:    config,
+		realStart: time.Now(),
+		now:       time.Now,
+		rng:       rand.New(rand.NewSource(config.Seed)),
+		devices:   make(map[string]*deviceState),
+	}
+	for i, d := range config.Devices {
+		s.devices[d.Token] = &deviceState{
+			device:     d,
+			createTime: config.Start.Add(-30 * 24 * time.Hour).Truncate(time.Second),
+			rng:        rand.New(rand.NewSource(config.Seed + int64(i) + 1)),
+			// Run the room for a day before the start so that the series begins settled
+			t:   config.Start.Add(-24 * time.Hour).Truncate(simulationStep),
+			co2: 420,
+			nox: 1,
+		}
+		s.order = append(s.order, d.Token)
+	}
+	return s
+}
+
+// simulatedNow returns the current simulated time
+func (s *Simulator) simulatedNow() time.Time {
+	elapsed := s.now().Sub(s.realStart)
+	return s.config.Start.Add(time.Duration(float64(elapsed) * s.config.Speed))
+}
+
+// ServeHTTP implements http.Handler. Requests are routed to a device by the token in
+// /api/v2/{token}/...; with a single device every path returns that device.
+func (s *Simulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	delay := s.config.Latency
+	if s.config.LatencyJitter > 0 {
+		delay += time.Duration((s.rng.Float64()*2 - 1) * float64(s.config.LatencyJitter))
+	}
+	httpError := s.rng.Float64() < s.config.HTTPErrorRate
+	apiError := s.rng.Float64() < s.config.ErrorRate
+	s.mu.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if httpError {
+		http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	if apiError {
+		writeJSON(w, apiResponse{Code: 500, Msg: "Internal Error"})
+		return
+	}
+
+	body, ok := s.response(tokenFromPath(r.URL.Path))
+	if !ok {
+		writeJSON(w, apiResponse{Code: 404, Msg: "device not found"})
+		return
+	}
+	writeJSON(w, body)
+}
+
+// tokenFromPath extracts {token} from /api/v2/{token}/...
+func tokenFromPath(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) >= 3 && parts[0] == "api" && parts[1] == "v2" {
+		return parts[2]
+	}
+	return ""
+}
+
+// apiResponse mirrors the ezdata2.m5stack.com response envelope
+type apiResponse struct {
+	Code int       `json:"code"`
+	Msg  string    `json:"msg"`
+	Data *dataBody `json:"data"`
+}
+
+type dataBody struct {
+	DataToken  string `json:"dataToken"`
+	DataType   string `json:"dataType"`
+	Name       string `json:"name"`
+	Value      string `json:"value"`
+	CreateTime string `json:"createTime"`
+	UpdateTime string `json:"updateTime"`
+}
+
+type sensorPayload struct {
+	SEN55 struct {
+		PM1_0       float64 `json:"pm1.0"`
+		PM2_5       float64 `json:"pm2.5"`
+		PM4_0       float64 `json:"pm4.0"`
+		PM10_0      float64 `json:"pm10.0"`
+		Humidity    float64 `json:"humidity"`
+		Temperature float64 `json:"temperature"`
+		VOC         int     `json:"voc"`
+		NOx         int     `json:"nox"`
+	} `json:"sen55"`
+	SCD40 struct {
+		CO2         int     `json:"co2"`
+		Humidity    float64 `json:"humidity"`
+		Temperature float64 `json:"temperature"`
+	} `json:"scd40"`
+	RTC struct {
+		SleepInterval int `json:"sleep_interval"`
+	} `json:"rtc"`
+	Profile struct {
+		Nickname string `json:"nickname"`
+	} `json:"profile"`
+}
+
+// response returns the API response for the device with the given token at the
+// current simulated time. An empty token selects the first device.
+func (s *Simulator) response(token string) (apiResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if token == "" || (len(s.order) == 1 && s.devices[token] == nil) {
+		if len(s.order) == 0 {
+			return apiResponse{}, false
+		}
+		token = s.order[0]
+	}
+	state, ok := s.devices[token]
+	if !ok {
+		return apiResponse{}, false
+	}
+
+	// The device only uploads at its interval; in between the same reading is returned
+	uploaded := s.simulatedNow().Truncate(s.config.UploadInterval)
+	if !uploaded.Equal(state.lastUpload) {
+		state.lastPayload = state.advance(uploaded, s.config)
+		state.lastUpload = uploaded
+	}
+	payload := state.lastPayload
+
+	value, _ := json.Marshal(payload)
+	escape := s.config.Escape
+	if escape == EscapeMixed {
+		escape = EscapeSingle
+		if s.rng.Intn(2) == 0 {
+			escape = EscapeDouble
+		}
+	}
+	valueStr := string(value)
+	if escape == EscapeDouble {
+		valueStr = strings.ReplaceAll(valueStr, `"`, `\"`)
+	}
+
+	return apiResponse{
+		Code: 200,
+		Msg:  "OK",
+		Data: &dataBody{
+			DataToken:  state.device.Token,
+			DataType:   "string",
+			Name:       "raw",
+			Value:      valueStr,
+			CreateTime: strconv.FormatInt(state.createTime.Unix(), 10),
+			UpdateTime: strconv.FormatInt(uploaded.Unix(), 10),
+		},
+	}, true
+}
+
+// occupancy returns the fraction of occupants present at the given time:
+// weekdays 9:00-18:00 with a quieter lunch hour
+func occupancy(t time.Time) float64 {
+	if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+		return 0
+	}
+	switch h := t.Hour(); {
+	case h < 9 || h >= 18:
+		return 0
+	case h == 12:
+		return 0.4
+	default:
+		return 1
+	}
+}
+
+// advance steps the room simulation up to t and returns the sensor readings at t
+func (d *deviceState) advance(t time.Time, config Config) sensorPayload {
+	const (
+		outdoorCO2       = 420.0
+		co2PerOccupant   = 180.0 // steady-state excess ppm per person
+		ventilationTau   = 30.0  // minutes
+		pmSpikeHalfLife  = 20.0  // minutes
+		noxSpikeHalfLife = 30.0  // minutes
+	)
+
+	for d.t.Before(t) {
+		d.t = d.t.Add(simulationStep)
+		occ := occupancy(d.t)
+
+		target := outdoorCO2 + co2PerOccupant*occ*float64(config.Occupants)
+		d.co2 += (target-d.co2)/ventilationTau + d.rng.NormFloat64()*3
+
+		d.pmSpike *= math.Pow(0.5, 1/pmSpikeHalfLife)
+		d.nox = 1 + (d.nox-1)*math.Pow(0.5, 1/noxSpikeHalfLife)
+		if d.rng.Float64() < config.PMSpikeRate/60 {
+			d.pmSpike += 20 + d.rng.Float64()*60
+			d.nox += 10 + d.rng.Float64()*20
+		}
+	}
+
+	occ := occupancy(t)
+	hour := float64(t.Hour()) + float64(t.Minute())/60
+	// Diurnal temperature cycle peaking mid-afternoon, plus body heat
+	temperature := 22 + 2*math.Sin(2*math.Pi*(hour-9)/24) + 0.8*occ + d.rng.NormFloat64()*0.05
+	humidity := 45 - 1.5*(temperature-22) + d.rng.NormFloat64()*0.3
+	pm25 := math.Max(0, 3+2*occ+d.pmSpike+d.rng.NormFloat64()*0.3)
+	voc := 100 + 60*occ + d.pmSpike*0.8 + d.rng.NormFloat64()*3
+
+	var p sensorPayload
+	p.SEN55.PM1_0 = round1(pm25 * 0.65)
+	p.SEN55.PM2_5 = round1(pm25)
+	p.SEN55.PM4_0 = round1(pm25 * 1.05)
+	p.SEN55.PM10_0 = round1(pm25 * 1.1)
+	p.SEN55.Humidity = round2(humidity)
+	p.SEN55.Temperature = round2(temperature)
+	p.SEN55.VOC = clamp(int(math.Round(voc)), 1, 500)
+	p.SEN55.NOx = clamp(int(math.Round(d.nox)), 1, 500)
+	p.SCD40.CO2 = int(math.Round(math.Max(400, d.co2)))
+	// The SCD40 sits closer to the board and reads warmer and drier
+	p.SCD40.Temperature = round2(temperature + 2.5)
+	p.SCD40.Humidity = round2(humidity * 0.85)
+	p.RTC.SleepInterval = int(config.UploadInterval.Seconds())
+	p.Profile.Nickname = d.device.Nickname
+	return p
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, fmt.Sprintf("failed to encode response: %v", err), http.StatusInternalServerError)
+	}
+}
+
+func round1(v float64) float64 { return math.Round(v*10) / 10 }
+func round2(v float64) float64 { return math.Round(v*100) / 100 }
+
+func clamp(v, lo, hi int) int {
+	return max(lo, min(v, hi))
+}
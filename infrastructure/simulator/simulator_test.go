@@ -0,0 +1,118 @@
+package simulator
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/suzutan/m5stack_airq_exporter/adapter/gateway"
+)
+
+func TestSimulator_GatewayEndToEnd(t *testing.T) {
+	for _, escape := range []EscapeMode{EscapeDouble, EscapeSingle, EscapeMixed} {
+		config := DefaultConfig()
+		config.Escape = escape
+		config.Devices = []Device{{Token: "TOKEN1", Nickname: "Office"}, {Token: "TOKEN2", Nickname: "Lobby"}}
+		// Tuesday 10:00, office hours
+		config.Start = time.Date(2026, 1, 6, 10, 0, 0, 0, time.UTC)
+
+		server := httptest.NewServer(New(config))
+
+		for _, d := range config.Devices {
+			g := gateway.NewAirQHTTPGateway(server.URL+"/api/v2/"+d.Token+"/dataMacByKey/raw", server.Client())
+			data, err := g.Fetch(context.Background())
+			if err != nil {
+				t.Fatalf("%s: expected no error, got %v", escape, err)
+			}
+
+			if data.Device != gateway.DeviceID(d.Token) || data.Nickname != d.Nickname {
+				t.Errorf("%s: expected device %s (%s), got %s (%s)", escape, gateway.DeviceID(d.Token), d.Nickname, data.Device, data.Nickname)
+			}
+			if data.CO2 < 800 {
+				t.Errorf("%s: expected occupied room to have elevated CO2, got %d", escape, data.CO2)
+			}
+			if data.Temperature < 15 || data.Temperature > 30 || data.Humidity <= 0 {
+				t.Errorf("%s: unrealistic climate: %.2f°C %.2f%%", escape, data.Temperature, data.Humidity)
+			}
+			if !data.UpdateTime.Equal(config.Start) {
+				t.Errorf("%s: expected update time %v, got %v", escape, config.Start, data.UpdateTime)
+			}
+		}
+
+		server.Close()
+	}
+}
+
+func TestSimulator_NightIsUnoccupied(t *testing.T) {
+	config := DefaultConfig()
+	config.Start = time.Date(2026, 1, 6, 4, 0, 0, 0, time.UTC)
+
+	server := httptest.NewServer(New(config))
+	defer server.Close()
+
+	data, err := gateway.NewAirQHTTPGateway(server.URL, server.Client()).Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if data.CO2 > 600 {
+		t.Errorf("expected CO2 close to outdoor level at night, got %d", data.CO2)
+	}
+}
+
+func TestSimulator_RepeatsReadingUntilNextUpload(t *testing.T) {
+	config := DefaultConfig()
+	config.Start = time.Date(2026, 1, 6, 10, 0, 0, 0, time.UTC)
+	sim := New(config)
+	realNow := sim.realStart
+	sim.now = func() time.Time { return realNow }
+
+	first, _ := sim.response("")
+	realNow = realNow.Add(30 * time.Second)
+	second, _ := sim.response("")
+	realNow = realNow.Add(30 * time.Second)
+	third, _ := sim.response("")
+
+	if first.Data.Value != second.Data.Value || first.Data.UpdateTime != second.Data.UpdateTime {
+		t.Error("expected the same reading within an upload interval")
+	}
+	if third.Data.UpdateTime == first.Data.UpdateTime {
+		t.Error("expected a new upload after the interval")
+	}
+}
+
+func TestSimulator_ErrorInjection(t *testing.T) {
+	config := DefaultConfig()
+	config.ErrorRate = 1
+
+	server := httptest.NewServer(New(config))
+	defer server.Close()
+
+	if _, err := gateway.NewAirQHTTPGateway(server.URL, server.Client()).Fetch(context.Background()); err == nil {
+		t.Error("expected API error, got nil")
+	}
+
+	config.ErrorRate = 0
+	config.HTTPErrorRate = 1
+	server2 := httptest.NewServer(New(config))
+	defer server2.Close()
+
+	if _, err := gateway.NewAirQHTTPGateway(server2.URL, server2.Client()).Fetch(context.Background()); err == nil {
+		t.Error("expected HTTP error, got nil")
+	}
+}
+
+func TestSimulator_Latency(t *testing.T) {
+	config := DefaultConfig()
+	config.Latency = 50 * time.Millisecond
+
+	server := httptest.NewServer(New(config))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := gateway.NewAirQHTTPGateway(server.URL, server.Client()).Fetch(ctx); err == nil {
+		t.Error("expected timeout error, got nil")
+	}
+}
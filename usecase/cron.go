@@ -0,0 +1,184 @@
+package usecase
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule matches times against a five-field cron expression: minute,
+// hour, day of month, month and day of week. Fields accept *, numbers, ranges,
+// steps and comma-separated lists; months and days of week also accept their
+// three-letter English names. As in cron, a time matches when the day of month
+// or the day of week matches if neither field starts with an asterisk.
+type CronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+// cronField describes the range and names of one field of a cron expression
+type cronField struct {
+	name     string
+	min, max int
+	names    []string
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	// 7 is Sunday as well
+	{name: "day of week", min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+// ParseCronSchedule parses a cron expression such as "0 9 * * mon-fri"
+func ParseCronSchedule(expr string) (*CronSchedule, error) {
+	parts := strings.Fields(expr)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("invalid schedule %q: expected %d fields", expr, len(cronFields))
+	}
+
+	var masks [5]uint64
+	for i, part := range parts {
+		b, err := cronFields[i].parse(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", expr, err)
+		}
+		masks[i] = b
+	}
+	// Sunday is both 0 and 7
+	if masks[4]&(1<<7) != 0 {
+		masks[4] |= 1
+	}
+	return &CronSchedule{
+		minute: masks[0],
+		hour:   masks[1],
+		dom:    masks[2],
+		month:  masks[3],
+		dow:    masks[4],
+		domAny: strings.HasPrefix(parts[2], "*"),
+		dowAny: strings.HasPrefix(parts[4], "*"),
+	}, nil
+}
+
+// parse returns the set of values of a field as a bit mask
+func (f cronField) parse(value string) (uint64, error) {
+	var mask uint64
+	for _, item := range strings.Split(value, ",") {
+		rng, step := item, 1
+		if r, s, ok := strings.Cut(item, "/"); ok {
+			n, err := strconv.Atoi(s)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid %s step %q", f.name, s)
+			}
+			rng, step = r, n
+		}
+
+		lo, hi := f.min, f.max
+		if rng != "*" {
+			from, to, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = f.value(from); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = f.value(to); err != nil {
+					return 0, err
+				}
+			} else if step > 1 {
+				// "5/15" means from 5 to the end of the range
+				hi = f.max
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("invalid %s range %q", f.name, rng)
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			mask |= 1 << v
+		}
+	}
+	return mask, nil
+}
+
+// value parses a single number or name of a field
+func (f cronField) value(s string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return f.min + i, nil
+		}
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < f.min || n > f.max {
+		return 0, fmt.Errorf("invalid %s %q", f.name, s)
+	}
+	return n, nil
+}
+
+// Matches reports whether the minute of t matches the schedule
+func (s *CronSchedule) Matches(t time.Time) bool {
+	return s.minute&(1<<t.Minute()) != 0 && s.hour&(1<<t.Hour()) != 0 && s.matchesDay(t)
+}
+
+// matchesDay reports whether the day of t matches the schedule
+func (s *CronSchedule) matchesDay(t time.Time) bool {
+	if s.month&(1<<int(t.Month())) == 0 {
+		return false
+	}
+	dom := s.dom&(1<<t.Day()) != 0
+	dow := s.dow&(1<<int(t.Weekday())) != 0
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dow
+	case s.dowAny:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+// Within reports whether a time matching the schedule lies in (t-d, t], that
+// is whether a window of length d started at such a time covers t
+func (s *CronSchedule) Within(t time.Time, d time.Duration) bool {
+	prev, ok := s.prev(t, t.Add(-d))
+	return ok && prev.After(t.Add(-d))
+}
+
+// prev returns the latest time matching the schedule at or before t, walking
+// back a day at a time until the day ends before start
+func (s *CronSchedule) prev(t, start time.Time) (time.Time, bool) {
+	hourLimit, minuteLimit := t.Hour(), t.Minute()
+	for day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()); day.AddDate(0, 0, 1).After(start); day = day.AddDate(0, 0, -1) {
+		if s.matchesDay(day) {
+			for hour := latestBit(s.hour, hourLimit); hour >= 0; hour = latestBit(s.hour, hour-1) {
+				limit := 59
+				if hour == hourLimit {
+					limit = minuteLimit
+				}
+				for minute := latestBit(s.minute, limit); minute >= 0; minute = latestBit(s.minute, minute-1) {
+					// Times skipped by a daylight saving change are normalized to
+					// other ones and do not match
+					m := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, day.Location())
+					if m.Hour() == hour && m.Minute() == minute && !m.After(t) {
+						return m, true
+					}
+				}
+			}
+		}
+		hourLimit, minuteLimit = 23, 59
+	}
+	return time.Time{}, false
+}
+
+// latestBit returns the highest value of a bit mask up to limit, or -1 if there is none
+func latestBit(mask uint64, limit int) int {
+	if limit < 0 {
+		return -1
+	}
+	return bits.Len64(mask&(1<<(limit+1)-1)) - 1
+}
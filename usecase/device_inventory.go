@@ -0,0 +1,67 @@
+package usecase
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+)
+
+// DeviceInventory keeps the metadata and fetch statistics of every device seen
+// since startup
+type DeviceInventory struct {
+	now func() time.Time
+
+	mu      sync.Mutex
+	devices map[string]*entity.DeviceInfo
+}
+
+// NewDeviceInventory creates a new empty DeviceInventory
+func NewDeviceInventory() *DeviceInventory {
+	return &DeviceInventory{
+		now:     time.Now,
+		devices: make(map[string]*entity.DeviceInfo),
+	}
+}
+
+// Observe records a fetch that returned the given reading
+func (i *DeviceInventory) Observe(data *entity.AirQuality, duplicate bool) {
+	now := i.now()
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	info, ok := i.devices[data.Device]
+	if !ok {
+		info = &entity.DeviceInfo{Device: data.Device, FirstSeen: now}
+		i.devices[data.Device] = info
+	}
+	info.Nickname = data.Nickname
+	info.Name = data.Name
+	info.DataType = data.DataType
+	info.SleepInterval = data.SleepInterval.Seconds()
+	info.LastSeen = now
+	info.Fetches++
+	if duplicate {
+		info.Duplicates++
+		return
+	}
+	info.Readings++
+	if !data.UpdateTime.IsZero() {
+		info.LastUpload = data.UpdateTime
+	}
+}
+
+// List returns every known device, ordered by device ID
+func (i *DeviceInventory) List() []entity.DeviceInfo {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	devices := make([]entity.DeviceInfo, 0, len(i.devices))
+	for _, info := range i.devices {
+		devices = append(devices, *info)
+	}
+	sort.Slice(devices, func(a, b int) bool { return devices[a].Device < devices[b].Device })
+	return devices
+}
@@ -0,0 +1,72 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+)
+
+func TestDeviceInventory_ObserveThroughFetch(t *testing.T) {
+	base := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	times := []time.Time{base, base.Add(time.Minute), base.Add(2 * time.Minute)}
+	inventory := NewDeviceInventory()
+	inventory.now = func() time.Time {
+		ts := times[0]
+		times = times[1:]
+		return ts
+	}
+
+	repo := &mockAirQRepository{data: &entity.AirQuality{
+		Device:        "dev-1",
+		Nickname:      "Office",
+		Name:          "raw",
+		DataType:      "string",
+		SleepInterval: time.Minute,
+		UpdateTime:    base.Add(-time.Minute),
+	}}
+	usecase := NewFetchAirQUsecase(repo, &mockMetricsRepository{}).WithInventory(inventory)
+
+	for range 2 {
+		if _, err := usecase.Execute(context.Background()); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	repo.data = &entity.AirQuality{Device: "dev-1", Nickname: "Meeting room", UpdateTime: base.Add(time.Minute)}
+	if _, err := usecase.Execute(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	devices := inventory.List()
+	if len(devices) != 1 {
+		t.Fatalf("expected 1 device, got %d", len(devices))
+	}
+	d := devices[0]
+	if d.Fetches != 3 || d.Readings != 2 || d.Duplicates != 1 {
+		t.Errorf("expected 3 fetches, 2 readings and 1 duplicate, got %d, %d and %d", d.Fetches, d.Readings, d.Duplicates)
+	}
+	if !d.FirstSeen.Equal(base) || !d.LastSeen.Equal(base.Add(2*time.Minute)) {
+		t.Errorf("unexpected first and last seen: %v, %v", d.FirstSeen, d.LastSeen)
+	}
+	if !d.LastUpload.Equal(base.Add(time.Minute)) {
+		t.Errorf("expected last upload %v, got %v", base.Add(time.Minute), d.LastUpload)
+	}
+	if d.Nickname != "Meeting room" {
+		t.Errorf("expected the latest nickname, got %s", d.Nickname)
+	}
+}
+
+func TestDeviceInventory_List(t *testing.T) {
+	inventory := NewDeviceInventory()
+	inventory.Observe(&entity.AirQuality{Device: "dev-2", Name: "raw", SleepInterval: 5 * time.Minute}, false)
+	inventory.Observe(&entity.AirQuality{Device: "dev-1"}, false)
+
+	devices := inventory.List()
+	if len(devices) != 2 || devices[0].Device != "dev-1" || devices[1].Device != "dev-2" {
+		t.Fatalf("expected devices ordered by ID, got %+v", devices)
+	}
+	if devices[1].SleepInterval != 300 || devices[1].Name != "raw" {
+		t.Errorf("unexpected metadata: %+v", devices[1])
+	}
+}
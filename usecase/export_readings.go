@@ -0,0 +1,215 @@
+package usecase
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+	"github.com/suzutan/m5stack_airq_exporter/domain/repository"
+)
+
+// ExportFormat is the output format of an export
+type ExportFormat string
+
+const (
+	// ExportFormatCSV writes comma-separated values with a header row
+	ExportFormatCSV ExportFormat = "csv"
+	// ExportFormatJSONL writes one JSON object per line
+	ExportFormatJSONL ExportFormat = "jsonl"
+)
+
+// Export columns in addition to the measurement fields
+const (
+	ExportColumnTime        = "time"
+	ExportColumnDevice      = "device"
+	ExportColumnNickname    = "nickname"
+	ExportColumnWarmingUp   = "warming_up"
+	ExportColumnMaintenance = "maintenance"
+)
+
+// ExportColumns returns all available export columns in their default order
+func ExportColumns() []string {
+	columns := []string{ExportColumnTime, ExportColumnDevice, ExportColumnNickname}
+	for _, f := range entity.Fields {
+		columns = append(columns, f.Key)
+	}
+	return append(columns, ExportColumnWarmingUp, ExportColumnMaintenance)
+}
+
+// ExportOptions holds the parameters of an export
+type ExportOptions struct {
+	Format   ExportFormat
+	From     time.Time
+	To       time.Time
+	Devices  []string
+	Columns  []string
+	Location *time.Location
+}
+
+// ExportReadingsUsecase writes recorded readings as CSV or JSON Lines
+type ExportReadingsUsecase struct {
+	history repository.HistoryRepository
+}
+
+// NewExportReadingsUsecase creates a new ExportReadingsUsecase reading from the given history
+func NewExportReadingsUsecase(history repository.HistoryRepository) *ExportReadingsUsecase {
+	return &ExportReadingsUsecase{
+		history: history,
+	}
+}
+
+// Validate checks the export options and fills in defaults
+func (o *ExportOptions) Validate() error {
+	switch o.Format {
+	case ExportFormatCSV, ExportFormatJSONL:
+	case "":
+		o.Format = ExportFormatCSV
+	default:
+		return fmt.Errorf("unsupported export format: %s", o.Format)
+	}
+
+	if len(o.Columns) == 0 {
+		o.Columns = ExportColumns()
+	}
+	available := make(map[string]bool)
+	for _, c := range ExportColumns() {
+		available[c] = true
+	}
+	for _, c := range o.Columns {
+		if !available[c] {
+			return fmt.Errorf("unknown export column: %s", c)
+		}
+	}
+
+	if o.Location == nil {
+		o.Location = time.UTC
+	}
+	if !o.To.IsZero() && o.From.After(o.To) {
+		return fmt.Errorf("export range start %s is after end %s", o.From, o.To)
+	}
+	return nil
+}
+
+// Execute writes the readings matching the options to w, oldest first for every
+// device, as they are read from the history
+func (u *ExportReadingsUsecase) Execute(w io.Writer, opts ExportOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	to := opts.To
+	if to.IsZero() {
+		to = time.Now()
+	}
+
+	each := func(fn func(data *entity.AirQuality) error) error {
+		return u.history.Each(opts.From, to, func(data *entity.AirQuality) error {
+			if !data.MatchesDevice(opts.Devices) {
+				return nil
+			}
+			return fn(data)
+		})
+	}
+
+	switch opts.Format {
+	case ExportFormatJSONL:
+		return writeJSONL(w, each, opts)
+	default:
+		return writeCSV(w, each, opts)
+	}
+}
+
+// exportReadings calls fn for every exported reading
+type exportReadings func(fn func(data *entity.AirQuality) error) error
+
+// exportValue returns the value of a column as a string and its JSON representation
+func exportValue(data *entity.AirQuality, column string, loc *time.Location) (string, any) {
+	switch column {
+	case ExportColumnTime:
+		ts := data.Timestamp().In(loc).Format(time.RFC3339)
+		return ts, ts
+	case ExportColumnDevice:
+		return data.Device, data.Device
+	case ExportColumnNickname:
+		return data.Nickname, data.Nickname
+	case ExportColumnWarmingUp:
+		return strconv.FormatBool(data.WarmingUp), data.WarmingUp
+	case ExportColumnMaintenance:
+		return strconv.FormatBool(data.Maintenance), data.Maintenance
+	}
+	f, _ := entity.FieldByKey(column)
+	if !data.Measures(f.Key) {
+		return "", nil
+	}
+	v := f.Value(data)
+	return strconv.FormatFloat(v, 'f', -1, 64), v
+}
+
+func writeCSV(w io.Writer, readings exportReadings, opts ExportOptions) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(opts.Columns); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	record := make([]string, len(opts.Columns))
+	err := readings(func(data *entity.AirQuality) error {
+		for i, column := range opts.Columns {
+			record[i], _ = exportValue(data, column, opts.Location)
+		}
+		if err := cw.Write(record); err != nil {
+			return fmt.Errorf("failed to write CSV record: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeJSONL(w io.Writer, readings exportReadings, opts ExportOptions) error {
+	return readings(func(data *entity.AirQuality) error {
+		var b strings.Builder
+		b.WriteByte('{')
+		for i, column := range opts.Columns {
+			_, v := exportValue(data, column, opts.Location)
+			key, _ := json.Marshal(column)
+			value, err := json.Marshal(v)
+			if err != nil {
+				return fmt.Errorf("failed to encode %s: %w", column, err)
+			}
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.Write(key)
+			b.WriteByte(':')
+			b.Write(value)
+		}
+		b.WriteString("}\n")
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return fmt.Errorf("failed to write JSON line: %w", err)
+		}
+		return nil
+	})
+}
+
+// ParseExportTime parses an export range boundary given as RFC 3339,
+// a local date-time (2006-01-02T15:04) or a local date (2006-01-02)
+func ParseExportTime(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time: %s", value)
+}
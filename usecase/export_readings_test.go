@@ -0,0 +1,157 @@
+package usecase
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+)
+
+// mockHistoryRepository is a mock implementation of HistoryRepository for testing
+type mockHistoryRepository struct {
+	readings []*entity.AirQuality
+	latest   []*entity.AirQuality
+}
+
+func (m *mockHistoryRepository) Range(from, to time.Time) []*entity.AirQuality {
+	var result []*entity.AirQuality
+	for _, r := range m.readings {
+		if ts := r.Timestamp(); !ts.Before(from) && !ts.After(to) {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+func (m *mockHistoryRepository) Each(from, to time.Time, fn func(data *entity.AirQuality) error) error {
+	for _, r := range m.Range(from, to) {
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *mockHistoryRepository) Latest() []*entity.AirQuality {
+	return m.latest
+}
+
+func newExportHistory() *mockHistoryRepository {
+	base := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	return &mockHistoryRepository{readings: []*entity.AirQuality{
+		{Device: "dev-1", Nickname: "Office", CO2: 700, PM2_5: 2.5, UpdateTime: base},
+		{Device: "dev-2", Nickname: "Lobby, main", CO2: 800, UpdateTime: base.Add(time.Minute)},
+		{Device: "dev-1", Nickname: "Office", CO2: 900, WarmingUp: true, UpdateTime: base.Add(time.Hour)},
+	}}
+}
+
+func TestExportReadingsUsecase_CSV(t *testing.T) {
+	usecase := NewExportReadingsUsecase(newExportHistory())
+	tokyo := time.FixedZone("JST", 9*60*60)
+
+	var buf bytes.Buffer
+	err := usecase.Execute(&buf, ExportOptions{
+		Format:   ExportFormatCSV,
+		From:     time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC),
+		To:       time.Date(2026, 1, 5, 0, 30, 0, 0, time.UTC),
+		Columns:  []string{"time", "nickname", "co2", "pm2_5"},
+		Location: tokyo,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := `time,nickname,co2,pm2_5
+2026-01-05T09:00:00+09:00,Office,700,2.5
+2026-01-05T09:01:00+09:00,"Lobby, main",800,0
+`
+	if buf.String() != expected {
+		t.Errorf("unexpected CSV:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+}
+
+func TestExportReadingsUsecase_JSONL(t *testing.T) {
+	usecase := NewExportReadingsUsecase(newExportHistory())
+
+	var buf bytes.Buffer
+	err := usecase.Execute(&buf, ExportOptions{
+		Format:  ExportFormatJSONL,
+		To:      time.Date(2026, 1, 6, 0, 0, 0, 0, time.UTC),
+		Devices: []string{"dev-1"},
+		Columns: []string{"time", "device", "co2", "warming_up"},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := `{"time":"2026-01-05T00:00:00Z","device":"dev-1","co2":700,"warming_up":false}
+{"time":"2026-01-05T01:00:00Z","device":"dev-1","co2":900,"warming_up":true}
+`
+	if buf.String() != expected {
+		t.Errorf("unexpected JSON Lines:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+}
+
+func TestExportReadingsUsecase_DefaultColumns(t *testing.T) {
+	usecase := NewExportReadingsUsecase(newExportHistory())
+
+	var buf bytes.Buffer
+	if err := usecase.Execute(&buf, ExportOptions{To: time.Date(2026, 1, 6, 0, 0, 0, 0, time.UTC)}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected header and 3 records, got %d lines", len(lines))
+	}
+	if lines[0] != strings.Join(ExportColumns(), ",") {
+		t.Errorf("unexpected header: %s", lines[0])
+	}
+}
+
+func TestExportOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name string
+		opts ExportOptions
+	}{
+		{"unknown format", ExportOptions{Format: "xlsx"}},
+		{"unknown column", ExportOptions{Columns: []string{"co2", "radon"}}},
+		{"inverted range", ExportOptions{From: time.Unix(200, 0), To: time.Unix(100, 0)}},
+	}
+
+	for _, tt := range tests {
+		if err := tt.opts.Validate(); err == nil {
+			t.Errorf("%s: expected error, got nil", tt.name)
+		}
+	}
+}
+
+func TestParseExportTime(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*60*60)
+
+	tests := []struct {
+		value    string
+		expected time.Time
+	}{
+		{"2026-01-05T09:00:00Z", time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)},
+		{"2026-01-05T09:00", time.Date(2026, 1, 5, 9, 0, 0, 0, tokyo)},
+		{"2026-01-05", time.Date(2026, 1, 5, 0, 0, 0, 0, tokyo)},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseExportTime(tt.value, tokyo)
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", tt.value, err)
+			continue
+		}
+		if !got.Equal(tt.expected) {
+			t.Errorf("%s: expected %v, got %v", tt.value, tt.expected, got)
+		}
+	}
+
+	if _, err := ParseExportTime("last monday", tokyo); err == nil {
+		t.Error("expected error for invalid time, got nil")
+	}
+}
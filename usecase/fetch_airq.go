@@ -3,7 +3,10 @@ package usecase
 import (
 	"context"
 	"fmt"
+	"sync"
+	"time"
 
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
 	"github.com/suzutan/m5stack_airq_exporter/domain/repository"
 )
 
@@ -11,6 +14,18 @@ import (
 type FetchAirQUsecase struct {
 	airqRepo    repository.AirQRepository
 	metricsRepo repository.MetricsRepository
+	warmUp      *WarmUpDetector
+	inventory   *DeviceInventory
+	maintenance *MaintenanceCalendar
+	sinks       []repository.ReadingSink
+	observers   []repository.FetchObserver
+
+	// The device only uploads periodically; fetches in between return the same reading
+	mu         sync.Mutex
+	lastUpdate map[string]time.Time
+	// latest holds the last reading of every device as given to the metrics,
+	// which duplicates update when a maintenance window opens or closes
+	latest map[string]*entity.AirQuality
 }
 
 // NewFetchAirQUsecase creates a new FetchAirQUsecase with the given dependencies
@@ -21,16 +36,131 @@ func NewFetchAirQUsecase(
 	return &FetchAirQUsecase{
 		airqRepo:    airqRepo,
 		metricsRepo: metricsRepo,
+		lastUpdate:  make(map[string]time.Time),
+		latest:      make(map[string]*entity.AirQuality),
 	}
 }
 
-// Execute fetches air quality data and updates the metrics
-func (u *FetchAirQUsecase) Execute(ctx context.Context) error {
+// WithWarmUpDetector enables marking readings taken during the sensor warm-up window
+func (u *FetchAirQUsecase) WithWarmUpDetector(detector *WarmUpDetector) *FetchAirQUsecase {
+	u.warmUp = detector
+	return u
+}
+
+// WithInventory enables tracking device metadata and fetch statistics
+func (u *FetchAirQUsecase) WithInventory(inventory *DeviceInventory) *FetchAirQUsecase {
+	u.inventory = inventory
+	return u
+}
+
+// WithMaintenance enables marking readings taken during maintenance windows
+func (u *FetchAirQUsecase) WithMaintenance(calendar *MaintenanceCalendar) *FetchAirQUsecase {
+	u.maintenance = calendar
+	return u
+}
+
+// WithSink registers a sink that receives every fetched reading
+func (u *FetchAirQUsecase) WithSink(sink repository.ReadingSink) *FetchAirQUsecase {
+	u.sinks = append(u.sinks, sink)
+	return u
+}
+
+// WithObserver registers an observer that is notified of every fetch outcome
+func (u *FetchAirQUsecase) WithObserver(observer repository.FetchObserver) *FetchAirQUsecase {
+	u.observers = append(u.observers, observer)
+	return u
+}
+
+// FetchResult describes the outcome of a successful fetch
+type FetchResult struct {
+	Reading *entity.AirQuality
+	// Duplicate is true if the device had not uploaded a new reading since the last fetch
+	Duplicate bool
+}
+
+// Execute fetches air quality data, updates the metrics and notifies the sinks.
+// A reading with the same device update time as the previous one is only counted
+// as a duplicate, so that sinks and derived statistics see every upload once.
+func (u *FetchAirQUsecase) Execute(ctx context.Context) (FetchResult, error) {
 	data, err := u.airqRepo.Fetch(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to fetch air quality data: %w", err)
+		for _, observer := range u.observers {
+			observer.FetchFailed(err)
+		}
+		return FetchResult{}, fmt.Errorf("failed to fetch air quality data: %w", err)
+	}
+	for _, observer := range u.observers {
+		observer.FetchSucceeded(data)
+	}
+
+	duplicate := u.isDuplicate(data)
+	if u.inventory != nil {
+		u.inventory.Observe(data, duplicate)
+	}
+	if duplicate {
+		u.metricsRepo.RecordDuplicate(data)
+		u.updateMaintenance(data)
+		return FetchResult{Reading: data, Duplicate: true}, nil
+	}
+
+	if u.warmUp != nil {
+		data.WarmingUp = u.warmUp.Observe(data)
+	}
+	if u.maintenance != nil {
+		data.Maintenance = u.maintenance.Active(data)
 	}
 
 	u.metricsRepo.Update(data)
-	return nil
+	u.mu.Lock()
+	u.latest[data.Device] = data
+	u.mu.Unlock()
+	for _, sink := range u.sinks {
+		sink.Publish(data)
+	}
+	return FetchResult{Reading: data}, nil
+}
+
+// updateMaintenance checks the maintenance windows at the time of a duplicate
+// fetch, so that the metrics follow a window opening or closing between two
+// uploads. The last reading is given to the metrics again with the new state;
+// sinks only see uploads.
+func (u *FetchAirQUsecase) updateMaintenance(data *entity.AirQuality) {
+	if u.maintenance == nil {
+		return
+	}
+	at := data.FetchedAt
+	if at.IsZero() {
+		at = time.Now()
+	}
+	data.Maintenance = u.maintenance.ActiveAt(data, at)
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	latest, ok := u.latest[data.Device]
+	if !ok || latest.Maintenance == data.Maintenance {
+		return
+	}
+	// Sinks keep the published reading, so it is copied rather than changed
+	updated := *latest
+	updated.Maintenance = data.Maintenance
+	u.latest[data.Device] = &updated
+	u.metricsRepo.Update(&updated)
+}
+
+// isDuplicate reports whether the device already uploaded this reading and
+// remembers its update time otherwise. Readings without an update time are
+// always treated as new.
+func (u *FetchAirQUsecase) isDuplicate(data *entity.AirQuality) bool {
+	if data.UpdateTime.IsZero() {
+		return false
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if last, ok := u.lastUpdate[data.Device]; ok && last.Equal(data.UpdateTime) {
+		return true
+	}
+	u.lastUpdate[data.Device] = data.UpdateTime
+	return false
 }
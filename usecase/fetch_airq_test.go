@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
 )
@@ -20,8 +21,9 @@ func (m *mockAirQRepository) Fetch(ctx context.Context) (*entity.AirQuality, err
 
 // mockMetricsRepository is a mock implementation of MetricsRepository for testing
 type mockMetricsRepository struct {
-	updatedData *entity.AirQuality
-	updateCount int
+	updatedData    *entity.AirQuality
+	updateCount    int
+	duplicateCount int
 }
 
 func (m *mockMetricsRepository) Update(data *entity.AirQuality) {
@@ -29,6 +31,33 @@ func (m *mockMetricsRepository) Update(data *entity.AirQuality) {
 	m.updateCount++
 }
 
+func (m *mockMetricsRepository) RecordDuplicate(data *entity.AirQuality) {
+	m.duplicateCount++
+}
+
+// mockReadingSink is a mock implementation of ReadingSink for testing
+type mockReadingSink struct {
+	published []*entity.AirQuality
+}
+
+func (m *mockReadingSink) Publish(data *entity.AirQuality) {
+	m.published = append(m.published, data)
+}
+
+// mockFetchObserver is a mock implementation of FetchObserver for testing
+type mockFetchObserver struct {
+	succeeded []*entity.AirQuality
+	failed    []error
+}
+
+func (m *mockFetchObserver) FetchSucceeded(data *entity.AirQuality) {
+	m.succeeded = append(m.succeeded, data)
+}
+
+func (m *mockFetchObserver) FetchFailed(err error) {
+	m.failed = append(m.failed, err)
+}
+
 func TestFetchAirQUsecase_Execute_Success(t *testing.T) {
 	expectedData := &entity.AirQuality{
 		PM1_0:            1.5,
@@ -49,7 +78,7 @@ func TestFetchAirQUsecase_Execute_Success(t *testing.T) {
 	metricsRepo := &mockMetricsRepository{}
 
 	usecase := NewFetchAirQUsecase(airqRepo, metricsRepo)
-	err := usecase.Execute(context.Background())
+	_, err := usecase.Execute(context.Background())
 
 	if err != nil {
 		t.Errorf("expected no error, got %v", err)
@@ -71,7 +100,7 @@ func TestFetchAirQUsecase_Execute_FetchError(t *testing.T) {
 	metricsRepo := &mockMetricsRepository{}
 
 	usecase := NewFetchAirQUsecase(airqRepo, metricsRepo)
-	err := usecase.Execute(context.Background())
+	_, err := usecase.Execute(context.Background())
 
 	if err == nil {
 		t.Error("expected error, got nil")
@@ -94,7 +123,7 @@ func TestFetchAirQUsecase_Execute_ContextCanceled(t *testing.T) {
 	metricsRepo := &mockMetricsRepository{}
 
 	usecase := NewFetchAirQUsecase(airqRepo, metricsRepo)
-	err := usecase.Execute(ctx)
+	_, err := usecase.Execute(ctx)
 
 	if err == nil {
 		t.Error("expected error, got nil")
@@ -104,3 +133,160 @@ func TestFetchAirQUsecase_Execute_ContextCanceled(t *testing.T) {
 		t.Errorf("expected Update not to be called, got %d", metricsRepo.updateCount)
 	}
 }
+
+func TestFetchAirQUsecase_Execute_PublishesToSinks(t *testing.T) {
+	expectedData := &entity.AirQuality{CO2: 725}
+
+	airqRepo := &mockAirQRepository{data: expectedData}
+	metricsRepo := &mockMetricsRepository{}
+	sink1 := &mockReadingSink{}
+	sink2 := &mockReadingSink{}
+
+	usecase := NewFetchAirQUsecase(airqRepo, metricsRepo).WithSink(sink1).WithSink(sink2)
+	if _, err := usecase.Execute(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	for i, sink := range []*mockReadingSink{sink1, sink2} {
+		if len(sink.published) != 1 || sink.published[0] != expectedData {
+			t.Errorf("expected sink %d to receive the reading once, got %v", i+1, sink.published)
+		}
+	}
+
+	airqRepo.err = errors.New("fetch error")
+	usecase.Execute(context.Background())
+	if len(sink1.published) != 1 {
+		t.Errorf("expected sinks not to be notified on fetch error, got %d readings", len(sink1.published))
+	}
+}
+
+func TestFetchAirQUsecase_Execute_NotifiesObservers(t *testing.T) {
+	base := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	airqRepo := &mockAirQRepository{data: &entity.AirQuality{Device: "airq-1", UpdateTime: base}}
+	observer := &mockFetchObserver{}
+	usecase := NewFetchAirQUsecase(airqRepo, &mockMetricsRepository{}).WithObserver(observer)
+
+	// Duplicates are successful fetches too
+	usecase.Execute(context.Background())
+	usecase.Execute(context.Background())
+	airqRepo.data, airqRepo.err = nil, errors.New("fetch error")
+	usecase.Execute(context.Background())
+
+	if len(observer.succeeded) != 2 {
+		t.Errorf("expected 2 successful fetches, got %d", len(observer.succeeded))
+	}
+	if len(observer.failed) != 1 || observer.failed[0] != airqRepo.err {
+		t.Errorf("expected the fetch error, got %v", observer.failed)
+	}
+}
+
+func TestFetchAirQUsecase_Execute_SkipsDuplicates(t *testing.T) {
+	base := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	airqRepo := &mockAirQRepository{}
+	metricsRepo := &mockMetricsRepository{}
+	sink := &mockReadingSink{}
+
+	usecase := NewFetchAirQUsecase(airqRepo, metricsRepo).WithSink(sink)
+
+	readings := []*entity.AirQuality{
+		{Device: "dev-1", CO2: 700, UpdateTime: base},
+		{Device: "dev-1", CO2: 700, UpdateTime: base},
+		{Device: "dev-2", CO2: 900, UpdateTime: base},
+		{Device: "dev-1", CO2: 750, UpdateTime: base.Add(time.Minute)},
+		{Device: "dev-1", CO2: 750, UpdateTime: base.Add(time.Minute)},
+	}
+	for i, data := range readings {
+		airqRepo.data = data
+		result, err := usecase.Execute(context.Background())
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if expected := i == 1 || i == 4; result.Duplicate != expected {
+			t.Errorf("reading %d: expected duplicate %v, got %v", i, expected, result.Duplicate)
+		}
+	}
+
+	if metricsRepo.updateCount != 3 {
+		t.Errorf("expected metrics to be updated 3 times, got %d", metricsRepo.updateCount)
+	}
+	if metricsRepo.duplicateCount != 2 {
+		t.Errorf("expected 2 duplicates, got %d", metricsRepo.duplicateCount)
+	}
+	if len(sink.published) != 3 {
+		t.Errorf("expected 3 published readings, got %d", len(sink.published))
+	}
+}
+
+func TestFetchAirQUsecase_Execute_WithoutUpdateTime(t *testing.T) {
+	airqRepo := &mockAirQRepository{data: &entity.AirQuality{CO2: 700}}
+	metricsRepo := &mockMetricsRepository{}
+
+	usecase := NewFetchAirQUsecase(airqRepo, metricsRepo)
+	for range 2 {
+		if _, err := usecase.Execute(context.Background()); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+
+	if metricsRepo.updateCount != 2 {
+		t.Errorf("expected readings without update time to be treated as new, got %d updates", metricsRepo.updateCount)
+	}
+}
+
+func TestFetchAirQUsecase_MarksMaintenance(t *testing.T) {
+	now := time.Now()
+	calendar := NewMaintenanceCalendar(time.UTC)
+	if _, err := calendar.Add(entity.MaintenanceWindow{Device: "dev-1", Start: now.Add(-time.Hour), End: now.Add(time.Hour)}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	sink := &mockReadingSink{}
+	u := NewFetchAirQUsecase(&mockAirQRepository{data: &entity.AirQuality{Device: "dev-1", UpdateTime: now}}, &mockMetricsRepository{}).
+		WithMaintenance(calendar).
+		WithSink(sink)
+
+	result, err := u.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !result.Reading.Maintenance || len(sink.published) != 1 || !sink.published[0].Maintenance {
+		t.Errorf("expected the reading to be marked, got %+v", result.Reading)
+	}
+}
+
+func TestFetchAirQUsecase_MaintenanceBetweenDuplicates(t *testing.T) {
+	base := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	calendar := NewMaintenanceCalendar(time.UTC)
+	// The window opens at 09:10 and closes at 09:20, between two uploads
+	if _, err := calendar.Add(entity.MaintenanceWindow{Device: "dev-1", Start: base.Add(10 * time.Minute), End: base.Add(20 * time.Minute)}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	airqRepo := &mockAirQRepository{}
+	metricsRepo := &mockMetricsRepository{}
+	sink := &mockReadingSink{}
+	u := NewFetchAirQUsecase(airqRepo, metricsRepo).WithMaintenance(calendar).WithSink(sink)
+
+	fetches := []struct {
+		at          time.Duration
+		maintenance bool
+		updates     int
+	}{
+		{0, false, 1},
+		{5 * time.Minute, false, 1},
+		{12 * time.Minute, true, 2},
+		{15 * time.Minute, true, 2},
+		{25 * time.Minute, false, 3},
+	}
+	for _, f := range fetches {
+		airqRepo.data = &entity.AirQuality{Device: "dev-1", CO2: 700, UpdateTime: base, FetchedAt: base.Add(f.at)}
+		if _, err := u.Execute(context.Background()); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if metricsRepo.updateCount != f.updates || metricsRepo.updatedData.Maintenance != f.maintenance {
+			t.Errorf("fetch at +%s: expected %d updates with maintenance %v, got %d with %v",
+				f.at, f.updates, f.maintenance, metricsRepo.updateCount, metricsRepo.updatedData.Maintenance)
+		}
+	}
+	if len(sink.published) != 1 || sink.published[0].Maintenance {
+		t.Errorf("expected the upload to be published once, unchanged, got %+v", sink.published)
+	}
+}
@@ -0,0 +1,155 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+)
+
+// maxRecurringMaintenance bounds the duration of recurring maintenance windows,
+// which are looked up minute by minute
+const maxRecurringMaintenance = 7 * 24 * time.Hour
+
+// ErrMaintenanceWindowNotFound is returned when removing an unknown window
+var ErrMaintenanceWindowNotFound = errors.New("maintenance window not found")
+
+// MaintenanceCalendar holds the maintenance windows of every device, recurring
+// ones from the configuration and one-off ones added at runtime, and tells
+// whether a reading was taken during one of them
+type MaintenanceCalendar struct {
+	location *time.Location
+	now      func() time.Time
+
+	mu      sync.Mutex
+	windows []*maintenanceWindow
+	lastID  int
+}
+
+// maintenanceWindow is a window with its parsed schedule
+type maintenanceWindow struct {
+	entity.MaintenanceWindow
+	schedule *CronSchedule
+}
+
+// NewMaintenanceCalendar creates a new empty MaintenanceCalendar evaluating
+// schedules in the given time zone
+func NewMaintenanceCalendar(location *time.Location) *MaintenanceCalendar {
+	if location == nil {
+		location = time.UTC
+	}
+	return &MaintenanceCalendar{
+		location: location,
+		now:      time.Now,
+	}
+}
+
+// ValidateMaintenanceWindow checks a window: recurring windows need a valid
+// schedule and a duration, one-off windows an end after their start
+func ValidateMaintenanceWindow(window entity.MaintenanceWindow) error {
+	if !window.Recurring() {
+		if window.Start.IsZero() || !window.End.After(window.Start) {
+			return errors.New("a one-off window needs an end after its start")
+		}
+		if window.Duration != 0 {
+			return errors.New("a one-off window has no duration")
+		}
+		return nil
+	}
+
+	if _, err := ParseCronSchedule(window.Schedule); err != nil {
+		return err
+	}
+	duration := time.Duration(window.Duration * float64(time.Second))
+	if duration < time.Minute || duration > maxRecurringMaintenance {
+		return fmt.Errorf("duration of a recurring window must be between 1m and %s", maxRecurringMaintenance)
+	}
+	if !window.Start.IsZero() || !window.End.IsZero() {
+		return errors.New("a recurring window has no start or end")
+	}
+	return nil
+}
+
+// Add validates a window and adds it under a new ID, which is returned with it
+func (c *MaintenanceCalendar) Add(window entity.MaintenanceWindow) (entity.MaintenanceWindow, error) {
+	if err := ValidateMaintenanceWindow(window); err != nil {
+		return entity.MaintenanceWindow{}, err
+	}
+	w := &maintenanceWindow{MaintenanceWindow: window}
+	if window.Recurring() {
+		w.schedule, _ = ParseCronSchedule(window.Schedule)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastID++
+	w.ID = strconv.Itoa(c.lastID)
+	w.Active = false
+	c.windows = append(c.windows, w)
+	return w.MaintenanceWindow, nil
+}
+
+// Remove removes the window with the given ID
+func (c *MaintenanceCalendar) Remove(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, w := range c.windows {
+		if w.ID == id {
+			c.windows = append(c.windows[:i], c.windows[i+1:]...)
+			return nil
+		}
+	}
+	return ErrMaintenanceWindowNotFound
+}
+
+// List returns the current and upcoming windows in the order they were added,
+// marking those in effect now. One-off windows are dropped once they ended.
+func (c *MaintenanceCalendar) List() []entity.MaintenanceWindow {
+	now := c.now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	kept := c.windows[:0]
+	for _, w := range c.windows {
+		if w.Recurring() || w.End.After(now) {
+			kept = append(kept, w)
+		}
+	}
+	c.windows = kept
+
+	windows := make([]entity.MaintenanceWindow, 0, len(c.windows))
+	for _, w := range c.windows {
+		window := w.MaintenanceWindow
+		window.Active = w.covers(now, c.location)
+		windows = append(windows, window)
+	}
+	return windows
+}
+
+// Active reports whether the reading was taken during a window of its device
+func (c *MaintenanceCalendar) Active(data *entity.AirQuality) bool {
+	return c.ActiveAt(data, data.Timestamp())
+}
+
+// ActiveAt reports whether a window of the reading's device is in effect at ts
+func (c *MaintenanceCalendar) ActiveAt(data *entity.AirQuality, ts time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, w := range c.windows {
+		if (w.Device == "" || data.MatchesDevice([]string{w.Device})) && w.covers(ts, c.location) {
+			return true
+		}
+	}
+	return false
+}
+
+// covers reports whether the window is in effect at t
+func (w *maintenanceWindow) covers(t time.Time, location *time.Location) bool {
+	if w.schedule != nil {
+		return w.schedule.Within(t.In(location), time.Duration(w.Duration*float64(time.Second)))
+	}
+	return !t.Before(w.Start) && t.Before(w.End)
+}
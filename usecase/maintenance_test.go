@@ -0,0 +1,158 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+)
+
+func TestParseCronSchedule(t *testing.T) {
+	// Monday 5 January 2026, 09:30 UTC
+	monday := time.Date(2026, 1, 5, 9, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr    string
+		at      time.Time
+		matches bool
+	}{
+		{"30 9 * * *", monday, true},
+		{"30 9 * * mon-fri", monday, true},
+		{"30 9 * * sat,sun", monday, false},
+		{"*/15 9-17 * * 1", monday, true},
+		{"*/20 * * * *", monday, false},
+		{"30 9 1 * *", monday, false},
+		// Day of month or day of week when both are restricted
+		{"30 9 1 * mon", monday, true},
+		{"30 9 5 jan *", monday, true},
+		{"30 9 * feb *", monday, false},
+		{"0 0 * * 7", time.Date(2026, 1, 4, 0, 0, 0, 0, time.UTC), true},
+	}
+	for _, tt := range tests {
+		schedule, err := ParseCronSchedule(tt.expr)
+		if err != nil {
+			t.Errorf("expected %q to parse, got %v", tt.expr, err)
+			continue
+		}
+		if got := schedule.Matches(tt.at); got != tt.matches {
+			t.Errorf("expected %q to match %v: %v, got %v", tt.expr, tt.at, tt.matches, got)
+		}
+	}
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * funday", "5-1 * * * *", "*/0 * * * *"} {
+		if _, err := ParseCronSchedule(expr); err == nil {
+			t.Errorf("expected %q to be rejected", expr)
+		}
+	}
+}
+
+func TestCronSchedule_Within(t *testing.T) {
+	// Sunday 4 January 2026, 12:07 UTC
+	at := time.Date(2026, 1, 4, 12, 7, 30, 0, time.UTC)
+
+	tests := []struct {
+		expr     string
+		d        time.Duration
+		expected bool
+	}{
+		{"7 12 * * *", time.Minute, true},
+		{"8 12 * * *", time.Hour, false},
+		{"0 12 * * *", 7 * time.Minute, false},
+		{"0 12 * * *", 8 * time.Minute, true},
+		{"59 23 * * *", 12*time.Hour + 8*time.Minute, false},
+		{"59 23 * * *", 12*time.Hour + 9*time.Minute, true},
+		{"30 9 * * wed", 5 * 24 * time.Hour, true},
+		{"30 9 * * wed", 4 * 24 * time.Hour, false},
+		{"0 0 1 * *", 4 * 24 * time.Hour, true},
+		{"0 0 1 jun *", 7 * 24 * time.Hour, false},
+	}
+	for _, tt := range tests {
+		schedule, err := ParseCronSchedule(tt.expr)
+		if err != nil {
+			t.Fatalf("expected %q to parse, got %v", tt.expr, err)
+		}
+		if got := schedule.Within(at, tt.d); got != tt.expected {
+			t.Errorf("expected %q within %v of %v: %v, got %v", tt.expr, tt.d, at, tt.expected, got)
+		}
+	}
+}
+
+func TestMaintenanceCalendar_Active(t *testing.T) {
+	loc := time.FixedZone("JST", 9*60*60)
+	calendar := NewMaintenanceCalendar(loc)
+	base := time.Date(2026, 1, 7, 9, 0, 0, 0, loc)
+
+	// Cleaning every Wednesday from 09:00 local time for two hours
+	if _, err := calendar.Add(entity.MaintenanceWindow{Device: "Office", Schedule: "0 9 * * wed", Duration: 7200}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	// Recalibration of another device the next day
+	if _, err := calendar.Add(entity.MaintenanceWindow{Device: "dev-2", Start: base.Add(24 * time.Hour), End: base.Add(25 * time.Hour)}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		data     *entity.AirQuality
+		expected bool
+	}{
+		{"start of the recurring window", &entity.AirQuality{Device: "dev-1", Nickname: "Office", UpdateTime: base}, true},
+		{"end of the recurring window", &entity.AirQuality{Device: "dev-1", Nickname: "Office", UpdateTime: base.Add(2*time.Hour - time.Second)}, true},
+		{"after the recurring window", &entity.AirQuality{Device: "dev-1", Nickname: "Office", UpdateTime: base.Add(2 * time.Hour)}, false},
+		{"before the recurring window", &entity.AirQuality{Device: "dev-1", Nickname: "Office", UpdateTime: base.Add(-time.Minute)}, false},
+		{"other device", &entity.AirQuality{Device: "dev-2", UpdateTime: base}, false},
+		{"one-off window", &entity.AirQuality{Device: "dev-2", UpdateTime: base.Add(24*time.Hour + 30*time.Minute)}, true},
+	}
+	for _, tt := range tests {
+		if got := calendar.Active(tt.data); got != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestMaintenanceCalendar_ListAndRemove(t *testing.T) {
+	now := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	calendar := NewMaintenanceCalendar(time.UTC)
+	calendar.now = func() time.Time { return now }
+
+	ended, _ := calendar.Add(entity.MaintenanceWindow{Start: now.Add(-2 * time.Hour), End: now.Add(-time.Hour)})
+	current, _ := calendar.Add(entity.MaintenanceWindow{Device: "Office", Start: now.Add(-time.Minute), End: now.Add(time.Hour)})
+	recurring, _ := calendar.Add(entity.MaintenanceWindow{Schedule: "0 3 * * *", Duration: 3600, Reason: "nightly"})
+
+	windows := calendar.List()
+	if len(windows) != 2 || windows[0].ID != current.ID || windows[1].ID != recurring.ID {
+		t.Fatalf("expected the current and recurring windows, got %+v", windows)
+	}
+	if !windows[0].Active || windows[1].Active {
+		t.Errorf("expected only the current window to be active, got %+v", windows)
+	}
+
+	if err := calendar.Remove(current.ID); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := calendar.Remove(ended.ID); !errors.Is(err, ErrMaintenanceWindowNotFound) {
+		t.Errorf("expected ErrMaintenanceWindowNotFound, got %v", err)
+	}
+	if windows := calendar.List(); len(windows) != 1 || windows[0].ID != recurring.ID {
+		t.Errorf("expected the recurring window, got %+v", windows)
+	}
+}
+
+func TestValidateMaintenanceWindow(t *testing.T) {
+	now := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	invalid := []entity.MaintenanceWindow{
+		{},
+		{Start: now, End: now},
+		{Start: now, End: now.Add(time.Hour), Duration: 60},
+		{Schedule: "0 9 * * *"},
+		{Schedule: "0 9 * * *", Duration: 30 * 24 * 3600},
+		{Schedule: "0 9 * *", Duration: 3600},
+		{Schedule: "0 9 * * *", Duration: 3600, Start: now},
+	}
+	for _, window := range invalid {
+		if err := ValidateMaintenanceWindow(window); err == nil {
+			t.Errorf("expected %+v to be rejected", window)
+		}
+	}
+}
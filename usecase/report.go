@@ -0,0 +1,289 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+	"github.com/suzutan/m5stack_airq_exporter/domain/repository"
+)
+
+// reportMaxHold is the longest time a reading is assumed to hold; longer gaps
+// between uploads count as not covered
+const reportMaxHold = 15 * time.Minute
+
+// whoPM25Guideline is the WHO 2021 24-hour PM2.5 guideline in µg/m³
+const whoPM25Guideline = 15
+
+// ComfortBand is the comfortable range of a measurement
+type ComfortBand struct {
+	Name  string
+	Field string
+	Min   float64
+	Max   float64
+}
+
+// ReportConfig holds the configuration of exposure reports
+type ReportConfig struct {
+	// Location defines the local days and weeks reports cover
+	Location      *time.Location
+	CO2Thresholds []float64
+	PM25Guideline float64
+	ComfortBands  []ComfortBand
+	// ExcludeMaintenance leaves out readings taken during maintenance windows;
+	// beyond the usual hold of the reading before them, the time they cover
+	// counts as not covered
+	ExcludeMaintenance bool
+}
+
+// DefaultReportConfig returns the default report configuration
+func DefaultReportConfig() ReportConfig {
+	return ReportConfig{
+		Location:      time.UTC,
+		CO2Thresholds: []float64{1000, 1500},
+		PM25Guideline: whoPM25Guideline,
+		ComfortBands: []ComfortBand{
+			{Name: "temperature", Field: "temperature", Min: 20, Max: 26},
+			{Name: "humidity", Field: "humidity", Min: 30, Max: 60},
+			{Name: "co2", Field: "co2", Min: 0, Max: 1000},
+		},
+	}
+}
+
+// ReportUsecase generates daily and weekly exposure reports from the history
+type ReportUsecase struct {
+	history   repository.HistoryRepository
+	config    ReportConfig
+	publisher repository.ReportPublisher
+	now       func() time.Time
+}
+
+// NewReportUsecase creates a new ReportUsecase reading from the given history
+func NewReportUsecase(history repository.HistoryRepository, config ReportConfig) *ReportUsecase {
+	if config.Location == nil {
+		config.Location = time.UTC
+	}
+	return &ReportUsecase{
+		history: history,
+		config:  config,
+		now:     time.Now,
+	}
+}
+
+// WithPublisher enables delivering reports through the given publisher
+func (u *ReportUsecase) WithPublisher(publisher repository.ReportPublisher) *ReportUsecase {
+	u.publisher = publisher
+	return u
+}
+
+// Location returns the time zone reports are generated in
+func (u *ReportUsecase) Location() *time.Location {
+	return u.config.Location
+}
+
+// ParseReportPeriod parses a report period name
+func ParseReportPeriod(value string) (entity.ReportPeriod, error) {
+	switch p := entity.ReportPeriod(value); p {
+	case entity.ReportPeriodDaily, entity.ReportPeriodWeekly:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown report period: %s", value)
+	}
+}
+
+// ParseCO2Thresholds parses a comma-separated list of CO2 thresholds in ppm
+func ParseCO2Thresholds(value string) ([]float64, error) {
+	var thresholds []float64
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		t, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CO2 threshold %q: %w", part, err)
+		}
+		thresholds = append(thresholds, t)
+	}
+	sort.Float64s(thresholds)
+	return thresholds, nil
+}
+
+// PeriodContaining returns the local day or week, starting on Monday, that contains t
+func PeriodContaining(period entity.ReportPeriod, t time.Time, loc *time.Location) (time.Time, time.Time) {
+	t = t.In(loc)
+	from := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	if period == entity.ReportPeriodWeekly {
+		offset := (int(from.Weekday()) + 6) % 7
+		from = from.AddDate(0, 0, -offset)
+		return from, from.AddDate(0, 0, 7)
+	}
+	return from, from.AddDate(0, 0, 1)
+}
+
+// Generate returns the report of the last period completed at the given time
+func (u *ReportUsecase) Generate(period entity.ReportPeriod, at time.Time) *entity.Report {
+	current, _ := PeriodContaining(period, at, u.config.Location)
+	from, to := PeriodContaining(period, current.Add(-time.Nanosecond), u.config.Location)
+	return u.GenerateRange(period, from, to)
+}
+
+// GenerateRange returns the report of every device over [from, to)
+func (u *ReportUsecase) GenerateRange(period entity.ReportPeriod, from, to time.Time) *entity.Report {
+	byDevice := make(map[string][]*entity.AirQuality)
+	var devices []string
+	for _, data := range u.history.Range(from, to) {
+		if !data.Timestamp().Before(to) || data.Maintenance && u.config.ExcludeMaintenance {
+			continue
+		}
+		if _, ok := byDevice[data.Device]; !ok {
+			devices = append(devices, data.Device)
+		}
+		byDevice[data.Device] = append(byDevice[data.Device], data)
+	}
+	sort.Strings(devices)
+
+	report := &entity.Report{
+		Period:      period,
+		From:        from,
+		To:          to,
+		GeneratedAt: u.now(),
+		Devices:     []entity.DeviceReport{},
+	}
+	for _, device := range devices {
+		report.Devices = append(report.Devices, u.deviceReport(byDevice[device], from, to))
+	}
+	return report
+}
+
+// Deliver generates the report of the last completed period and publishes it
+func (u *ReportUsecase) Deliver(ctx context.Context, period entity.ReportPeriod) error {
+	if u.publisher == nil {
+		return errors.New("no report publisher configured")
+	}
+	report := u.Generate(period, u.now())
+	if err := u.publisher.Publish(ctx, report); err != nil {
+		return fmt.Errorf("failed to publish %s report: %w", period, err)
+	}
+	return nil
+}
+
+// deviceReport summarises the readings of one device, oldest first. Every reading
+// holds until the next one, for at most reportMaxHold.
+func (u *ReportUsecase) deviceReport(readings []*entity.AirQuality, from, to time.Time) entity.DeviceReport {
+	last := readings[len(readings)-1]
+	report := entity.DeviceReport{
+		Device:   last.Device,
+		Nickname: last.Nickname,
+		Readings: len(readings),
+	}
+
+	co2Above := make([]time.Duration, len(u.config.CO2Thresholds))
+	inBand := make([]time.Duration, len(u.config.ComfortBands))
+	var inAllBands, covered time.Duration
+	var pm25Weighted, pm25Sum float64
+	var pm25Covered time.Duration
+	var pm25Readings int
+	dayPM25 := make(map[time.Time][2]float64)
+
+	// Peaks of the fields the data source provides, by field
+	peaks := make(map[string]*entity.Peak)
+
+	for i, data := range readings {
+		ts := data.Timestamp()
+		until := to
+		if i+1 < len(readings) {
+			until = readings[i+1].Timestamp()
+		}
+		hold := min(until.Sub(ts), reportMaxHold)
+		covered += hold
+
+		for j, t := range u.config.CO2Thresholds {
+			if data.Measures("co2") && float64(data.CO2) > t {
+				co2Above[j] += hold
+			}
+		}
+
+		all := true
+		for j, band := range u.config.ComfortBands {
+			f, ok := entity.FieldByKey(band.Field)
+			if !ok || !data.Measures(f.Key) {
+				continue
+			}
+			if v := f.Value(data); v >= band.Min && v <= band.Max {
+				inBand[j] += hold
+			} else {
+				all = false
+			}
+		}
+		if all {
+			inAllBands += hold
+		}
+
+		if data.Measures("pm2_5") {
+			pm25Weighted += data.PM2_5 * hold.Hours()
+			pm25Sum += data.PM2_5
+			pm25Covered += hold
+			pm25Readings++
+			day, _ := PeriodContaining(entity.ReportPeriodDaily, ts, u.config.Location)
+			d := dayPM25[day]
+			dayPM25[day] = [2]float64{d[0] + data.PM2_5*hold.Hours(), d[1] + hold.Hours()}
+		}
+
+		for _, f := range entity.Fields {
+			if !data.Measures(f.Key) {
+				continue
+			}
+			if peak, ok := peaks[f.Key]; !ok || f.Value(data) > peak.Value {
+				peaks[f.Key] = &entity.Peak{Field: f.Key, Unit: f.Unit, Value: f.Value(data), Time: ts}
+			}
+		}
+	}
+
+	report.CoveredHours = covered.Hours()
+	for j, t := range u.config.CO2Thresholds {
+		report.CO2 = append(report.CO2, entity.ThresholdExposure{Threshold: t, Hours: co2Above[j].Hours()})
+	}
+
+	report.PM25 = entity.PM25Summary{Guideline: u.config.PM25Guideline}
+	if pm25Covered > 0 {
+		report.PM25.Mean = pm25Weighted / pm25Covered.Hours()
+	} else if pm25Readings > 0 {
+		report.PM25.Mean = pm25Sum / float64(pm25Readings)
+	}
+	for _, d := range dayPM25 {
+		if d[1] == 0 {
+			continue
+		}
+		report.PM25.Days++
+		if d[0]/d[1] > u.config.PM25Guideline {
+			report.PM25.DaysAboveGuideline++
+		}
+	}
+
+	for _, f := range entity.Fields {
+		if peak, ok := peaks[f.Key]; ok {
+			report.Peaks = append(report.Peaks, *peak)
+		}
+	}
+	for j, band := range u.config.ComfortBands {
+		report.Comfort = append(report.Comfort, entity.ComfortShare{
+			Name: band.Name, Field: band.Field, Min: &band.Min, Max: &band.Max,
+			Percent: percentOf(inBand[j], covered),
+		})
+	}
+	report.Comfort = append(report.Comfort, entity.ComfortShare{Name: "all", Percent: percentOf(inAllBands, covered)})
+	return report
+}
+
+func percentOf(part, total time.Duration) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return 100 * float64(part) / float64(total)
+}
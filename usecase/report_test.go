@@ -0,0 +1,171 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+)
+
+// mockReportPublisher is a mock implementation of ReportPublisher for testing
+type mockReportPublisher struct {
+	published []*entity.Report
+	err       error
+}
+
+func (m *mockReportPublisher) Publish(ctx context.Context, report *entity.Report) error {
+	m.published = append(m.published, report)
+	return m.err
+}
+
+func newReportHistory(day time.Time) *mockHistoryRepository {
+	var readings []*entity.AirQuality
+	// Every 10 minutes from 09:00 to 11:00: one hour at 1200 ppm, one hour at 800 ppm
+	for i := range 12 {
+		data := &entity.AirQuality{
+			Device: "dev-1", Nickname: "Office",
+			CO2: 1200, PM2_5: 30, Temperature: 22, Humidity: 45,
+			UpdateTime: day.Add(9*time.Hour + time.Duration(i)*10*time.Minute),
+		}
+		if i >= 6 {
+			data.CO2 = 800
+			data.PM2_5 = 10
+		}
+		readings = append(readings, data)
+	}
+	readings[3].CO2 = 1600
+	return &mockHistoryRepository{readings: readings}
+}
+
+func TestReportUsecase_Generate(t *testing.T) {
+	day := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	u := NewReportUsecase(newReportHistory(day), DefaultReportConfig())
+
+	report := u.Generate(entity.ReportPeriodDaily, day.Add(30*time.Hour))
+	if !report.From.Equal(day) || !report.To.Equal(day.Add(24*time.Hour)) {
+		t.Errorf("unexpected period %v - %v", report.From, report.To)
+	}
+	if len(report.Devices) != 1 {
+		t.Fatalf("expected 1 device, got %d", len(report.Devices))
+	}
+
+	d := report.Devices[0]
+	if d.Readings != 12 || d.Nickname != "Office" {
+		t.Errorf("unexpected device summary: %+v", d)
+	}
+	// The last reading holds for at most 15 minutes
+	if math.Abs(d.CoveredHours-(110.0+15)/60) > 1e-9 {
+		t.Errorf("expected covered hours %f, got %f", (110.0+15)/60, d.CoveredHours)
+	}
+	if d.CO2[0].Threshold != 1000 || d.CO2[0].Hours != 1 {
+		t.Errorf("expected 1h above 1000 ppm, got %+v", d.CO2[0])
+	}
+	if d.CO2[1].Threshold != 1500 || math.Abs(d.CO2[1].Hours-10.0/60) > 1e-9 {
+		t.Errorf("expected 10m above 1500 ppm, got %+v", d.CO2[1])
+	}
+
+	if d.PM25.Days != 1 || d.PM25.DaysAboveGuideline != 1 || d.PM25.Guideline != 15 {
+		t.Errorf("unexpected PM2.5 summary: %+v", d.PM25)
+	}
+
+	for _, p := range d.Peaks {
+		if p.Field == "co2" && (p.Value != 1600 || !p.Time.Equal(day.Add(9*time.Hour+30*time.Minute))) {
+			t.Errorf("unexpected CO2 peak: %+v", p)
+		}
+	}
+
+	for _, c := range d.Comfort {
+		switch c.Name {
+		case "temperature":
+			if c.Percent != 100 {
+				t.Errorf("expected 100%% in temperature band, got %f", c.Percent)
+			}
+		case "co2", "all":
+			if expected := 100 * (50.0 + 15) / (110 + 15); math.Abs(c.Percent-expected) > 1e-9 {
+				t.Errorf("expected %f%% in %s band, got %f", expected, c.Name, c.Percent)
+			}
+		}
+		// A band starting at zero keeps its min
+		if c.Name == "co2" && (c.Min == nil || *c.Min != 0 || c.Max == nil || *c.Max != 1000) {
+			t.Errorf("expected the CO2 band 0 - 1000, got %+v", c)
+		}
+		if c.Name == "all" && (c.Min != nil || c.Max != nil) {
+			t.Errorf("expected no band for all, got %+v", c)
+		}
+	}
+}
+
+func TestReportUsecase_Generate_ExcludeMaintenance(t *testing.T) {
+	day := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	history := newReportHistory(day)
+	// Cleaning from 10:00 to 11:00
+	for _, data := range history.readings[6:] {
+		data.Maintenance = true
+	}
+	config := DefaultReportConfig()
+	config.ExcludeMaintenance = true
+
+	d := NewReportUsecase(history, config).Generate(entity.ReportPeriodDaily, day.Add(30*time.Hour)).Devices[0]
+	if d.Readings != 6 {
+		t.Errorf("expected 6 readings, got %d", d.Readings)
+	}
+	// The last reading before the window holds for at most 15 minutes
+	if math.Abs(d.CoveredHours-(50.0+15)/60) > 1e-9 {
+		t.Errorf("expected covered hours %f, got %f", (50.0+15)/60, d.CoveredHours)
+	}
+	if math.Abs(d.PM25.Mean-30) > 1e-9 {
+		t.Errorf("expected a PM2.5 mean of 30, got %f", d.PM25.Mean)
+	}
+}
+
+func TestReportUsecase_Generate_Weekly(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*60*60)
+	config := DefaultReportConfig()
+	config.Location = tokyo
+	u := NewReportUsecase(&mockHistoryRepository{}, config)
+
+	// Wednesday 2026-01-14 in Tokyo: the last completed week is 5-12 January
+	report := u.Generate(entity.ReportPeriodWeekly, time.Date(2026, 1, 14, 3, 0, 0, 0, time.UTC))
+	if !report.From.Equal(time.Date(2026, 1, 5, 0, 0, 0, 0, tokyo)) || !report.To.Equal(time.Date(2026, 1, 12, 0, 0, 0, 0, tokyo)) {
+		t.Errorf("unexpected period %v - %v", report.From, report.To)
+	}
+	if report.Devices == nil || len(report.Devices) != 0 {
+		t.Errorf("expected an empty device list, got %v", report.Devices)
+	}
+}
+
+func TestReportUsecase_Deliver(t *testing.T) {
+	day := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	publisher := &mockReportPublisher{}
+	u := NewReportUsecase(newReportHistory(day), DefaultReportConfig()).WithPublisher(publisher)
+	u.now = func() time.Time { return day.Add(31 * time.Hour) }
+
+	if err := u.Deliver(context.Background(), entity.ReportPeriodDaily); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(publisher.published) != 1 || len(publisher.published[0].Devices) != 1 {
+		t.Errorf("expected the daily report to be published, got %+v", publisher.published)
+	}
+
+	publisher.err = errors.New("connection refused")
+	if err := u.Deliver(context.Background(), entity.ReportPeriodDaily); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestParseCO2Thresholds(t *testing.T) {
+	thresholds, err := ParseCO2Thresholds("1500, 800,1000")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(thresholds) != 3 || thresholds[0] != 800 || thresholds[2] != 1500 {
+		t.Errorf("expected sorted thresholds, got %v", thresholds)
+	}
+
+	if _, err := ParseCO2Thresholds("high"); err == nil {
+		t.Error("expected error for invalid threshold, got nil")
+	}
+}
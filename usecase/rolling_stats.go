@@ -0,0 +1,191 @@
+package usecase
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+	"github.com/suzutan/m5stack_airq_exporter/domain/repository"
+)
+
+// DefaultRollingWindows are the windows of the comfort guidelines most readings are compared against
+var DefaultRollingWindows = []time.Duration{time.Hour, 8 * time.Hour, 24 * time.Hour}
+
+// RollingStat holds the aggregates of one measurement of one device over one window
+type RollingStat struct {
+	Device string
+	Field  entity.Field
+	Window time.Duration
+	Avg    float64
+	Min    float64
+	Max    float64
+}
+
+// RollingStatsUsecase computes rolling aggregates of every measurement from the history
+type RollingStatsUsecase struct {
+	history            repository.HistoryRepository
+	windows            []time.Duration
+	excludeMaintenance bool
+}
+
+// NewRollingStatsUsecase creates a new RollingStatsUsecase over the given windows
+func NewRollingStatsUsecase(history repository.HistoryRepository, windows []time.Duration) *RollingStatsUsecase {
+	return &RollingStatsUsecase{
+		history: history,
+		windows: windows,
+	}
+}
+
+// WithoutMaintenance excludes readings taken during maintenance windows; the
+// reading before a window then holds until the first one after it
+func (u *RollingStatsUsecase) WithoutMaintenance() *RollingStatsUsecase {
+	u.excludeMaintenance = true
+	return u
+}
+
+// Windows returns the configured windows
+func (u *RollingStatsUsecase) Windows() []time.Duration {
+	return u.windows
+}
+
+// Execute returns the aggregates of every measurement of every device for every
+// window that contains at least one reading of the device. Windows end at the
+// device's newest reading, so that the statistics follow the device clock rather
+// than the time of the scrape.
+func (u *RollingStatsUsecase) Execute() []RollingStat {
+	var longest time.Duration
+	for _, w := range u.windows {
+		longest = max(longest, w)
+	}
+
+	var stats []RollingStat
+	for _, latest := range u.history.Latest() {
+		end := latest.Timestamp()
+		if end.IsZero() {
+			continue
+		}
+		var readings []*entity.AirQuality
+		for _, data := range u.history.Range(end.Add(-longest), end) {
+			if data.Device == latest.Device {
+				readings = append(readings, data)
+			}
+		}
+		if u.excludeMaintenance {
+			readings = withoutMaintenance(readings)
+		}
+
+		for _, w := range u.windows {
+			for _, stat := range ComputeRollingStats(readings, end.Add(-w), end, w) {
+				stat.Device = latest.Device
+				stats = append(stats, stat)
+			}
+		}
+	}
+	return stats
+}
+
+// ComputeRollingStats aggregates the readings taken within [from, to], oldest first.
+// The average is time-weighted: every reading holds until the next one, so that
+// gaps and irregular upload intervals do not skew it. Window is only used to label
+// the results.
+func ComputeRollingStats(readings []*entity.AirQuality, from, to time.Time, window time.Duration) []RollingStat {
+	var inWindow []*entity.AirQuality
+	for _, data := range readings {
+		if ts := data.Timestamp(); !ts.Before(from) && !ts.After(to) {
+			inWindow = append(inWindow, data)
+		}
+	}
+	if len(inWindow) == 0 {
+		return nil
+	}
+
+	stats := make([]RollingStat, 0, len(entity.Fields))
+	for _, f := range entity.Fields {
+		// Fields the data source does not provide have no statistics
+		var measured []*entity.AirQuality
+		for _, data := range inWindow {
+			if data.Measures(f.Key) {
+				measured = append(measured, data)
+			}
+		}
+		if len(measured) == 0 {
+			continue
+		}
+
+		stat := RollingStat{Field: f, Window: window, Min: math.Inf(1), Max: math.Inf(-1)}
+		var weighted, total, sum float64
+		for i, data := range measured {
+			v := f.Value(data)
+			stat.Min = math.Min(stat.Min, v)
+			stat.Max = math.Max(stat.Max, v)
+			sum += v
+
+			until := to
+			if i+1 < len(measured) {
+				until = measured[i+1].Timestamp()
+			}
+			weight := until.Sub(data.Timestamp()).Seconds()
+			weighted += v * weight
+			total += weight
+		}
+
+		// A single reading, or readings that share one timestamp, have no duration
+		if total > 0 {
+			stat.Avg = weighted / total
+		} else {
+			stat.Avg = sum / float64(len(measured))
+		}
+		stats = append(stats, stat)
+	}
+	return stats
+}
+
+// withoutMaintenance returns the readings not taken during maintenance windows
+func withoutMaintenance(readings []*entity.AirQuality) []*entity.AirQuality {
+	kept := make([]*entity.AirQuality, 0, len(readings))
+	for _, data := range readings {
+		if !data.Maintenance {
+			kept = append(kept, data)
+		}
+	}
+	return kept
+}
+
+// ParseRollingWindows parses a comma-separated list of windows such as "1h,8h,24h".
+// "none" disables rolling statistics.
+func ParseRollingWindows(value string) ([]time.Duration, error) {
+	if strings.TrimSpace(value) == "none" {
+		return nil, nil
+	}
+
+	var windows []time.Duration
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		w, err := time.ParseDuration(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rolling window %q: %w", part, err)
+		}
+		if w <= 0 {
+			return nil, fmt.Errorf("invalid rolling window %q: must be positive", part)
+		}
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
+
+// FormatWindow formats a window as a compact label value such as "1h", "90m" or "24h"
+func FormatWindow(w time.Duration) string {
+	switch {
+	case w%time.Hour == 0:
+		return fmt.Sprintf("%dh", w/time.Hour)
+	case w%time.Minute == 0:
+		return fmt.Sprintf("%dm", w/time.Minute)
+	default:
+		return w.String()
+	}
+}
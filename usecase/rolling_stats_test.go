@@ -0,0 +1,184 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+)
+
+func TestComputeRollingStats_TimeWeighted(t *testing.T) {
+	base := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	readings := []*entity.AirQuality{
+		{CO2: 600, UpdateTime: base},
+		// Held for 45 minutes: a gap in uploads must not shrink its weight
+		{CO2: 1000, UpdateTime: base.Add(15 * time.Minute)},
+		{CO2: 800, UpdateTime: base.Add(time.Hour)},
+	}
+
+	stats := ComputeRollingStats(readings, base, base.Add(time.Hour), time.Hour)
+	if len(stats) != len(entity.Fields) {
+		t.Fatalf("expected %d stats, got %d", len(entity.Fields), len(stats))
+	}
+
+	var co2 RollingStat
+	for _, s := range stats {
+		if s.Field.Key == "co2" {
+			co2 = s
+		}
+	}
+	if co2.Avg != 900 {
+		t.Errorf("expected time-weighted average 900, got %f", co2.Avg)
+	}
+	if co2.Min != 600 || co2.Max != 1000 {
+		t.Errorf("expected min 600 and max 1000, got %f and %f", co2.Min, co2.Max)
+	}
+	if co2.Window != time.Hour {
+		t.Errorf("expected window 1h, got %v", co2.Window)
+	}
+}
+
+func TestComputeRollingStats_SingleReading(t *testing.T) {
+	base := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	readings := []*entity.AirQuality{{CO2: 700, UpdateTime: base}}
+
+	for _, s := range ComputeRollingStats(readings, base.Add(-time.Hour), base, time.Hour) {
+		if s.Field.Key == "co2" && s.Avg != 700 {
+			t.Errorf("expected average 700, got %f", s.Avg)
+		}
+	}
+
+	if stats := ComputeRollingStats(readings, base.Add(time.Minute), base.Add(time.Hour), time.Hour); stats != nil {
+		t.Errorf("expected no stats for an empty window, got %d", len(stats))
+	}
+}
+
+func TestComputeRollingStats_UnmeasuredFields(t *testing.T) {
+	base := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	co2Only := map[string]bool{"co2": true}
+	readings := []*entity.AirQuality{
+		{CO2: 600, UpdateTime: base, Measured: co2Only},
+		{CO2: 1000, UpdateTime: base.Add(30 * time.Minute), Measured: co2Only},
+	}
+
+	stats := ComputeRollingStats(readings, base, base.Add(time.Hour), time.Hour)
+	if len(stats) != 1 || stats[0].Field.Key != "co2" {
+		t.Fatalf("expected only CO2 stats, got %v", stats)
+	}
+	if stats[0].Avg != 800 {
+		t.Errorf("expected average 800, got %f", stats[0].Avg)
+	}
+}
+
+func TestRollingStatsUsecase_Execute(t *testing.T) {
+	base := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	history := &mockHistoryRepository{readings: []*entity.AirQuality{
+		{CO2: 2000, UpdateTime: base.Add(-2 * time.Hour)},
+		{CO2: 600, UpdateTime: base.Add(-30 * time.Minute)},
+		{CO2: 800, UpdateTime: base},
+	}}
+	history.latest = history.readings[2:]
+
+	usecase := NewRollingStatsUsecase(history, []time.Duration{time.Hour, 8 * time.Hour})
+	stats := usecase.Execute()
+	if len(stats) != 2*len(entity.Fields) {
+		t.Fatalf("expected stats for 2 windows, got %d", len(stats))
+	}
+
+	for _, s := range stats {
+		if s.Field.Key != "co2" {
+			continue
+		}
+		switch s.Window {
+		case time.Hour:
+			if s.Max != 800 {
+				t.Errorf("expected 1h max 800, got %f", s.Max)
+			}
+		case 8 * time.Hour:
+			if s.Max != 2000 {
+				t.Errorf("expected 8h max 2000, got %f", s.Max)
+			}
+		}
+	}
+}
+
+func TestRollingStatsUsecase_Execute_PerDevice(t *testing.T) {
+	base := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	history := &mockHistoryRepository{readings: []*entity.AirQuality{
+		{Device: "dev-1", CO2: 600, UpdateTime: base.Add(-40 * time.Minute)},
+		{Device: "dev-2", CO2: 2000, UpdateTime: base.Add(-30 * time.Minute)},
+		{Device: "dev-1", CO2: 800, UpdateTime: base.Add(-20 * time.Minute)},
+		// The clock of dev-2 is behind; its window ends at its own last reading
+		{Device: "dev-2", CO2: 1000, UpdateTime: base.Add(-10 * time.Minute)},
+	}}
+	history.latest = []*entity.AirQuality{history.readings[2], history.readings[3]}
+
+	stats := NewRollingStatsUsecase(history, []time.Duration{time.Hour}).Execute()
+	if len(stats) != 2*len(entity.Fields) {
+		t.Fatalf("expected stats for 2 devices, got %d", len(stats))
+	}
+	expected := map[string][2]float64{"dev-1": {600, 800}, "dev-2": {1000, 2000}}
+	for _, s := range stats {
+		if s.Field.Key != "co2" {
+			continue
+		}
+		if minMax, ok := expected[s.Device]; !ok || s.Min != minMax[0] || s.Max != minMax[1] {
+			t.Errorf("unexpected CO2 stats of %q: %+v", s.Device, s)
+		}
+		delete(expected, s.Device)
+	}
+	if len(expected) != 0 {
+		t.Errorf("expected CO2 stats of every device, missing %v", expected)
+	}
+}
+
+func TestRollingStatsUsecase_WithoutMaintenance(t *testing.T) {
+	base := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	history := &mockHistoryRepository{readings: []*entity.AirQuality{
+		{CO2: 600, UpdateTime: base.Add(-time.Hour)},
+		// Recalibration
+		{CO2: 3000, UpdateTime: base.Add(-30 * time.Minute), Maintenance: true},
+		{CO2: 600, UpdateTime: base},
+	}}
+	history.latest = history.readings[2:]
+
+	for _, s := range NewRollingStatsUsecase(history, []time.Duration{time.Hour}).WithoutMaintenance().Execute() {
+		if s.Field.Key == "co2" && (s.Max != 600 || s.Avg != 600) {
+			t.Errorf("expected the maintenance reading to be excluded, got %+v", s)
+		}
+	}
+}
+
+func TestParseRollingWindows(t *testing.T) {
+	windows, err := ParseRollingWindows("1h, 90m,24h")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(windows) != 3 || windows[1] != 90*time.Minute {
+		t.Errorf("unexpected windows: %v", windows)
+	}
+
+	if windows, err := ParseRollingWindows("none"); err != nil || windows != nil {
+		t.Errorf("expected none to disable windows, got %v, %v", windows, err)
+	}
+
+	for _, value := range []string{"1 hour", "-1h"} {
+		if _, err := ParseRollingWindows(value); err == nil {
+			t.Errorf("%s: expected error, got nil", value)
+		}
+	}
+}
+
+func TestFormatWindow(t *testing.T) {
+	tests := map[time.Duration]string{
+		time.Hour:        "1h",
+		24 * time.Hour:   "24h",
+		90 * time.Minute: "90m",
+		90 * time.Second: "1m30s",
+	}
+	for w, expected := range tests {
+		if got := FormatWindow(w); got != expected {
+			t.Errorf("expected %s, got %s", expected, got)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package usecase
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+)
+
+// WarmUpSignal identifies an observation that indicates the device has restarted
+type WarmUpSignal string
+
+const (
+	// WarmUpSignalCreateTime fires when the reported createTime changes
+	WarmUpSignalCreateTime WarmUpSignal = "create_time"
+	// WarmUpSignalUpdateGap fires when updateTime jumps backwards or further than MaxUpdateGap
+	WarmUpSignalUpdateGap WarmUpSignal = "update_gap"
+	// WarmUpSignalVOCReset fires when the SEN55 VOC index falls back to its initial value of 100
+	WarmUpSignalVOCReset WarmUpSignal = "voc_reset"
+)
+
+// vocIndexInitialValue is the value the SEN55 reports for the VOC index right after power-up
+const vocIndexInitialValue = 100
+
+// WarmUpConfig holds the configuration for the warm-up detector
+type WarmUpConfig struct {
+	// Duration is how long readings are considered unreliable after a restart
+	Duration time.Duration
+	// MaxUpdateGap is the largest expected gap between two device uploads
+	MaxUpdateGap time.Duration
+	// Signals lists the restart signals that are evaluated
+	Signals []WarmUpSignal
+}
+
+// DefaultWarmUpConfig returns the default warm-up detector configuration
+func DefaultWarmUpConfig() WarmUpConfig {
+	return WarmUpConfig{
+		Duration:     30 * time.Minute,
+		MaxUpdateGap: 10 * time.Minute,
+		Signals:      []WarmUpSignal{WarmUpSignalCreateTime, WarmUpSignalUpdateGap},
+	}
+}
+
+// ParseWarmUpSignals parses a comma-separated list of warm-up signals
+func ParseWarmUpSignals(value string) ([]WarmUpSignal, error) {
+	var signals []WarmUpSignal
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		switch s := WarmUpSignal(part); s {
+		case WarmUpSignalCreateTime, WarmUpSignalUpdateGap, WarmUpSignalVOCReset:
+			signals = append(signals, s)
+		default:
+			return nil, fmt.Errorf("unknown warm-up signal: %s", part)
+		}
+	}
+	return signals, nil
+}
+
+// warmUpState holds the last observation for a single device
+type warmUpState struct {
+	createTime time.Time
+	updateTime time.Time
+	voc        int
+	warmUntil  time.Time
+}
+
+// WarmUpDetector detects device restarts and tracks the sensor warm-up window per device
+type WarmUpDetector struct {
+	config  WarmUpConfig
+	signals map[WarmUpSignal]bool
+
+	mu      sync.Mutex
+	devices map[string]*warmUpState
+}
+
+// NewWarmUpDetector creates a new WarmUpDetector with the given configuration
+func NewWarmUpDetector(config WarmUpConfig) *WarmUpDetector {
+	signals := make(map[WarmUpSignal]bool, len(config.Signals))
+	for _, s := range config.Signals {
+		signals[s] = true
+	}
+	return &WarmUpDetector{
+		config:  config,
+		signals: signals,
+		devices: make(map[string]*warmUpState),
+	}
+}
+
+// Observe records the reading and reports whether the device is within its warm-up window.
+// The first reading of a device only establishes a baseline, as the exporter cannot tell
+// how long the device has already been running.
+func (d *WarmUpDetector) Observe(data *entity.AirQuality) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := readingTime(data)
+
+	state, ok := d.devices[data.Device]
+	if !ok {
+		d.devices[data.Device] = &warmUpState{
+			createTime: data.CreateTime,
+			updateTime: data.UpdateTime,
+			voc:        data.VOC,
+		}
+		return false
+	}
+
+	if d.restarted(state, data) {
+		state.warmUntil = now.Add(d.config.Duration)
+	}
+
+	state.createTime = data.CreateTime
+	state.updateTime = data.UpdateTime
+	state.voc = data.VOC
+
+	return now.Before(state.warmUntil)
+}
+
+// restarted reports whether any enabled signal indicates a restart since the previous reading
+func (d *WarmUpDetector) restarted(prev *warmUpState, data *entity.AirQuality) bool {
+	if d.signals[WarmUpSignalCreateTime] &&
+		!prev.createTime.IsZero() && !data.CreateTime.IsZero() &&
+		!prev.createTime.Equal(data.CreateTime) {
+		return true
+	}
+
+	if d.signals[WarmUpSignalUpdateGap] &&
+		!prev.updateTime.IsZero() && !data.UpdateTime.IsZero() {
+		gap := data.UpdateTime.Sub(prev.updateTime)
+		if gap < 0 || gap > d.config.MaxUpdateGap {
+			return true
+		}
+	}
+
+	if d.signals[WarmUpSignalVOCReset] &&
+		prev.voc != vocIndexInitialValue && data.VOC == vocIndexInitialValue {
+		return true
+	}
+
+	return false
+}
+
+// readingTime returns the best known time the reading was taken
+func readingTime(data *entity.AirQuality) time.Time {
+	if ts := data.Timestamp(); !ts.IsZero() {
+		return ts
+	}
+	return time.Now()
+}
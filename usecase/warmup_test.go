@@ -0,0 +1,147 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/suzutan/m5stack_airq_exporter/domain/entity"
+)
+
+func newReading(createTime, updateTime time.Time, voc int) *entity.AirQuality {
+	return &entity.AirQuality{
+		Device:     "device-1",
+		CreateTime: createTime,
+		UpdateTime: updateTime,
+		VOC:        voc,
+	}
+}
+
+func TestWarmUpDetector_FirstReadingIsBaseline(t *testing.T) {
+	detector := NewWarmUpDetector(DefaultWarmUpConfig())
+	base := time.Unix(1767573960, 0)
+
+	if detector.Observe(newReading(base, base, 100)) {
+		t.Error("expected first reading not to be marked as warming up")
+	}
+}
+
+func TestWarmUpDetector_UpdateGap(t *testing.T) {
+	config := DefaultWarmUpConfig()
+	detector := NewWarmUpDetector(config)
+	created := time.Unix(1703591914, 0)
+	base := time.Unix(1767573960, 0)
+
+	detector.Observe(newReading(created, base, 80))
+	if detector.Observe(newReading(created, base.Add(time.Minute), 80)) {
+		t.Error("expected regular upload not to be marked as warming up")
+	}
+
+	// Power cut: the next upload arrives after a long gap
+	restart := base.Add(2 * time.Hour)
+	if !detector.Observe(newReading(created, restart, 80)) {
+		t.Error("expected reading after update gap to be marked as warming up")
+	}
+	for elapsed := time.Minute; elapsed < config.Duration; elapsed += time.Minute {
+		if !detector.Observe(newReading(created, restart.Add(elapsed), 80)) {
+			t.Fatalf("expected reading %v after restart to be marked as warming up", elapsed)
+		}
+	}
+	if detector.Observe(newReading(created, restart.Add(config.Duration), 80)) {
+		t.Error("expected reading after warm-up window not to be marked as warming up")
+	}
+}
+
+func TestWarmUpDetector_UpdateTimeGoesBackwards(t *testing.T) {
+	detector := NewWarmUpDetector(DefaultWarmUpConfig())
+	created := time.Unix(1703591914, 0)
+	base := time.Unix(1767573960, 0)
+
+	detector.Observe(newReading(created, base, 80))
+	if !detector.Observe(newReading(created, base.Add(-time.Hour), 80)) {
+		t.Error("expected reading with earlier updateTime to be marked as warming up")
+	}
+}
+
+func TestWarmUpDetector_CreateTimeChanged(t *testing.T) {
+	detector := NewWarmUpDetector(DefaultWarmUpConfig())
+	base := time.Unix(1767573960, 0)
+
+	detector.Observe(newReading(time.Unix(1703591914, 0), base, 80))
+	if !detector.Observe(newReading(time.Unix(1767573950, 0), base.Add(time.Minute), 80)) {
+		t.Error("expected reading with new createTime to be marked as warming up")
+	}
+}
+
+func TestWarmUpDetector_VOCReset(t *testing.T) {
+	created := time.Unix(1703591914, 0)
+	base := time.Unix(1767573960, 0)
+
+	// Disabled by default
+	detector := NewWarmUpDetector(DefaultWarmUpConfig())
+	detector.Observe(newReading(created, base, 80))
+	if detector.Observe(newReading(created, base.Add(time.Minute), 100)) {
+		t.Error("expected VOC reset to be ignored when the signal is disabled")
+	}
+
+	config := DefaultWarmUpConfig()
+	config.Signals = []WarmUpSignal{WarmUpSignalVOCReset}
+	detector = NewWarmUpDetector(config)
+	detector.Observe(newReading(created, base, 80))
+	if !detector.Observe(newReading(created, base.Add(time.Minute), 100)) {
+		t.Error("expected VOC reset to be marked as warming up")
+	}
+}
+
+func TestWarmUpDetector_DevicesAreIndependent(t *testing.T) {
+	detector := NewWarmUpDetector(DefaultWarmUpConfig())
+	created := time.Unix(1703591914, 0)
+	base := time.Unix(1767573960, 0)
+
+	detector.Observe(newReading(created, base, 80))
+	other := newReading(created, base.Add(2*time.Hour), 80)
+	other.Device = "device-2"
+
+	if detector.Observe(other) {
+		t.Error("expected first reading of another device not to be marked as warming up")
+	}
+}
+
+func TestParseWarmUpSignals(t *testing.T) {
+	signals, err := ParseWarmUpSignals("create_time, voc_reset")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(signals) != 2 || signals[0] != WarmUpSignalCreateTime || signals[1] != WarmUpSignalVOCReset {
+		t.Errorf("unexpected signals: %v", signals)
+	}
+
+	if _, err := ParseWarmUpSignals("uptime"); err == nil {
+		t.Error("expected error for unknown signal, got nil")
+	}
+}
+
+func TestFetchAirQUsecase_Execute_MarksWarmingUp(t *testing.T) {
+	created := time.Unix(1703591914, 0)
+	base := time.Unix(1767573960, 0)
+
+	airqRepo := &mockAirQRepository{data: newReading(created, base, 80)}
+	metricsRepo := &mockMetricsRepository{}
+	usecase := NewFetchAirQUsecase(airqRepo, metricsRepo).
+		WithWarmUpDetector(NewWarmUpDetector(DefaultWarmUpConfig()))
+
+	if _, err := usecase.Execute(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if metricsRepo.updatedData.WarmingUp {
+		t.Error("expected first reading not to be marked as warming up")
+	}
+
+	airqRepo.data = newReading(created, base.Add(2*time.Hour), 80)
+	if _, err := usecase.Execute(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !metricsRepo.updatedData.WarmingUp {
+		t.Error("expected reading after restart to be marked as warming up")
+	}
+}